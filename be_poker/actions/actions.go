@@ -0,0 +1,213 @@
+package actions
+
+import (
+	"fmt"
+	"poker_tracker_backend/models"
+	"strings"
+)
+
+// 街道順序
+var streetOrder = map[string]int{"preflop": 0, "flop": 1, "turn": 2, "river": 3}
+
+var actionTypes = map[string]bool{
+	"ante":  true,
+	"post":  true,
+	"fold":  true,
+	"check": true,
+	"call":  true,
+	"bet":   true,
+	"raise": true,
+}
+
+// Validate 檢查街道與動作是否合法，並在每個動作上填入動作後的底池
+// 如果客戶端有提供底池且與計算結果不符，會回報錯誤
+func Validate(streets []models.Street) models.ValidationErrors {
+	var errs models.ValidationErrors
+
+	pot := 0
+	lastStreet := -1
+	folded := map[string]bool{}
+	for i := range streets {
+		street := &streets[i]
+		field := fmt.Sprintf("streets[%d]", i)
+
+		order, ok := streetOrder[street.Name]
+		if !ok {
+			errs.Add(field+".name", fmt.Sprintf("unknown street %q", street.Name))
+			continue
+		}
+		if order <= lastStreet {
+			errs.Add(field+".name", fmt.Sprintf("street %q is out of order or repeated", street.Name))
+		}
+		lastStreet = order
+
+		// 每條街重新計算下注
+		currentBet := 0
+		committed := map[string]int{}
+		voluntary := false
+		for j := range street.Actions {
+			a := &street.Actions[j]
+			af := fmt.Sprintf("%s.actions[%d]", field, j)
+
+			if strings.TrimSpace(a.Actor) == "" {
+				errs.Add(af+".actor", "actor is required")
+			}
+			if !actionTypes[a.Type] {
+				errs.Add(af+".type", fmt.Sprintf("unknown action type %q", a.Type))
+				continue
+			}
+			if folded[a.Actor] {
+				errs.Add(af+".actor", fmt.Sprintf("%s already folded", a.Actor))
+			}
+			if a.Amount < 0 {
+				errs.Add(af+".amount", "amount cannot be negative")
+			}
+
+			toCall := currentBet - committed[a.Actor]
+			switch a.Type {
+			case "ante", "post":
+				if street.Name != "preflop" || voluntary {
+					errs.Add(af+".type", "blinds and antes can only be posted before preflop action")
+				}
+				if a.Amount <= 0 {
+					errs.Add(af+".amount", "amount must be positive")
+				}
+			case "fold":
+				folded[a.Actor] = true
+				if a.Amount != 0 {
+					errs.Add(af+".amount", "fold cannot add chips")
+				}
+			case "check":
+				if a.Amount != 0 {
+					errs.Add(af+".amount", "check cannot add chips")
+				}
+				if toCall > 0 {
+					errs.Add(af+".type", fmt.Sprintf("cannot check facing %d", toCall))
+				}
+			case "call":
+				if toCall <= 0 {
+					errs.Add(af+".type", "nothing to call")
+				} else if a.Amount > toCall || (a.Amount < toCall && !a.AllIn) {
+					errs.Add(af+".amount", fmt.Sprintf("call amount should be %d", toCall))
+				}
+			case "bet":
+				if currentBet > 0 {
+					errs.Add(af+".type", "cannot bet facing a bet, use raise")
+				}
+				if a.Amount <= 0 {
+					errs.Add(af+".amount", "amount must be positive")
+				}
+			case "raise":
+				if currentBet == 0 {
+					errs.Add(af+".type", "nothing to raise, use bet")
+				}
+				if a.Amount <= toCall {
+					errs.Add(af+".amount", fmt.Sprintf("raise must add more than %d", toCall))
+				}
+			}
+
+			if a.Type != "ante" && a.Type != "post" {
+				voluntary = true
+			}
+			if a.Type != "ante" {
+				committed[a.Actor] += a.Amount
+				if committed[a.Actor] > currentBet {
+					currentBet = committed[a.Actor]
+				}
+			}
+
+			pot += a.Amount
+			if a.Pot != 0 && a.Pot != pot {
+				errs.Add(af+".pot", fmt.Sprintf("pot should be %d", pot))
+			}
+			a.Pot = pot
+		}
+	}
+	return errs
+}
+
+// Render 將結構化的動作轉成可讀的文字，存回 Hand.Details
+// 與 heroPosition 相同的 actor 會顯示為 Hero
+func Render(streets []models.Street, heroPosition string) string {
+	lines := []string{}
+	for _, street := range streets {
+		if street.Name == "" {
+			continue
+		}
+		parts := []string{}
+		committed := map[string]int{}
+		for _, a := range street.Actions {
+			actor := a.Actor
+			if heroPosition != "" && actor == heroPosition {
+				actor = "Hero (" + actor + ")"
+			}
+
+			var text string
+			switch a.Type {
+			case "ante":
+				text = fmt.Sprintf("%s posts ante %d", actor, a.Amount)
+			case "post":
+				text = fmt.Sprintf("%s posts %d", actor, a.Amount)
+			case "raise":
+				text = fmt.Sprintf("%s raises to %d", actor, committed[a.Actor]+a.Amount)
+			case "bet", "call":
+				text = fmt.Sprintf("%s %ss %d", actor, a.Type, a.Amount)
+			default:
+				text = fmt.Sprintf("%s %ss", actor, a.Type)
+			}
+			if a.AllIn {
+				text += " (all-in)"
+			}
+			if a.Type != "ante" {
+				committed[a.Actor] += a.Amount
+			}
+			parts = append(parts, text)
+		}
+
+		label := strings.ToUpper(street.Name[:1]) + street.Name[1:]
+		if street.Cards != "" {
+			label += " [" + street.Cards + "]"
+		}
+		if len(parts) == 0 {
+			lines = append(lines, label)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", label, strings.Join(parts, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FinalPot 回傳最後一個動作後的底池
+func FinalPot(streets []models.Street) int {
+	for i := len(streets) - 1; i >= 0; i-- {
+		if n := len(streets[i].Actions); n > 0 {
+			return streets[i].Actions[n-1].Pot
+		}
+	}
+	return 0
+}
+
+// AllInStreet 回傳第一個全下動作發生的街，沒有全下時回傳 false
+func AllInStreet(streets []models.Street) (string, bool) {
+	for _, street := range streets {
+		for _, a := range street.Actions {
+			if a.AllIn {
+				return street.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// BoardSize 回傳某條街結束時公共牌的張數
+func BoardSize(street string) int {
+	switch street {
+	case "flop":
+		return 3
+	case "turn":
+		return 4
+	case "river":
+		return 5
+	}
+	return 0
+}
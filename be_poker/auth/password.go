@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// 密碼以 PBKDF2-HMAC-SHA256 雜湊，格式為 pbkdf2-sha256$<iterations>$<salt>$<hash>
+// 迭代次數存在雜湊裡，之後調高也能驗證舊密碼
+const (
+	hashScheme = "pbkdf2-sha256"
+	iterations = 210000
+	saltSize   = 16
+	keySize    = 32
+)
+
+// MinPasswordLength 是密碼的最小長度
+const MinPasswordLength = 8
+
+// HashPassword 產生含隨機 salt 的密碼雜湊
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := pbkdf2.Key([]byte(password), salt, iterations, keySize, sha256.New)
+	return fmt.Sprintf("%s$%d$%s$%s", hashScheme, iterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckPassword 比對密碼與雜湊，格式不正確時視為不符
+func CheckPassword(password, encoded string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != hashScheme {
+		return false
+	}
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil || iter < 1 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(want) == 0 {
+		return false
+	}
+	got := pbkdf2.Key([]byte(password), salt, iter, len(want), sha256.New)
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
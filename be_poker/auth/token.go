@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"poker_tracker_backend/models"
+	"strings"
+	"time"
+)
+
+// TokenLifetime 是登入 token 的有效期限
+const TokenLifetime = 30 * 24 * time.Hour
+
+// NewToken 產生隨機的登入 token，回傳 token 本身與存進資料庫的雜湊
+func NewToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken 回傳 token 的 SHA-256，資料庫外洩時無法直接拿來登入
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// BearerToken 讀取 Authorization: Bearer <token>，沒有時回傳空字串
+func BearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+type userKey struct{}
+
+// WithUser 把登入的使用者放進 request context
+func WithUser(r *http.Request, user models.User) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userKey{}, user))
+}
+
+// User 回傳 request 的登入使用者；沒有經過驗證的 request 回傳 false
+func User(r *http.Request) (models.User, bool) {
+	user, ok := r.Context().Value(userKey{}).(models.User)
+	return user, ok
+}
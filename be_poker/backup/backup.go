@@ -0,0 +1,223 @@
+package backup
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"poker_tracker_backend/db"
+	"time"
+)
+
+const (
+	// Format 是備份檔 manifest 的格式名稱
+	Format = "poker-tracker-backup"
+	// FormatVersion 是備份檔的格式版本，改變檔案結構時才需要增加
+	FormatVersion = 1
+	manifestFile  = "manifest.json"
+
+	// MaxArchiveSize 是上傳的備份檔（壓縮後）的大小上限
+	MaxArchiveSize = 256 << 20
+	// MaxFileSize 是備份中每個檔案解壓縮後的大小上限，避免解壓縮後過大的 zip
+	MaxFileSize = 1 << 30
+)
+
+// ErrInvalidArchive 表示上傳的檔案不是完整的備份
+var ErrInvalidArchive = errors.New("invalid backup archive")
+
+// Manifest 描述備份的內容；每張表一個 JSON lines 檔案，以 SHA-256 檢查是否完整
+type Manifest struct {
+	Format        string  `json:"format"`
+	FormatVersion int     `json:"formatVersion"`
+	SchemaVersion int     `json:"schemaVersion"` // 備份時資料庫的 migration 版本
+	Driver        string  `json:"driver"`
+	Account       string  `json:"account,omitempty"`
+	CreatedAt     string  `json:"createdAt"`
+	Tables        []Table `json:"tables"`
+}
+
+// Table 是備份中的一張表
+type Table struct {
+	Name   string `json:"name"`
+	File   string `json:"file"`
+	Rows   int    `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+// Write 把 snapshot 中使用者的資料寫成 zip 備份：tables/<table>.jsonl 加上最後寫入的 manifest.json
+// 資料邊讀邊寫，不會整份留在記憶體；中途失敗的備份沒有 manifest，還原時會被拒絕
+func Write(w io.Writer, snapshot *db.Snapshot, manifest Manifest) error {
+	zw := zip.NewWriter(w)
+	manifest.Format = Format
+	manifest.FormatVersion = FormatVersion
+	manifest.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	manifest.Tables = []Table{}
+	for _, name := range db.BackupTables() {
+		table := Table{Name: name, File: "tables/" + name + ".jsonl"}
+		f, err := zw.Create(table.File)
+		if err != nil {
+			return err
+		}
+		hash := sha256.New()
+		out := bufio.NewWriter(io.MultiWriter(f, hash))
+		enc := json.NewEncoder(out)
+		err = snapshot.Rows(name, func(row db.BackupRow) error {
+			table.Rows++
+			return enc.Encode(row)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+		table.SHA256 = hex.EncodeToString(hash.Sum(nil))
+		manifest.Tables = append(manifest.Tables, table)
+	}
+
+	f, err := zw.Create(manifestFile)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Archive 是已檢查過 manifest 與 checksum 的備份檔
+type Archive struct {
+	Manifest Manifest
+	files    map[string]*zip.File
+}
+
+// Open 讀取備份並檢查格式、每個檔案的 checksum；schemaVersion 是目前資料庫的版本
+// 比目前資料庫還新的備份無法還原
+func Open(r io.ReaderAt, size int64, schemaVersion int) (*Archive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	a := &Archive{files: map[string]*zip.File{}}
+	for _, f := range zr.File {
+		a.files[f.Name] = f
+	}
+
+	mf, ok := a.files[manifestFile]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidArchive, manifestFile)
+	}
+	rc, err := a.open(mf)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	err = json.NewDecoder(rc).Decode(&a.Manifest)
+	rc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%w: manifest: %v", ErrInvalidArchive, err)
+	}
+	m := a.Manifest
+	if m.Format != Format {
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidArchive, m.Format)
+	}
+	if m.FormatVersion < 1 || m.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrInvalidArchive, m.FormatVersion)
+	}
+	if m.SchemaVersion > schemaVersion {
+		return nil, fmt.Errorf("%w: backup is from schema version %d, this server is at %d; upgrade the server first", ErrInvalidArchive, m.SchemaVersion, schemaVersion)
+	}
+
+	for _, t := range m.Tables {
+		f, ok := a.files[t.File]
+		if !ok {
+			return nil, fmt.Errorf("%w: missing %s", ErrInvalidArchive, t.File)
+		}
+		rc, err := a.open(f)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, t.File, err)
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != t.SHA256 {
+			return nil, fmt.Errorf("%w: checksum mismatch for %s", ErrInvalidArchive, t.File)
+		}
+	}
+	return a, nil
+}
+
+// Restore 把備份載入 repo 的使用者；備份中沒有的表視為空的
+func (a *Archive) Restore(repo db.Repository, opts db.RestoreOptions) (db.RestoreResult, error) {
+	tables := map[string]Table{}
+	for _, t := range a.Manifest.Tables {
+		tables[t.Name] = t
+	}
+	return repo.Restore(opts, func(name string, fn func(db.BackupRow) error) error {
+		t, ok := tables[name]
+		if !ok {
+			return nil
+		}
+		rc, err := a.open(a.files[t.File])
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		scanner := bufio.NewScanner(rc)
+		// 手牌的分析與描述可能很長
+		scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			dec := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+			dec.UseNumber()
+			var row db.BackupRow
+			if err := dec.Decode(&row); err != nil {
+				return fmt.Errorf("line %d: %v", line, err)
+			}
+			if err := fn(row); err != nil {
+				return fmt.Errorf("line %d: %v", line, err)
+			}
+		}
+		return scanner.Err()
+	})
+}
+
+// open 開啟備份中的檔案，讀超過 MaxFileSize 時回傳錯誤；zip 標頭中的大小不可信，以實際讀到的為準
+func (a *Archive) open(f *zip.File) (io.ReadCloser, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &limitedFile{ReadCloser: rc, left: MaxFileSize + 1}, nil
+}
+
+type limitedFile struct {
+	io.ReadCloser
+	left int64
+}
+
+func (l *limitedFile) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.left {
+		p = p[:l.left]
+	}
+	n, err := l.ReadCloser.Read(p)
+	l.left -= int64(n)
+	if l.left <= 0 {
+		return n, fmt.Errorf("larger than %d bytes", MaxFileSize)
+	}
+	return n, err
+}
@@ -0,0 +1,151 @@
+package cards
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Card 以 0-51 表示一張牌：(rank-2)*4 + suit
+type Card uint8
+
+// 花色順序：♠ ♥ ♦ ♣
+const (
+	Spades = iota
+	Hearts
+	Diamonds
+	Clubs
+)
+
+const rankChars = "23456789TJQKA"
+
+var suitSymbols = []string{"♠", "♥", "♦", "♣"}
+var suitLetters = "shdc"
+
+// New 由點數（2-14）與花色建立一張牌
+func New(rank, suit int) Card {
+	return Card((rank-2)*4 + suit)
+}
+
+// Rank 回傳點數，2-14（A 為 14）
+func (c Card) Rank() int {
+	return int(c)/4 + 2
+}
+
+// Suit 回傳花色 0-3
+func (c Card) Suit() int {
+	return int(c) % 4
+}
+
+// String 回傳 App 使用的標準格式，例如 A♠、T♥
+func (c Card) String() string {
+	return string(rankChars[c.Rank()-2]) + suitSymbols[c.Suit()]
+}
+
+// ASCII 回傳英文字母格式，例如 As、Th
+func (c Card) ASCII() string {
+	return string(rankChars[c.Rank()-2]) + string(suitLetters[c.Suit()])
+}
+
+// Deck 回傳一副完整的 52 張牌
+func Deck() []Card {
+	deck := make([]Card, 52)
+	for i := range deck {
+		deck[i] = Card(i)
+	}
+	return deck
+}
+
+// Parse 解析單張牌，接受 "A♠"、"As"、"aS"、"10h" 等寫法
+func Parse(s string) (Card, error) {
+	list, err := ParseList(s)
+	if err != nil {
+		return 0, err
+	}
+	if len(list) != 1 {
+		return 0, fmt.Errorf("expected one card, got %d in %q", len(list), s)
+	}
+	return list[0], nil
+}
+
+// ParseList 解析一串牌，花色符號與字母寫法可以混用
+// 牌與牌之間可以用空白、逗號分隔，也可以連在一起，例如 "AsKd"
+func ParseList(s string) ([]Card, error) {
+	runes := []rune{}
+	for _, r := range s {
+		// 去掉分隔符號與 emoji 的變體選擇符
+		if unicode.IsSpace(r) || r == ',' || r == '\uFE0F' || r == '\uFE0E' {
+			continue
+		}
+		runes = append(runes, r)
+	}
+
+	list := []Card{}
+	for i := 0; i < len(runes); {
+		rank := 0
+		if runes[i] == '1' && i+1 < len(runes) && runes[i+1] == '0' {
+			rank = 10
+			i += 2
+		} else {
+			idx := strings.IndexRune(rankChars, unicode.ToUpper(runes[i]))
+			if idx < 0 {
+				return nil, fmt.Errorf("invalid rank %q in %q", string(runes[i]), s)
+			}
+			rank = idx + 2
+			i++
+		}
+
+		if i >= len(runes) {
+			return nil, fmt.Errorf("missing suit after rank in %q", s)
+		}
+		suit := parseSuit(runes[i])
+		if suit < 0 {
+			return nil, fmt.Errorf("invalid suit %q in %q", string(runes[i]), s)
+		}
+		i++
+
+		list = append(list, New(rank, suit))
+	}
+	return list, nil
+}
+
+func parseSuit(r rune) int {
+	switch r {
+	case 's', 'S', '♠', '♤':
+		return Spades
+	case 'h', 'H', '♥', '♡':
+		return Hearts
+	case 'd', 'D', '♦', '♢':
+		return Diamonds
+	case 'c', 'C', '♣', '♧':
+		return Clubs
+	}
+	return -1
+}
+
+// Format 以標準格式輸出，牌之間以空白分隔
+func Format(list []Card) string {
+	parts := make([]string, len(list))
+	for i, c := range list {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// FormatASCII 以英文字母格式輸出，例如 "As Kd"
+func FormatASCII(list []Card) string {
+	parts := make([]string, len(list))
+	for i, c := range list {
+		parts[i] = c.ASCII()
+	}
+	return strings.Join(parts, " ")
+}
+
+// Normalize 將任意寫法轉成標準格式
+func Normalize(s string) (string, error) {
+	list, err := ParseList(s)
+	if err != nil {
+		return "", err
+	}
+	return Format(list), nil
+}
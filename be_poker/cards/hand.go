@@ -0,0 +1,127 @@
+package cards
+
+import (
+	"fmt"
+	"poker_tracker_backend/models"
+)
+
+// 每條街應發出的公共牌張數
+var streetCardCounts = map[string]int{"preflop": 0, "flop": 3, "turn": 1, "river": 1}
+
+// ValidateHand 檢查手牌、公共牌與對手手牌，並轉成標準格式
+// 同一張牌在一手牌中只能出現一次；公共牌張數必須是 0、3、4 或 5
+// 沒有填公共牌但結構化動作有發牌時，會依各條街組出公共牌
+func ValidateHand(hand *models.Hand) models.ValidationErrors {
+	var errs models.ValidationErrors
+	seen := map[Card]string{}
+
+	use := func(field string, list []Card) {
+		for _, c := range list {
+			if other, ok := seen[c]; ok {
+				errs.Add(field, fmt.Sprintf("%s is already used in %s", c, other))
+				continue
+			}
+			seen[c] = field
+		}
+	}
+
+	if hand.HoleCards != nil && *hand.HoleCards != "" {
+		list, err := ParseList(*hand.HoleCards)
+		switch {
+		case err != nil:
+			errs.Add("holeCards", err.Error())
+		case len(list) != 2:
+			errs.Add("holeCards", fmt.Sprintf("expected 2 hole cards, got %d", len(list)))
+		default:
+			use("holeCards", list)
+			formatted := Format(list)
+			hand.HoleCards = &formatted
+		}
+	}
+
+	var board []Card
+	if hand.Board != nil && *hand.Board != "" {
+		list, err := ParseList(*hand.Board)
+		switch {
+		case err != nil:
+			errs.Add("board", err.Error())
+		case len(list) == 1 || len(list) == 2 || len(list) > 5:
+			errs.Add("board", fmt.Sprintf("board must have 3, 4 or 5 cards, got %d", len(list)))
+		default:
+			board = list
+		}
+	}
+
+	// 各條街的公共牌
+	var dealt []Card
+	for i := range hand.Streets {
+		street := &hand.Streets[i]
+		field := fmt.Sprintf("streets[%d].cards", i)
+		expected, known := streetCardCounts[street.Name]
+		if street.Cards == "" || !known {
+			continue
+		}
+		list, err := ParseList(street.Cards)
+		if err != nil {
+			errs.Add(field, err.Error())
+			continue
+		}
+		if len(list) != expected {
+			errs.Add(field, fmt.Sprintf("%s should deal %d cards, got %d", street.Name, expected, len(list)))
+			continue
+		}
+		if len(dealt) != boardSizeBefore(street.Name) {
+			errs.Add(field, fmt.Sprintf("%s cards given without the earlier streets", street.Name))
+			continue
+		}
+		street.Cards = Format(list)
+		dealt = append(dealt, list...)
+	}
+
+	switch {
+	case board == nil && len(dealt) > 0:
+		board = dealt
+	case len(dealt) > 0:
+		for i, c := range dealt {
+			if i >= len(board) || board[i] != c {
+				errs.Add("board", "board does not match the cards dealt on each street")
+				break
+			}
+		}
+	}
+	if board != nil {
+		use("board", board)
+		formatted := Format(board)
+		hand.Board = &formatted
+	}
+
+	for i := range hand.Villains {
+		villain := &hand.Villains[i]
+		if villain.HoleCards == "" {
+			continue
+		}
+		field := fmt.Sprintf("villains[%d].holeCards", i)
+		list, err := ParseList(villain.HoleCards)
+		switch {
+		case err != nil:
+			errs.Add(field, err.Error())
+		case len(list) != 2:
+			errs.Add(field, fmt.Sprintf("expected 2 hole cards, got %d", len(list)))
+		default:
+			use(field, list)
+			villain.HoleCards = Format(list)
+		}
+	}
+
+	return errs
+}
+
+func boardSizeBefore(street string) int {
+	switch street {
+	case "turn":
+		return 3
+	case "river":
+		return 4
+	}
+	return 0
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"poker_tracker_backend/backup"
+	"poker_tracker_backend/currency"
+	"poker_tracker_backend/db"
+	"poker_tracker_backend/importer"
+	"strconv"
+)
+
+// 命令列子指令，例如：
+//
+//	./main migrate status
+//	./main migrate up
+//	./main migrate down 1
+//	./main import hands1.txt hands2.txt
+//	./main import --user me@example.com hands1.txt
+//	./main rates import rates.csv
+//	./main users admin me@example.com
+//	./main users claim me@example.com
+//	./main backup --user me@example.com backup.zip
+//	./main restore --user me@example.com --mode replace backup.zip
+func runCommand(args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(args[1:])
+	case "import":
+		return runImport(args[1:])
+	case "rates":
+		return runRates(args[1:])
+	case "users":
+		return runUsers(args[1:])
+	case "backup":
+		return runBackup(args[1:])
+	case "restore":
+		return runRestore(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func runMigrate(args []string) error {
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	cfg := db.ConfigFromEnv()
+	conn, err := db.Connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	switch action {
+	case "status":
+		statuses, err := db.MigrationStatuses(conn)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🗄️  Database: %s\n", cfg.Driver)
+		for _, s := range statuses {
+			if s.Applied {
+				fmt.Printf("   ✅ %03d_%s (applied %s)\n", s.Version, s.Name, s.AppliedAt)
+			} else {
+				fmt.Printf("   ⏳ %03d_%s (pending)\n", s.Version, s.Name)
+			}
+		}
+		return nil
+
+	case "up":
+		applied, err := db.Migrate(conn, cfg.Driver)
+		if err != nil {
+			return err
+		}
+		version, err := db.SchemaVersion(conn)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ Applied %d migration(s), schema version %d\n", applied, version)
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		rolledBack, err := db.Rollback(conn, cfg.Driver, steps)
+		for _, m := range rolledBack {
+			fmt.Printf("⬇️  Rolled back %03d_%s\n", m.Version, m.Name)
+		}
+		return err
+
+	default:
+		return fmt.Errorf("unknown migrate action %q (expected status, up or down)", action)
+	}
+}
+
+// runImport 匯入 PokerStars 手牌歷史檔案，已匯入過的手牌會略過
+// 已經有帳號時要用 --user 指定匯入到哪個帳號；還沒有帳號時匯入的資料沒有擁有者，之後以 users claim 指定
+func runImport(args []string) error {
+	email := ""
+	if len(args) > 1 && args[0] == "--user" {
+		email, args = args[1], args[2:]
+	}
+	files := args
+	if len(files) == 0 {
+		return fmt.Errorf("usage: import [--user <email>] <hand history file>...")
+	}
+	if err := db.InitDB(); err != nil {
+		return err
+	}
+
+	repo := db.Repo
+	if email != "" {
+		user, _, err := db.Repo.UserByEmail(email)
+		if err != nil {
+			return fmt.Errorf("user %s: %v", email, err)
+		}
+		repo = db.Repo.ForUser(user.ID)
+	} else if users, err := db.Repo.CountUsers(); err != nil {
+		return err
+	} else if users > 0 {
+		return fmt.Errorf("use --user <email> to choose the account to import into")
+	}
+
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		result, err := importer.ImportPokerStars(repo, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		fmt.Printf("📥 %s: %d hands imported, %d already imported, %d sessions created\n",
+			name, result.HandsImported, result.HandsSkipped, result.SessionsCreated)
+		for _, msg := range result.Errors {
+			fmt.Printf("   ⚠️  %s\n", msg)
+		}
+	}
+	return nil
+}
+
+// runRates 匯入匯率 CSV（date,currency,rate）
+func runRates(args []string) error {
+	if len(args) < 2 || args[0] != "import" {
+		return fmt.Errorf("usage: rates import <csv file>...")
+	}
+	if err := db.InitDB(); err != nil {
+		return err
+	}
+
+	for _, name := range args[1:] {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		rates, err := currency.ParseCSV(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if err := db.Repo.SaveExchangeRates(rates); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		fmt.Printf("💱 %s: %d exchange rates imported\n", name, len(rates))
+	}
+	return nil
+}
+
+// runUsers 由伺服器管理者指定管理者帳號，或讓帳號接手加上帳號功能之前的資料
+// 這兩件事都不會在註冊時自動發生
+func runUsers(args []string) error {
+	if len(args) != 2 || (args[0] != "admin" && args[0] != "claim") {
+		return fmt.Errorf("usage: users admin|claim <email>")
+	}
+	if err := db.InitDB(); err != nil {
+		return err
+	}
+
+	user, _, err := db.Repo.UserByEmail(args[1])
+	if err != nil {
+		return fmt.Errorf("user %s: %v", args[1], err)
+	}
+	if args[0] == "admin" {
+		if err := db.Repo.SetAdmin(user.ID); err != nil {
+			return err
+		}
+		fmt.Printf("👤 %s is now an administrator\n", user.Email)
+		return nil
+	}
+	claimed, err := db.Repo.ClaimUnowned(user.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("👤 %s: %d existing rows claimed\n", user.Email, claimed)
+	return nil
+}
+
+// userRepoByEmail 回傳 --user 指定帳號的 Repository
+func userRepoByEmail(email string) (db.Repository, error) {
+	user, _, err := db.Repo.UserByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("user %s: %v", email, err)
+	}
+	return db.Repo.ForUser(user.ID), nil
+}
+
+// runBackup 把帳號的資料寫成備份檔，格式與 GET /backup 相同
+func runBackup(args []string) error {
+	if len(args) != 3 || args[0] != "--user" {
+		return fmt.Errorf("usage: backup --user <email> <backup file>")
+	}
+	if err := db.InitDB(); err != nil {
+		return err
+	}
+	repo, err := userRepoByEmail(args[1])
+	if err != nil {
+		return err
+	}
+	version, err := db.SchemaVersion(db.DB)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(args[2])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	snapshot, err := repo.Snapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+	if err := backup.Write(f, snapshot, backup.Manifest{SchemaVersion: version, Driver: db.Driver, Account: args[1]}); err != nil {
+		return err
+	}
+	fmt.Printf("💾 Backup of %s written to %s\n", args[1], args[2])
+	return nil
+}
+
+// runRestore 把備份檔載入帳號，選項與 POST /restore 相同
+func runRestore(args []string) error {
+	email, file := "", ""
+	// 命令列由伺服器管理者執行，可以還原共用的匯率
+	opts := db.RestoreOptions{Mode: db.RestoreMerge, OnConflict: db.ConflictSkip, ExchangeRates: true}
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--user" && i+1 < len(args):
+			i++
+			email = args[i]
+		case args[i] == "--mode" && i+1 < len(args):
+			i++
+			opts.Mode = args[i]
+		case args[i] == "--on-conflict" && i+1 < len(args):
+			i++
+			opts.OnConflict = args[i]
+		default:
+			file = args[i]
+		}
+	}
+	if email == "" || file == "" {
+		return fmt.Errorf("usage: restore --user <email> [--mode merge|replace] [--on-conflict skip|overwrite|copy] <backup file>")
+	}
+	if opts.Mode != db.RestoreMerge && opts.Mode != db.RestoreReplace {
+		return fmt.Errorf("invalid --mode %q (expected merge or replace)", opts.Mode)
+	}
+	if opts.OnConflict != db.ConflictSkip && opts.OnConflict != db.ConflictOverwrite && opts.OnConflict != db.ConflictCopy {
+		return fmt.Errorf("invalid --on-conflict %q (expected skip, overwrite or copy)", opts.OnConflict)
+	}
+	if err := db.InitDB(); err != nil {
+		return err
+	}
+	repo, err := userRepoByEmail(email)
+	if err != nil {
+		return err
+	}
+	version, err := db.SchemaVersion(db.DB)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	archive, err := backup.Open(f, info.Size(), version)
+	if err != nil {
+		return err
+	}
+	result, err := archive.Restore(repo, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("♻️  Restored %s into %s (%s, %d existing rows deleted)\n", file, email, result.Mode, result.Deleted)
+	for _, name := range db.BackupTables() {
+		c := result.Tables[name]
+		fmt.Printf("   %s: %d inserted (%d with new ids), %d updated, %d skipped\n", name, c.Inserted, c.Renamed, c.Updated, c.Skipped)
+	}
+	return nil
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,54 @@
+package currency
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"poker_tracker_backend/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseCSV 讀取匯率 CSV，欄位為 date,currency,rate，第一列可以是標題
+// rate 是 1 美元可以換多少該幣別，例如 2024-01-02,TWD,30.7
+func ParseCSV(r io.Reader) ([]models.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rates := []models.ExchangeRate{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("line %d: expected date,currency,rate", line)
+		}
+		date := strings.TrimPrefix(strings.TrimSpace(record[0]), "\uFEFF")
+		if line == 1 && strings.EqualFold(date, "date") {
+			continue
+		}
+
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", line, date)
+		}
+		code, _ := Parse(strings.TrimSpace(record[1]))
+		if code == "" {
+			return nil, fmt.Errorf("line %d: unknown currency %q", line, record[1])
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, record[2])
+		}
+		rates = append(rates, models.ExchangeRate{Currency: code, Date: date, Rate: rate})
+	}
+	return rates, nil
+}
@@ -0,0 +1,113 @@
+package currency
+
+import (
+	"fmt"
+	"poker_tracker_backend/models"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Base 是匯率表的基準幣別，匯率表示 1 單位 Base 可以換多少該幣別
+const Base = "USD"
+
+// SettingKey 是報表幣別在 settings 表中的 key
+const SettingKey = "reporting_currency"
+
+var codePattern = regexp.MustCompile(`\b[A-Z]{3}\b`)
+
+// 沒有 ISO 代碼時以符號判斷
+var symbols = []struct{ symbol, code string }{
+	{"NT$", "TWD"},
+	{"HK$", "HKD"},
+	{"US$", "USD"},
+	{"€", "EUR"},
+	{"£", "GBP"},
+	{"¥", "JPY"},
+	{"₩", "KRW"},
+	{"$", "USD"},
+}
+
+// Parse 從 session 的幣別文字取出 ISO 代碼與記帳單位
+// 例如 "🇹🇼 TWD (NT$)" → TWD, 1；"USD (¢)" → USD, 100（金額以分記錄）
+// 無法辨識時回傳空字串
+func Parse(s string) (code string, scale float64) {
+	scale = 1
+	if strings.Contains(s, "¢") {
+		scale = 100
+	}
+	if m := codePattern.FindString(strings.ToUpper(s)); m != "" {
+		return m, scale
+	}
+	for _, sym := range symbols {
+		if strings.Contains(s, sym.symbol) {
+			return sym.code, scale
+		}
+	}
+	return "", scale
+}
+
+// Converter 把各幣別的金額換算成報表幣別
+type Converter struct {
+	To    string
+	rates map[string][]models.ExchangeRate // 依日期排序
+}
+
+// NewConverter 以匯率表建立換算器
+func NewConverter(to string, rates []models.ExchangeRate) *Converter {
+	c := &Converter{To: to, rates: map[string][]models.ExchangeRate{}}
+	for _, r := range rates {
+		c.rates[r.Currency] = append(c.rates[r.Currency], r)
+	}
+	for code := range c.rates {
+		list := c.rates[code]
+		sort.Slice(list, func(i, j int) bool { return list[i].Date < list[j].Date })
+	}
+	return c
+}
+
+// Code 回傳金額所屬的幣別；沒有填或無法辨識的幣別視為 Base
+func (c *Converter) Code(currency string) string {
+	code, _ := Parse(currency)
+	if code == "" {
+		return Base
+	}
+	return code
+}
+
+// Convert 把以 currency 記錄的金額換算成報表幣別
+// 使用 date 當天或之前最近的匯率；比所有匯率都早時使用最早的一筆
+func (c *Converter) Convert(amount float64, currency, date string) (float64, error) {
+	_, scale := Parse(currency)
+	code := c.Code(currency)
+	value := amount / scale
+	if code == c.To {
+		return value, nil
+	}
+	from, err := c.rate(code, date)
+	if err != nil {
+		return 0, err
+	}
+	to, err := c.rate(c.To, date)
+	if err != nil {
+		return 0, err
+	}
+	return value / from * to, nil
+}
+
+// rate 回傳 date 當天或之前最近一筆匯率，早於所有匯率時使用最早的一筆
+func (c *Converter) rate(code, date string) (float64, error) {
+	if code == Base {
+		return 1, nil
+	}
+	list := c.rates[code]
+	if len(list) == 0 {
+		return 0, fmt.Errorf("no exchange rate for %s", code)
+	}
+	date = models.DateDay(date)
+	i := sort.Search(len(list), func(i int) bool { return list[i].Date > date })
+	if i == 0 {
+		return list[0].Rate, nil
+	}
+	return list[i-1].Rate, nil
+}
@@ -0,0 +1,639 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// 還原模式
+const (
+	RestoreMerge   = "merge"   // 保留現有資料，加入備份中的資料
+	RestoreReplace = "replace" // 先刪除使用者現有的資料，再載入備份
+)
+
+// 備份中的 id 已經是自己的資料時的處理方式
+const (
+	ConflictSkip      = "skip"      // 保留現有的資料
+	ConflictOverwrite = "overwrite" // 以備份的內容覆蓋
+	ConflictCopy      = "copy"      // 以新的 id 另存一份
+)
+
+// BackupRow 是備份中的一列，欄位名稱對應資料表的欄位
+// 值只會是 string、int64、float64、bool；從 JSON 讀回時數字為 json.Number
+type BackupRow map[string]interface{}
+
+// RestoreOptions 是還原的模式與 id 衝突的處理方式
+type RestoreOptions struct {
+	Mode       string
+	OnConflict string
+	// ExchangeRates 為 true 時才還原匯率；匯率是所有使用者共用的，只有管理者可以修改
+	ExchangeRates bool
+}
+
+// RestoreCounts 是一張表的還原結果
+type RestoreCounts struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Skipped  int `json:"skipped"`
+	Renamed  int `json:"renamed"` // 因為 id 已被使用而改用新 id 的列，也計入 inserted
+}
+
+// RestoreResult 依表名列出還原結果，Deleted 是 replace 模式先刪除的列數
+type RestoreResult struct {
+	Mode    string                   `json:"mode"`
+	Deleted int                      `json:"deleted"`
+	Tables  map[string]RestoreCounts `json:"tables"`
+}
+
+type backupColumn struct {
+	name string
+	kind string // text, int, bool, float, time
+}
+
+// backupTable 描述一張表如何備份與還原
+// 實體表以 id 為主鍵；關聯表沒有 id，以兩端的 id 組成主鍵
+type backupTable struct {
+	name    string
+	columns []backupColumn
+	scope   string            // 備份時只讀取使用者的資料，$1 為使用者 id
+	owned   bool              // 有 owner_id 欄位
+	refs    map[string]string // 參照其他表 id 的欄位，還原時換成新的 id
+	natural string            // 同一位使用者不能重複的欄位，例如標籤名稱
+	// ownerOf 查詢既有 id 屬於哪位使用者；沒有時表示沒有 id 欄位
+	ownerOf string
+	// 覆蓋時先清除的子資料，讓內容與備份一致；$1 為 id
+	children []string
+}
+
+func columnsOf(kind string, names ...string) []backupColumn {
+	list := make([]backupColumn, len(names))
+	for i, name := range names {
+		list[i] = backupColumn{name: name, kind: kind}
+	}
+	return list
+}
+
+func allColumns(lists ...[]backupColumn) []backupColumn {
+	all := []backupColumn{}
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	return all
+}
+
+const (
+	ownedSessions = `SELECT id FROM sessions WHERE owner_id = $1`
+	ownedHands    = `SELECT id FROM hands WHERE owner_id = $1`
+)
+
+// backupTables 依還原的順序排列：被參照的表在前
+// hand_shares 不備份，分享連結的 token 等同密碼，還原後也不應該重新生效
+var backupTables = []backupTable{
+	{
+		name:    "tags",
+		columns: allColumns(columnsOf("text", "id", "name", "color", "category"), columnsOf("time", "created_at")),
+		scope:   `owner_id = $1`,
+		owned:   true,
+		natural: "name",
+		ownerOf: `SELECT owner_id FROM tags WHERE id = $1`,
+	}, {
+		name:    "players",
+		columns: allColumns(columnsOf("text", "id", "name", "site", "notes", "color"), columnsOf("time", "created_at")),
+		scope:   `owner_id = $1`,
+		owned:   true,
+		ownerOf: `SELECT owner_id FROM players WHERE id = $1`,
+	}, {
+		name: "sessions",
+		columns: allColumns(
+			columnsOf("text", "id", "location", "date"),
+			columnsOf("int", "small_blind", "big_blind"),
+			columnsOf("text", "currency"),
+			columnsOf("int", "effective_stack", "table_size"),
+			columnsOf("text", "tag", "start_time", "end_time", "state", "paused_at"),
+			columnsOf("int", "paused_seconds"),
+			columnsOf("text", "session_type", "tournament", "external_id"),
+			columnsOf("time", "created_at", "updated_at"),
+		),
+		scope:   `owner_id = $1`,
+		owned:   true,
+		natural: "external_id",
+		ownerOf: `SELECT owner_id FROM sessions WHERE id = $1`,
+		children: []string{
+			`DELETE FROM session_transactions WHERE session_id = $1`,
+			`DELETE FROM session_tags WHERE session_id = $1`,
+		},
+	}, {
+		name:    "session_transactions",
+		columns: allColumns(columnsOf("text", "id", "session_id", "type"), columnsOf("int", "amount"), columnsOf("text", "occurred_at")),
+		scope:   `session_id IN (` + ownedSessions + `)`,
+		refs:    map[string]string{"session_id": "sessions"},
+		ownerOf: `SELECT COALESCE((SELECT owner_id FROM sessions WHERE sessions.id = t.session_id), '') FROM session_transactions t WHERE t.id = $1`,
+	}, {
+		name:    "session_tags",
+		columns: allColumns(columnsOf("text", "session_id", "tag_id"), columnsOf("int", "position")),
+		scope:   `session_id IN (` + ownedSessions + `)`,
+		refs:    map[string]string{"session_id": "sessions", "tag_id": "tags"},
+	}, {
+		name: "hands",
+		columns: allColumns(
+			columnsOf("text", "id", "session_id", "position", "hole_cards", "board", "details", "note"),
+			columnsOf("int", "result_amount"),
+			columnsOf("text", "date", "villains", "analysis", "analysis_date"),
+			columnsOf("bool", "is_favorite"),
+			columnsOf("text", "tag", "external_id", "streets"),
+			columnsOf("int", "level", "ante"),
+			columnsOf("time", "created_at", "updated_at"),
+		),
+		scope:   `owner_id = $1`,
+		owned:   true,
+		refs:    map[string]string{"session_id": "sessions"},
+		natural: "external_id",
+		ownerOf: `SELECT owner_id FROM hands WHERE id = $1`,
+		children: []string{
+			`DELETE FROM hand_tags WHERE hand_id = $1`,
+			`DELETE FROM hand_players WHERE hand_id = $1`,
+		},
+	}, {
+		name:    "hand_tags",
+		columns: allColumns(columnsOf("text", "hand_id", "tag_id"), columnsOf("int", "position")),
+		scope:   `hand_id IN (` + ownedHands + `)`,
+		refs:    map[string]string{"hand_id": "hands", "tag_id": "tags"},
+	}, {
+		name:    "hand_players",
+		columns: columnsOf("text", "hand_id", "player_id"),
+		scope:   `hand_id IN (` + ownedHands + `)`,
+		refs:    map[string]string{"hand_id": "hands", "player_id": "players"},
+	}, {
+		name:    "settings",
+		columns: columnsOf("text", "key", "value"),
+		scope:   `owner_id = $1`,
+		owned:   true,
+	}, {
+		// 匯率是所有使用者共用的，只有 ExchangeRates 時才還原，而且只補上缺少的匯率，不會覆蓋或刪除
+		name:    "exchange_rates",
+		columns: allColumns(columnsOf("text", "currency", "rate_date"), columnsOf("float", "rate")),
+	},
+}
+
+// BackupTables 依還原順序列出備份包含的資料表
+func BackupTables() []string {
+	names := make([]string, len(backupTables))
+	for i, t := range backupTables {
+		names[i] = t.name
+	}
+	return names
+}
+
+func findBackupTable(name string) (backupTable, bool) {
+	for _, t := range backupTables {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return backupTable{}, false
+}
+
+// selectExpr 讓兩種後端讀出相同型別的值：文字與時間一律轉成字串，NULL 轉成零值
+func (c backupColumn) selectExpr() string {
+	switch c.kind {
+	case "int", "float":
+		return fmt.Sprintf("COALESCE(%s, 0)", c.name)
+	case "bool":
+		return fmt.Sprintf("COALESCE(%s, false)", c.name)
+	}
+	return fmt.Sprintf("COALESCE(CAST(%s AS TEXT), '')", c.name)
+}
+
+// Snapshot 是備份用的 transaction，所有表都從同一個時間點讀出
+type Snapshot struct {
+	tx    *sql.Tx
+	store *sqlStore
+}
+
+// Snapshot 開始讀取使用者的備份，讀完要呼叫 Close
+func (s *sqlStore) Snapshot() (*Snapshot, error) {
+	opts := &sql.TxOptions{}
+	if s.driver == DriverPostgres {
+		opts = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	}
+	tx, err := s.db.BeginTx(context.Background(), opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{tx: tx, store: s}, nil
+}
+
+// Rows 依主鍵順序讀出一張表的資料，每一列呼叫一次 fn
+func (sn *Snapshot) Rows(table string, fn func(BackupRow) error) error {
+	t, ok := findBackupTable(table)
+	if !ok {
+		return fmt.Errorf("unknown backup table %q", table)
+	}
+	exprs := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		exprs[i] = c.selectExpr()
+	}
+	query := `SELECT ` + strings.Join(exprs, ", ") + ` FROM ` + t.name
+	args := []interface{}{}
+	if t.scope != "" {
+		query += ` WHERE ` + t.scope
+		args = append(args, sn.store.owner)
+	}
+	query += ` ORDER BY ` + t.columns[0].name + `, ` + t.columns[1].name
+
+	rows, err := sn.tx.Query(sn.store.bind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		dest := make([]interface{}, len(t.columns))
+		for i, c := range t.columns {
+			switch c.kind {
+			case "int":
+				dest[i] = new(int64)
+			case "float":
+				dest[i] = new(float64)
+			case "bool":
+				dest[i] = new(bool)
+			default:
+				dest[i] = new(string)
+			}
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		row := BackupRow{}
+		for i, c := range t.columns {
+			switch v := dest[i].(type) {
+			case *int64:
+				row[c.name] = *v
+			case *float64:
+				row[c.name] = *v
+			case *bool:
+				row[c.name] = *v
+			case *string:
+				row[c.name] = *v
+			}
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Close 結束讀取
+func (sn *Snapshot) Close() error {
+	return sn.tx.Rollback()
+}
+
+// value 把備份中的值轉成欄位的型別；缺少的欄位（較舊的備份）使用零值
+func (c backupColumn) value(raw interface{}) (interface{}, error) {
+	switch c.kind {
+	case "int":
+		switch v := raw.(type) {
+		case nil:
+			return int64(0), nil
+		case json.Number:
+			return v.Int64()
+		case int64:
+			return v, nil
+		case float64:
+			return int64(v), nil
+		}
+	case "float":
+		switch v := raw.(type) {
+		case nil:
+			return float64(0), nil
+		case json.Number:
+			return v.Float64()
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		}
+	case "bool":
+		switch v := raw.(type) {
+		case nil:
+			return false, nil
+		case bool:
+			return v, nil
+		}
+	case "time":
+		// 空的時間寫入 NULL，Postgres 的 TIMESTAMP 不接受空字串
+		switch v := raw.(type) {
+		case nil:
+			return nil, nil
+		case string:
+			if v == "" {
+				return nil, nil
+			}
+			return v, nil
+		}
+	default:
+		switch v := raw.(type) {
+		case nil:
+			return "", nil
+		case string:
+			return v, nil
+		case json.Number:
+			return v.String(), nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		}
+	}
+	return nil, fmt.Errorf("column %s: unexpected value %v", c.name, raw)
+}
+
+// restorer 在同一個 transaction 中載入備份，ids 記錄備份中的 id 對應到資料庫中的 id
+type restorer struct {
+	s      *sqlStore
+	tx     *sql.Tx
+	opts   RestoreOptions
+	ids    map[string]map[string]string
+	result RestoreResult
+}
+
+func (r *restorer) exec(query string, args ...interface{}) (sql.Result, error) {
+	return r.tx.Exec(r.s.bind(query), args...)
+}
+
+func (r *restorer) queryString(query string, args ...interface{}) (string, bool, error) {
+	var v string
+	err := r.tx.QueryRow(r.s.bind(query), args...).Scan(&v)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return v, err == nil, err
+}
+
+// Restore 把備份載入目前的使用者；read 依序提供每張表的資料
+// 整個還原在一個 transaction 中進行，任何錯誤都不會留下一半的資料
+// 備份中的 id 如果已被其他使用者使用，一律改用新的 id，並更新所有參照
+func (s *sqlStore) Restore(opts RestoreOptions, read func(table string, fn func(BackupRow) error) error) (RestoreResult, error) {
+	r := &restorer{
+		s:      s,
+		opts:   opts,
+		ids:    map[string]map[string]string{},
+		result: RestoreResult{Mode: opts.Mode, Tables: map[string]RestoreCounts{}},
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return r.result, err
+	}
+	defer tx.Rollback()
+	r.tx = tx
+
+	if opts.Mode == RestoreReplace {
+		if err := r.deleteAll(); err != nil {
+			return r.result, err
+		}
+	}
+
+	for _, t := range backupTables {
+		r.ids[t.name] = map[string]string{}
+		counts := RestoreCounts{}
+		err := read(t.name, func(row BackupRow) error {
+			return r.restoreRow(t, row, &counts)
+		})
+		if err != nil {
+			return r.result, fmt.Errorf("%s: %v", t.name, err)
+		}
+		r.result.Tables[t.name] = counts
+	}
+	return r.result, tx.Commit()
+}
+
+// deleteAll 刪除使用者現有的資料，順序與 DeleteSession 相同：先刪關聯再刪本體
+func (r *restorer) deleteAll() error {
+	statements := []string{
+		`DELETE FROM hand_players WHERE hand_id IN (` + ownedHands + `)`,
+		`DELETE FROM hand_tags WHERE hand_id IN (` + ownedHands + `)`,
+		`DELETE FROM hand_shares WHERE owner_id = $1`,
+		`DELETE FROM hands WHERE owner_id = $1`,
+		`DELETE FROM session_tags WHERE session_id IN (` + ownedSessions + `)`,
+		`DELETE FROM session_transactions WHERE session_id IN (` + ownedSessions + `)`,
+		`DELETE FROM sessions WHERE owner_id = $1`,
+		`DELETE FROM tags WHERE owner_id = $1`,
+		`DELETE FROM players WHERE owner_id = $1`,
+		`DELETE FROM settings WHERE owner_id = $1`,
+	}
+	for _, stmt := range statements {
+		res, err := r.exec(stmt, r.s.owner)
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		r.result.Deleted += int(n)
+	}
+	return nil
+}
+
+func (r *restorer) restoreRow(t backupTable, row BackupRow, counts *RestoreCounts) error {
+	values := map[string]interface{}{}
+	for _, c := range t.columns {
+		v, err := c.value(row[c.name])
+		if err != nil {
+			return err
+		}
+		values[c.name] = v
+	}
+
+	// 參照換成還原後的 id；參照不在備份中的資料不能指到別人的資料，關聯直接略過，手牌改成沒有 session
+	for column, target := range t.refs {
+		old, _ := values[column].(string)
+		if old == "" {
+			continue
+		}
+		id, ok := r.ids[target][old]
+		if !ok {
+			if t.ownerOf == "" || t.name == "session_transactions" {
+				counts.Skipped++
+				return nil
+			}
+			id = ""
+		}
+		values[column] = id
+	}
+	if t.name == "hands" {
+		values["villains"] = r.remapVillains(values["villains"].(string))
+	}
+
+	switch {
+	case t.name == "exchange_rates":
+		if !r.opts.ExchangeRates {
+			counts.Skipped++
+			return nil
+		}
+		return r.insertIfMissing(t, values, counts, `SELECT currency FROM exchange_rates WHERE currency = $1 AND rate_date = $2`, values["currency"], values["rate_date"])
+	case t.name == "settings":
+		return r.restoreSetting(t, values, counts)
+	case t.ownerOf == "":
+		// 關聯表：已經有相同的關聯時略過
+		return r.insertIfMissing(t, values, counts, `SELECT `+t.columns[0].name+` FROM `+t.name+` WHERE `+t.columns[0].name+` = $1 AND `+t.columns[1].name+` = $2`,
+			values[t.columns[0].name], values[t.columns[1].name])
+	}
+	return r.restoreEntity(t, values, counts)
+}
+
+// restoreEntity 還原有 id 的資料，處理 id 與名稱的衝突
+func (r *restorer) restoreEntity(t backupTable, values map[string]interface{}, counts *RestoreCounts) error {
+	oldID, _ := values["id"].(string)
+	if oldID == "" {
+		counts.Skipped++
+		return nil
+	}
+
+	existing := ""
+	owner, found, err := r.queryString(t.ownerOf, oldID)
+	if err != nil {
+		return err
+	}
+	if found && owner == r.s.owner {
+		existing = oldID
+	}
+	// 相同名稱的標籤、相同來源編號的手牌視為同一筆
+	if natural, _ := values[t.natural].(string); existing == "" && t.natural != "" && natural != "" {
+		id, ok, err := r.queryString(`SELECT id FROM `+t.name+` WHERE owner_id = $1 AND `+t.natural+` = $2`, r.s.owner, natural)
+		if err != nil {
+			return err
+		}
+		if ok {
+			existing = id
+		}
+	}
+
+	if existing != "" {
+		// 標籤名稱與手牌來源編號不能重複，這類資料 copy 時也只能沿用現有的資料
+		copyAllowed := t.natural == "" || values[t.natural] == ""
+		switch {
+		case r.opts.OnConflict == ConflictOverwrite:
+			if err := r.update(t, existing, values); err != nil {
+				return err
+			}
+			r.ids[t.name][oldID] = existing
+			counts.Updated++
+			return nil
+		case r.opts.OnConflict == ConflictCopy && copyAllowed:
+			// 下面以新的 id 新增
+		default:
+			r.ids[t.name][oldID] = existing
+			counts.Skipped++
+			return nil
+		}
+	}
+
+	id := oldID
+	if found {
+		id = uuid.New().String()
+		counts.Renamed++
+	}
+	values["id"] = id
+	if err := r.insert(t, values); err != nil {
+		return err
+	}
+	r.ids[t.name][oldID] = id
+	counts.Inserted++
+	return nil
+}
+
+func (r *restorer) restoreSetting(t backupTable, values map[string]interface{}, counts *RestoreCounts) error {
+	_, found, err := r.queryString(`SELECT value FROM settings WHERE owner_id = $1 AND key = $2`, r.s.owner, values["key"])
+	if err != nil {
+		return err
+	}
+	if !found {
+		counts.Inserted++
+		return r.insert(t, values)
+	}
+	if r.opts.OnConflict != ConflictOverwrite {
+		counts.Skipped++
+		return nil
+	}
+	counts.Updated++
+	_, err = r.exec(`UPDATE settings SET value = $1 WHERE owner_id = $2 AND key = $3`, values["value"], r.s.owner, values["key"])
+	return err
+}
+
+func (r *restorer) insertIfMissing(t backupTable, values map[string]interface{}, counts *RestoreCounts, query string, args ...interface{}) error {
+	_, found, err := r.queryString(query, args...)
+	if err != nil {
+		return err
+	}
+	if found {
+		counts.Skipped++
+		return nil
+	}
+	counts.Inserted++
+	return r.insert(t, values)
+}
+
+func (r *restorer) insert(t backupTable, values map[string]interface{}) error {
+	names, placeholders, args := []string{}, []string{}, []interface{}{}
+	for _, c := range t.columns {
+		names = append(names, c.name)
+		args = append(args, values[c.name])
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+	if t.owned {
+		names = append(names, "owner_id")
+		args = append(args, r.s.owner)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+	_, err := r.exec(`INSERT INTO `+t.name+` (`+strings.Join(names, ", ")+`) VALUES (`+strings.Join(placeholders, ", ")+`)`, args...)
+	return err
+}
+
+// update 以備份覆蓋既有的資料，並清除子資料讓備份中的內容重新載入
+func (r *restorer) update(t backupTable, id string, values map[string]interface{}) error {
+	sets, args := []string{}, []interface{}{}
+	for _, c := range t.columns {
+		if c.name == "id" {
+			continue
+		}
+		args = append(args, values[c.name])
+		sets = append(sets, fmt.Sprintf("%s = $%d", c.name, len(args)))
+	}
+	args = append(args, id)
+	if _, err := r.exec(`UPDATE `+t.name+` SET `+strings.Join(sets, ", ")+fmt.Sprintf(` WHERE id = $%d`, len(args)), args...); err != nil {
+		return err
+	}
+	for _, stmt := range t.children {
+		if _, err := r.exec(stmt, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remapVillains 把手牌 villains 中的 playerId 換成還原後的玩家 id，保留其他欄位
+func (r *restorer) remapVillains(raw string) string {
+	var villains []map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &villains); err != nil {
+		return raw
+	}
+	for _, v := range villains {
+		old, _ := v["playerId"].(string)
+		if old == "" {
+			continue
+		}
+		if id, ok := r.ids["players"][old]; ok {
+			v["playerId"] = id
+		} else {
+			delete(v, "playerId")
+		}
+	}
+	data, err := json.Marshal(villains)
+	if err != nil {
+		return raw
+	}
+	return string(data)
+}
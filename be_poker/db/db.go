@@ -5,142 +5,97 @@ import (
 	"fmt"
 	"log"
 	"os"
-	_ "github.com/lib/pq" // PostgreSQL driver
 )
 
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// DB 是目前使用中的資料庫連線
 var DB *sql.DB
 
-func InitDB() error {
-	// 使用 Railway PostgreSQL 資料庫
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		return fmt.Errorf("DATABASE_URL environment variable is not set")
-	}
-	
-	log.Printf("🗄️  Using PostgreSQL database from Railway")
+// Repo 是 handlers 使用的資料存取層
+var Repo Repository
 
-	var err error
-	DB, err = sql.Open("postgres", databaseURL)
-	if err != nil {
-		return fmt.Errorf("failed to connect to database: %v", err)
+// Driver 是目前使用中的後端名稱
+var Driver string
+
+// Config 決定使用哪一種資料庫後端
+type Config struct {
+	Driver      string // postgres 或 sqlite
+	DatabaseURL string // Postgres 連線字串
+	SQLitePath  string // SQLite 檔案路徑
+}
+
+// ConfigFromEnv 從環境變數讀取資料庫設定
+// 預設使用 Postgres，沒有 DATABASE_URL 時無法啟動；只有明確設定 DB_DRIVER=sqlite 才使用本地 SQLite，
+// 避免部署時少了環境變數卻默默寫到會消失的本地檔案
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Driver:      os.Getenv("DB_DRIVER"),
+		DatabaseURL: os.Getenv("DATABASE_URL"),
+		SQLitePath:  os.Getenv("SQLITE_PATH"),
+	}
+	if cfg.Driver == "" {
+		cfg.Driver = DriverPostgres
 	}
+	if cfg.SQLitePath == "" {
+		cfg.SQLitePath = "poker_tracker.db"
+	}
+	return cfg
+}
+
+func InitDB() error {
+	return Open(ConfigFromEnv())
+}
 
-	if err = DB.Ping(); err != nil {
-		return fmt.Errorf("failed to ping database: %v", err)
+// Connect 依設定開啟資料庫連線，但不套用 migration
+func Connect(cfg Config) (*sql.DB, error) {
+	switch cfg.Driver {
+	case DriverPostgres:
+		return openPostgres(cfg.DatabaseURL)
+	case DriverSQLite:
+		return openSQLite(cfg.SQLitePath)
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q (expected %q or %q)", cfg.Driver, DriverPostgres, DriverSQLite)
 	}
+}
 
-	log.Println("✅ Database connected successfully")
-	
-	// 檢查數據表是否存在，不存在則自動創建
-	if err = ensureTablesExist(); err != nil {
-		return fmt.Errorf("failed to ensure tables exist: %v", err)
+// Open 依設定開啟資料庫、套用待執行的 migration，並設定全域的 DB 與 Repo
+func Open(cfg Config) error {
+	conn, err := Connect(cfg)
+	if err != nil {
+		return err
 	}
+	DB = conn
+	Driver = cfg.Driver
 
-	return nil
-}
+	log.Println("✅ Database connected successfully")
 
-// 確保數據表存在
-func ensureTablesExist() error {
+	// 套用尚未執行的 migration
 	log.Println("🔍 Checking database schema...")
-	
-	// 檢查sessions表是否存在 (PostgreSQL語法)
-	var sessionsExists int
-	err := DB.QueryRow(`SELECT COUNT(*) FROM information_schema.tables WHERE table_name = 'sessions'`).Scan(&sessionsExists)
+	applied, err := Migrate(DB, Driver)
 	if err != nil {
-		return fmt.Errorf("failed to check sessions table: %v", err)
+		return fmt.Errorf("failed to migrate database: %v", err)
 	}
-	
-	// 檢查hands表是否存在 (PostgreSQL語法)
-	var handsExists int
-	err = DB.QueryRow(`SELECT COUNT(*) FROM information_schema.tables WHERE table_name = 'hands'`).Scan(&handsExists)
+	version, err := SchemaVersion(DB)
 	if err != nil {
-		return fmt.Errorf("failed to check hands table: %v", err)
+		return fmt.Errorf("failed to read schema version: %v", err)
 	}
-	
-	// 如果表不存在，創建它們
-	if sessionsExists == 0 || handsExists == 0 {
-		log.Println("⚠️ Database schema incomplete, creating tables...")
-		
-		// 先刪除現有表格（如果存在）以確保乾淨的狀態
-		_, err = DB.Exec(`DROP TABLE IF EXISTS hands CASCADE`)
-		if err != nil {
-			return fmt.Errorf("failed to drop hands table: %v", err)
-		}
-		
-		_, err = DB.Exec(`DROP TABLE IF EXISTS sessions CASCADE`)
-		if err != nil {
-			return fmt.Errorf("failed to drop sessions table: %v", err)
-		}
-		
-		// 創建sessions表（PostgreSQL語法）
-		_, err = DB.Exec(`
-			CREATE TABLE sessions (
-				id TEXT PRIMARY KEY,
-				location TEXT DEFAULT '',
-				date TEXT DEFAULT '',
-				small_blind INTEGER DEFAULT 0,
-				big_blind INTEGER DEFAULT 0,
-				currency TEXT DEFAULT '',
-				effective_stack INTEGER DEFAULT 0,
-				table_size INTEGER DEFAULT 6,
-				tag TEXT DEFAULT '',
-				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-			)
-		`)
-		
-		if err != nil {
-			return fmt.Errorf("failed to create sessions table: %v", err)
-		}
-		
-		// 創建hands表（PostgreSQL語法）
-		_, err = DB.Exec(`
-			CREATE TABLE hands (
-				id TEXT PRIMARY KEY,
-				session_id TEXT DEFAULT '',
-				position TEXT DEFAULT '',
-				hole_cards TEXT DEFAULT '',
-				board TEXT DEFAULT '',
-				details TEXT DEFAULT '',
-				note TEXT DEFAULT '',
-				result_amount INTEGER DEFAULT 0,
-				date TEXT DEFAULT '',
-				villains TEXT DEFAULT '[]',
-				analysis TEXT DEFAULT '',
-				analysis_date TEXT DEFAULT '',
-				is_favorite BOOLEAN DEFAULT FALSE,
-				tag TEXT DEFAULT '',
-				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-				FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
-			)
-		`)
-		
-		if err != nil {
-			return fmt.Errorf("failed to create hands table: %v", err)
-		}
-		
-		// 創建索引
-		indexes := []string{
-			`CREATE INDEX IF NOT EXISTS idx_sessions_date ON sessions(date)`,
-			`CREATE INDEX IF NOT EXISTS idx_hands_session_id ON hands(session_id)`,
-			`CREATE INDEX IF NOT EXISTS idx_hands_date ON hands(date)`,
-			`CREATE INDEX IF NOT EXISTS idx_hands_result_amount ON hands(result_amount)`,
-			`CREATE INDEX IF NOT EXISTS idx_hands_is_favorite ON hands(is_favorite)`,
-		}
-		
-		for _, indexSQL := range indexes {
-			_, err = DB.Exec(indexSQL)
-			if err != nil {
-				log.Printf("⚠️ Warning: failed to create index: %v", err)
-				// 繼續執行，索引錯誤不應該阻止應用啟動
-			}
-		}
-		
-		log.Println("✅ Database schema created successfully")
+	if applied > 0 {
+		log.Printf("✅ Applied %d migration(s), schema version %d", applied, version)
 	} else {
-		log.Println("✅ Database schema is up to date")
+		log.Printf("✅ Database schema is up to date (version %d)", version)
 	}
-	
+
+	Repo = newRepository(DB, Driver)
 	return nil
 }
+
+func newRepository(conn *sql.DB, driver string) Repository {
+	if driver == DriverSQLite {
+		return &sqlStore{db: conn, bind: bindQuestion, driver: driver}
+	}
+	return &sqlStore{db: conn, bind: bindDollar, driver: driver}
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// legacyRenames 是版本控管之前的表使用的欄位名稱，對應到目前的名稱
+var legacyRenames = map[string]map[string]string{
+	"hands": {"result": "result_amount", "favorite": "is_favorite"},
+}
+
+// legacyTables 是 migration 1 建立的表，依序升級：hands 參照 sessions
+var legacyTables = []struct {
+	name   string
+	create string
+}{
+	{"sessions", createSessionsTable},
+	{"hands", createHandsTable},
+}
+
+type tableColumn struct {
+	name     string
+	dataType string
+	def      string // 預設值的 SQL，沒有時為空字串
+}
+
+// upgradeLegacyTables 讓版本控管之前建立的 sessions 與 hands 表符合 migration 1 的結構
+// CREATE TABLE IF NOT EXISTS 不會更動已經存在的表，例如舊的 poker_tracker_old.db 以 result、favorite 命名欄位，
+// 也缺少之後才加入的欄位；表不存在時不做任何事，由 migration 1 建立
+func upgradeLegacyTables(tx *sql.Tx, driver string) error {
+	for _, t := range legacyTables {
+		if err := upgradeLegacyTable(tx, driver, t.name, t.create); err != nil {
+			return fmt.Errorf("upgrade legacy %s table: %v", t.name, err)
+		}
+	}
+	return nil
+}
+
+func upgradeLegacyTable(tx *sql.Tx, driver, table, create string) error {
+	columns, err := tableColumns(tx, driver, table)
+	if err != nil || len(columns) == 0 {
+		return err
+	}
+	existing := map[string]tableColumn{}
+	for _, c := range columns {
+		existing[c.name] = c
+	}
+	for old, name := range legacyRenames[table] {
+		c, ok := existing[old]
+		if _, taken := existing[name]; !ok || taken {
+			continue
+		}
+		if _, err := tx.Exec(`ALTER TABLE ` + table + ` RENAME COLUMN ` + old + ` TO ` + name); err != nil {
+			return err
+		}
+		delete(existing, old)
+		c.name = name
+		existing[name] = c
+	}
+
+	// 以 migration 1 的定義建立一張暫存表，取得目前應有的欄位
+	upgrade := table + "_legacy_upgrade"
+	if _, err := tx.Exec(strings.Replace(create, "CREATE TABLE IF NOT EXISTS "+table+" (", "CREATE TABLE "+upgrade+" (", 1)); err != nil {
+		return err
+	}
+	target, err := tableColumns(tx, driver, upgrade)
+	if err != nil {
+		return err
+	}
+
+	if driver == DriverSQLite {
+		return rebuildSQLiteTable(tx, table, upgrade, existing, target)
+	}
+	for _, c := range target {
+		current, ok := existing[c.name]
+		switch {
+		case !ok:
+			stmt := `ALTER TABLE ` + table + ` ADD COLUMN ` + c.name + ` ` + c.dataType
+			if c.def != "" {
+				stmt += ` DEFAULT ` + c.def
+			}
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		case c.dataType == "boolean" && current.dataType != "boolean":
+			// 舊表以整數記錄收藏
+			if _, err := tx.Exec(`ALTER TABLE ` + table + ` ALTER COLUMN ` + c.name + ` DROP DEFAULT`); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`ALTER TABLE ` + table + ` ALTER COLUMN ` + c.name + ` TYPE BOOLEAN USING COALESCE(` + c.name + `, 0) <> 0`); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`ALTER TABLE ` + table + ` ALTER COLUMN ` + c.name + ` SET DEFAULT ` + c.def); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = tx.Exec(`DROP TABLE ` + upgrade)
+	return err
+}
+
+// rebuildSQLiteTable 把舊表的資料搬到暫存表後換掉舊表
+// SQLite 無法新增預設值為 CURRENT_TIMESTAMP 的欄位，所以整張表重建；欄位已經齊全時不重建
+func rebuildSQLiteTable(tx *sql.Tx, table, upgrade string, existing map[string]tableColumn, target []tableColumn) error {
+	common := []string{}
+	for _, c := range target {
+		if _, ok := existing[c.name]; ok {
+			common = append(common, c.name)
+		}
+	}
+	if len(common) == len(target) {
+		_, err := tx.Exec(`DROP TABLE ` + upgrade)
+		return err
+	}
+	list := strings.Join(common, ", ")
+	statements := []string{
+		`INSERT INTO ` + upgrade + ` (` + list + `) SELECT ` + list + ` FROM ` + table,
+		`DROP TABLE ` + table,
+		`ALTER TABLE ` + upgrade + ` RENAME TO ` + table,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tableColumns 依順序列出表的欄位，表不存在時回傳空的清單
+func tableColumns(tx *sql.Tx, driver, table string) ([]tableColumn, error) {
+	query := `SELECT column_name, data_type, COALESCE(column_default, '') FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 ORDER BY ordinal_position`
+	if driver == DriverSQLite {
+		query = `SELECT name, LOWER(type), COALESCE(dflt_value, '') FROM pragma_table_info(?)`
+	}
+	rows, err := tx.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := []tableColumn{}
+	for rows.Next() {
+		var c tableColumn
+		if err := rows.Scan(&c.name, &c.dataType, &c.def); err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,217 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// Migration 是一個有編號的結構變更，Up 套用、Down 還原
+// SQLiteUp/SQLiteDown 只在 SQLite 語法不同時才需要填寫
+// Prepare 在 Up 之前執行，處理要先檢查現有結構才能決定的變更
+type Migration struct {
+	Version    int
+	Name       string
+	Up         []string
+	Down       []string
+	SQLiteUp   []string
+	SQLiteDown []string
+	Prepare    func(tx *sql.Tx, driver string) error
+}
+
+func (m Migration) upStatements(driver string) []string {
+	if driver == DriverSQLite && m.SQLiteUp != nil {
+		return m.SQLiteUp
+	}
+	return m.Up
+}
+
+func (m Migration) downStatements(driver string) []string {
+	if driver == DriverSQLite && m.SQLiteDown != nil {
+		return m.SQLiteDown
+	}
+	return m.Down
+}
+
+// MigrationStatus 描述一個 migration 是否已套用
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt string
+}
+
+func sortedMigrations() []Migration {
+	list := make([]Migration, len(migrations))
+	copy(list, migrations)
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+func ensureMigrationsTable(conn *sql.DB) error {
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL DEFAULT '',
+			applied_at TEXT NOT NULL DEFAULT ''
+		)
+	`)
+	return err
+}
+
+func appliedMigrations(conn *sql.DB) (map[int]string, error) {
+	if err := ensureMigrationsTable(conn); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+
+	rows, err := conn.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]string{}
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// SchemaVersion 回傳目前已套用的最高 migration 版本
+func SchemaVersion(conn *sql.DB) (int, error) {
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// MigrationStatuses 列出所有 migration 以及是否已套用
+func MigrationStatuses(conn *sql.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []MigrationStatus{}
+	for _, m := range sortedMigrations() {
+		appliedAt, ok := applied[m.Version]
+		statuses = append(statuses, MigrationStatus{
+			Version:   m.Version,
+			Name:      m.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Migrate 在同一個 transaction 中套用所有尚未套用的 migration
+// 任何一步失敗都會整批回滾，資料庫維持原本的版本
+func Migrate(conn *sql.DB, driver string) (int, error) {
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := []Migration{}
+	for _, m := range sortedMigrations() {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	bind := bindDollar
+	if driver == DriverSQLite {
+		bind = bindQuestion
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, m := range pending {
+		log.Printf("⬆️  Applying migration %03d_%s", m.Version, m.Name)
+		if m.Prepare != nil {
+			if err := m.Prepare(tx, driver); err != nil {
+				return 0, fmt.Errorf("migration %03d_%s failed: %v", m.Version, m.Name, err)
+			}
+		}
+		for _, stmt := range m.upStatements(driver) {
+			if _, err := tx.Exec(stmt); err != nil {
+				return 0, fmt.Errorf("migration %03d_%s failed: %v", m.Version, m.Name, err)
+			}
+		}
+		_, err := tx.Exec(bind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`),
+			m.Version, m.Name, time.Now().UTC().Format(time.RFC3339))
+		if err != nil {
+			return 0, fmt.Errorf("failed to record migration %03d_%s: %v", m.Version, m.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(pending), nil
+}
+
+// Rollback 依序還原最近套用的 steps 個 migration，每個各自一個 transaction
+func Rollback(conn *sql.DB, driver string, steps int) ([]Migration, error) {
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	bind := bindDollar
+	if driver == DriverSQLite {
+		bind = bindQuestion
+	}
+
+	list := sortedMigrations()
+	rolledBack := []Migration{}
+	for i := len(list) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+		m := list[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		tx, err := conn.Begin()
+		if err != nil {
+			return rolledBack, err
+		}
+		log.Printf("⬇️  Rolling back migration %03d_%s", m.Version, m.Name)
+		for _, stmt := range m.downStatements(driver) {
+			if _, err := tx.Exec(stmt); err != nil {
+				tx.Rollback()
+				return rolledBack, fmt.Errorf("rollback of %03d_%s failed: %v", m.Version, m.Name, err)
+			}
+		}
+		if _, err := tx.Exec(bind(`DELETE FROM schema_migrations WHERE version = $1`), m.Version); err != nil {
+			tx.Rollback()
+			return rolledBack, err
+		}
+		if err := tx.Commit(); err != nil {
+			return rolledBack, err
+		}
+		rolledBack = append(rolledBack, m)
+	}
+	return rolledBack, nil
+}
@@ -0,0 +1,348 @@
+package db
+
+// migration 1 的 sessions 與 hands 表，upgradeLegacyTables 也以這兩個定義補齊舊表的欄位
+const (
+	createSessionsTable = `CREATE TABLE IF NOT EXISTS sessions (
+	id TEXT PRIMARY KEY,
+	location TEXT DEFAULT '',
+	date TEXT DEFAULT '',
+	small_blind INTEGER DEFAULT 0,
+	big_blind INTEGER DEFAULT 0,
+	currency TEXT DEFAULT '',
+	effective_stack INTEGER DEFAULT 0,
+	table_size INTEGER DEFAULT 6,
+	tag TEXT DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`
+	createHandsTable = `CREATE TABLE IF NOT EXISTS hands (
+	id TEXT PRIMARY KEY,
+	session_id TEXT DEFAULT '',
+	position TEXT DEFAULT '',
+	hole_cards TEXT DEFAULT '',
+	board TEXT DEFAULT '',
+	details TEXT DEFAULT '',
+	note TEXT DEFAULT '',
+	result_amount INTEGER DEFAULT 0,
+	date TEXT DEFAULT '',
+	villains TEXT DEFAULT '[]',
+	analysis TEXT DEFAULT '',
+	analysis_date TEXT DEFAULT '',
+	is_favorite BOOLEAN DEFAULT FALSE,
+	tag TEXT DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+)`
+)
+
+// migrations 是所有結構變更的清單，新欄位請新增一個更高版本的 migration
+// 已經發佈的 migration 不要再修改
+var migrations = []Migration{
+	{
+		// 使用 IF NOT EXISTS，既有的 Railway 與舊 SQLite 資料庫可以直接接上版本控管
+		// 版本控管之前建立的表先由 upgradeLegacyTables 補齊欄位，之後的索引才建得起來
+		Version: 1,
+		Name:    "create_sessions_and_hands",
+		Prepare: upgradeLegacyTables,
+		Up: []string{
+			createSessionsTable,
+			createHandsTable,
+			`CREATE INDEX IF NOT EXISTS idx_sessions_date ON sessions(date)`,
+			`CREATE INDEX IF NOT EXISTS idx_hands_session_id ON hands(session_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_hands_date ON hands(date)`,
+			`CREATE INDEX IF NOT EXISTS idx_hands_result_amount ON hands(result_amount)`,
+			`CREATE INDEX IF NOT EXISTS idx_hands_is_favorite ON hands(is_favorite)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS hands`,
+			`DROP TABLE IF EXISTS sessions`,
+		},
+	}, {
+		// 匯入的手牌記錄站點手牌編號，避免重複匯入
+		// 匯入的 session 記錄來源（站點、桌名、級別與日期），再次匯入同一張桌子時加到原本的 session
+		Version: 2,
+		Name:    "add_external_ids",
+		Up: []string{
+			`ALTER TABLE hands ADD COLUMN external_id TEXT DEFAULT ''`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_hands_external_id ON hands(external_id) WHERE external_id <> ''`,
+			`ALTER TABLE sessions ADD COLUMN external_id TEXT DEFAULT ''`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_external_id ON sessions(external_id) WHERE external_id <> ''`,
+		},
+		Down: []string{
+			`DROP INDEX IF EXISTS idx_sessions_external_id`,
+			`ALTER TABLE sessions DROP COLUMN external_id`,
+			`DROP INDEX IF EXISTS idx_hands_external_id`,
+			`ALTER TABLE hands DROP COLUMN external_id`,
+		},
+	},	{
+		// 結構化的行動紀錄，以 JSON 儲存
+		Version: 3,
+		Name:    "add_hands_streets",
+		Up: []string{
+			`ALTER TABLE hands ADD COLUMN streets TEXT DEFAULT '[]'`,
+		},
+		Down: []string{
+			`ALTER TABLE hands DROP COLUMN streets`,
+		},
+	}, {
+		// 匯率表與設定（例如報表幣別）
+		Version: 4,
+		Name:    "create_exchange_rates_and_settings",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS exchange_rates (
+				currency TEXT NOT NULL,
+				rate_date TEXT NOT NULL,
+				rate DOUBLE PRECISION NOT NULL,
+				PRIMARY KEY (currency, rate_date)
+			)`,
+			`CREATE TABLE IF NOT EXISTS settings (
+				key TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS settings`,
+			`DROP TABLE IF EXISTS exchange_rates`,
+		},
+	}, {
+		// session 的開始、結束時間與現金異動
+		// 舊資料庫的 sessions.id 型別不一定是 TEXT，所以不加外鍵，刪除 session 時由程式清除
+		Version: 5,
+		Name:    "add_session_times_and_transactions",
+		Up: []string{
+			`ALTER TABLE sessions ADD COLUMN start_time TEXT DEFAULT ''`,
+			`ALTER TABLE sessions ADD COLUMN end_time TEXT DEFAULT ''`,
+			`CREATE TABLE IF NOT EXISTS session_transactions (
+				id TEXT PRIMARY KEY,
+				session_id TEXT NOT NULL,
+				type TEXT NOT NULL,
+				amount INTEGER NOT NULL,
+				occurred_at TEXT DEFAULT ''
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_session_transactions_session_id ON session_transactions(session_id)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS session_transactions`,
+			`ALTER TABLE sessions DROP COLUMN end_time`,
+			`ALTER TABLE sessions DROP COLUMN start_time`,
+		},
+	}, {
+		// 進行中的 session：狀態與暫停時間
+		Version: 6,
+		Name:    "add_session_live_state",
+		Up: []string{
+			`ALTER TABLE sessions ADD COLUMN state TEXT DEFAULT ''`,
+			`ALTER TABLE sessions ADD COLUMN paused_at TEXT DEFAULT ''`,
+			`ALTER TABLE sessions ADD COLUMN paused_seconds INTEGER DEFAULT 0`,
+		},
+		Down: []string{
+			`ALTER TABLE sessions DROP COLUMN paused_seconds`,
+			`ALTER TABLE sessions DROP COLUMN paused_at`,
+			`ALTER TABLE sessions DROP COLUMN state`,
+		},
+	}, {
+		// 錦標賽：session 類型與錦標賽資料（JSON），手牌記錄盲注級別與前注
+		Version: 7,
+		Name:    "add_tournaments",
+		Up: []string{
+			`ALTER TABLE sessions ADD COLUMN session_type TEXT DEFAULT 'cash'`,
+			`ALTER TABLE sessions ADD COLUMN tournament TEXT DEFAULT ''`,
+			`ALTER TABLE hands ADD COLUMN level INTEGER DEFAULT 0`,
+			`ALTER TABLE hands ADD COLUMN ante INTEGER DEFAULT 0`,
+		},
+		Down: []string{
+			`ALTER TABLE hands DROP COLUMN ante`,
+			`ALTER TABLE hands DROP COLUMN level`,
+			`ALTER TABLE sessions DROP COLUMN tournament`,
+			`ALTER TABLE sessions DROP COLUMN session_type`,
+		},
+	}, {
+		// 全文搜尋：Postgres 以 GIN 索引加速，運算式需與 search.go 的 handSearchVector 相同
+		// SQLite 沒有對應的索引，改用 LIKE 搜尋
+		Version: 8,
+		Name:    "add_hands_search_index",
+		Up: []string{
+			`CREATE INDEX IF NOT EXISTS idx_hands_search ON hands USING GIN ((setweight(to_tsvector('simple', COALESCE(note, '')), 'A') || setweight(to_tsvector('simple', COALESCE(details, '')), 'B') || setweight(to_tsvector('simple', COALESCE(analysis, '')), 'C')))`,
+		},
+		Down: []string{
+			`DROP INDEX IF EXISTS idx_hands_search`,
+		},
+		SQLiteUp:   []string{},
+		SQLiteDown: []string{},
+	}, {
+		// 多對多標籤：hands、sessions 原本的 tag 字串搬到 tags 表，舊欄位保留但不再使用
+		// 搬移的標籤以名稱當 id，之後新增的標籤使用 uuid
+		Version: 9,
+		Name:    "create_tags",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS tags (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL UNIQUE,
+				color TEXT DEFAULT '',
+				category TEXT DEFAULT '',
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE TABLE IF NOT EXISTS hand_tags (
+				hand_id TEXT NOT NULL,
+				tag_id TEXT NOT NULL,
+				position INTEGER DEFAULT 0,
+				PRIMARY KEY (hand_id, tag_id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS session_tags (
+				session_id TEXT NOT NULL,
+				tag_id TEXT NOT NULL,
+				position INTEGER DEFAULT 0,
+				PRIMARY KEY (session_id, tag_id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_hand_tags_tag_id ON hand_tags(tag_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_session_tags_tag_id ON session_tags(tag_id)`,
+			`INSERT INTO tags (id, name)
+				SELECT DISTINCT TRIM(tag), TRIM(tag) FROM (
+					SELECT tag FROM hands UNION SELECT tag FROM sessions
+				) old_tags WHERE TRIM(COALESCE(tag, '')) <> ''`,
+			`INSERT INTO hand_tags (hand_id, tag_id)
+				SELECT CAST(id AS TEXT), TRIM(tag) FROM hands WHERE TRIM(COALESCE(tag, '')) <> ''`,
+			`INSERT INTO session_tags (session_id, tag_id)
+				SELECT CAST(id AS TEXT), TRIM(tag) FROM sessions WHERE TRIM(COALESCE(tag, '')) <> ''`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS session_tags`,
+			`DROP TABLE IF EXISTS hand_tags`,
+			`DROP TABLE IF EXISTS tags`,
+		},
+	}, {
+		// 對手檔案；hand_players 由手牌 villains 中的 playerId 產生，用來查詢某位玩家的手牌
+		Version: 10,
+		Name:    "create_players",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS players (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL,
+				site TEXT DEFAULT '',
+				notes TEXT DEFAULT '',
+				color TEXT DEFAULT '',
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE TABLE IF NOT EXISTS hand_players (
+				hand_id TEXT NOT NULL,
+				player_id TEXT NOT NULL,
+				PRIMARY KEY (hand_id, player_id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_hand_players_player_id ON hand_players(player_id)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS hand_players`,
+			`DROP TABLE IF EXISTS players`,
+		},
+	}, {
+		// 帳號與登入 token；sessions、hands、players、tags、settings 加上 owner_id
+		// 既有資料的 owner_id 為空字串，由伺服器管理者以 users claim 指令指定的帳號接手
+		// tags 與 settings 的唯一鍵要改成以使用者區分，所以重建這兩張表
+		Version: 11,
+		Name:    "create_users_and_owner_ids",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS users (
+				id TEXT PRIMARY KEY,
+				email TEXT NOT NULL UNIQUE,
+				password_hash TEXT NOT NULL,
+				is_admin BOOLEAN DEFAULT FALSE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users(LOWER(email))`,
+			`CREATE TABLE IF NOT EXISTS auth_tokens (
+				token_hash TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				created_at TEXT NOT NULL DEFAULT '',
+				expires_at TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_auth_tokens_user_id ON auth_tokens(user_id)`,
+			`ALTER TABLE sessions ADD COLUMN owner_id TEXT DEFAULT ''`,
+			`ALTER TABLE hands ADD COLUMN owner_id TEXT DEFAULT ''`,
+			`ALTER TABLE players ADD COLUMN owner_id TEXT DEFAULT ''`,
+			`CREATE INDEX IF NOT EXISTS idx_sessions_owner_id ON sessions(owner_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_hands_owner_id ON hands(owner_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_players_owner_id ON players(owner_id)`,
+			// 不同使用者可以匯入同一手牌
+			`DROP INDEX IF EXISTS idx_hands_external_id`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_hands_owner_external_id ON hands(owner_id, external_id) WHERE external_id <> ''`,
+			`DROP INDEX IF EXISTS idx_sessions_external_id`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_owner_external_id ON sessions(owner_id, external_id) WHERE external_id <> ''`,
+			`CREATE TABLE tags_by_owner (
+				id TEXT PRIMARY KEY,
+				owner_id TEXT NOT NULL DEFAULT '',
+				name TEXT NOT NULL,
+				color TEXT DEFAULT '',
+				category TEXT DEFAULT '',
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (owner_id, name)
+			)`,
+			`INSERT INTO tags_by_owner (id, name, color, category, created_at) SELECT id, name, color, category, created_at FROM tags`,
+			`DROP TABLE tags`,
+			`ALTER TABLE tags_by_owner RENAME TO tags`,
+			`CREATE TABLE settings_by_owner (
+				owner_id TEXT NOT NULL DEFAULT '',
+				key TEXT NOT NULL,
+				value TEXT NOT NULL,
+				PRIMARY KEY (owner_id, key)
+			)`,
+			`INSERT INTO settings_by_owner (key, value) SELECT key, value FROM settings`,
+			`DROP TABLE settings`,
+			`ALTER TABLE settings_by_owner RENAME TO settings`,
+		},
+		// 還原時不同使用者的同名標籤與設定只保留一筆
+		Down: []string{
+			`CREATE TABLE settings_shared (
+				key TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			)`,
+			`INSERT INTO settings_shared (key, value) SELECT key, MAX(value) FROM settings GROUP BY key`,
+			`DROP TABLE settings`,
+			`ALTER TABLE settings_shared RENAME TO settings`,
+			`CREATE TABLE tags_shared (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL UNIQUE,
+				color TEXT DEFAULT '',
+				category TEXT DEFAULT '',
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`INSERT INTO tags_shared (id, name, color, category, created_at)
+				SELECT id, name, color, category, created_at FROM tags WHERE id IN (SELECT MIN(id) FROM tags GROUP BY name)`,
+			`DROP TABLE tags`,
+			`ALTER TABLE tags_shared RENAME TO tags`,
+			`DROP INDEX IF EXISTS idx_sessions_owner_external_id`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_external_id ON sessions(external_id) WHERE external_id <> ''`,
+			`DROP INDEX IF EXISTS idx_hands_owner_external_id`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_hands_external_id ON hands(external_id) WHERE external_id <> ''`,
+			`DROP INDEX IF EXISTS idx_players_owner_id`,
+			`DROP INDEX IF EXISTS idx_hands_owner_id`,
+			`DROP INDEX IF EXISTS idx_sessions_owner_id`,
+			`ALTER TABLE players DROP COLUMN owner_id`,
+			`ALTER TABLE hands DROP COLUMN owner_id`,
+			`ALTER TABLE sessions DROP COLUMN owner_id`,
+			`DROP TABLE IF EXISTS auth_tokens`,
+			`DROP TABLE IF EXISTS users`,
+		},
+	}, {
+		// 手牌的公開分享連結；連結中的隨機 token 只存 SHA-256，撤銷時記錄 revoked_at
+		Version: 12,
+		Name:    "create_hand_shares",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS hand_shares (
+				id TEXT PRIMARY KEY,
+				token_hash TEXT NOT NULL UNIQUE,
+				hand_id TEXT NOT NULL,
+				owner_id TEXT NOT NULL DEFAULT '',
+				hide_hole_cards BOOLEAN DEFAULT FALSE,
+				hide_results BOOLEAN DEFAULT FALSE,
+				created_at TEXT NOT NULL DEFAULT '',
+				expires_at TEXT NOT NULL DEFAULT '',
+				revoked_at TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_hand_shares_hand_id ON hand_shares(hand_id)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS hand_shares`,
+		},
+	},
+}
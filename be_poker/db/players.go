@@ -0,0 +1,226 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"poker_tracker_backend/models"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const playerColumns = `p.id, p.name, COALESCE(p.site, ''), COALESCE(p.notes, ''), COALESCE(p.color, ''),
+	(SELECT COUNT(*) FROM hand_players WHERE player_id = p.id),
+	COALESCE((SELECT MAX(h.date) FROM hand_players hp JOIN hands h ON h.id = hp.hand_id WHERE hp.player_id = p.id), '')`
+
+func scanPlayer(row rowScanner) (models.Player, error) {
+	var p models.Player
+	err := row.Scan(&p.ID, &p.Name, &p.Site, &p.Notes, &p.Color, &p.Hands, &p.LastSeen)
+	return p, err
+}
+
+// ListPlayers 列出所有玩家，依名稱排序
+func (s *sqlStore) ListPlayers() ([]models.Player, error) {
+	rows, err := s.query(`SELECT `+playerColumns+` FROM players p WHERE p.owner_id = $1 ORDER BY p.name, p.id`, s.owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	players := []models.Player{}
+	for rows.Next() {
+		player, err := scanPlayer(rows)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, player)
+	}
+	return players, rows.Err()
+}
+
+func (s *sqlStore) GetPlayer(id string) (models.Player, error) {
+	player, err := scanPlayer(s.queryRow(`SELECT `+playerColumns+` FROM players p WHERE p.id = $1 AND p.owner_id = $2`, id, s.owner))
+	return player, notFound(err)
+}
+
+// CreatePlayer 新增玩家並回傳含 id 的資料
+func (s *sqlStore) CreatePlayer(player models.Player) (models.Player, error) {
+	player.ID = uuid.New().String()
+	player.Name = strings.TrimSpace(player.Name)
+	_, err := s.exec(`INSERT INTO players (id, name, site, notes, color, owner_id) VALUES ($1, $2, $3, $4, $5, $6)`,
+		player.ID, player.Name, player.Site, player.Notes, player.Color, s.owner)
+	return player, err
+}
+
+func (s *sqlStore) UpdatePlayer(player models.Player) error {
+	result, err := s.exec(`UPDATE players SET name = $1, site = $2, notes = $3, color = $4 WHERE id = $5 AND owner_id = $6`,
+		strings.TrimSpace(player.Name), player.Site, player.Notes, player.Color, player.ID, s.owner)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeletePlayer 刪除玩家，手牌中的 villain 保留但取消連結
+func (s *sqlStore) DeletePlayer(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(s.bind(`DELETE FROM players WHERE id = $1 AND owner_id = $2`), id, s.owner)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+
+	rows, err := tx.Query(s.bind(`SELECT h.id, COALESCE(h.villains, '[]') FROM hands h JOIN hand_players hp ON hp.hand_id = h.id WHERE hp.player_id = $1`), id)
+	if err != nil {
+		return err
+	}
+	updates := map[string]string{}
+	for rows.Next() {
+		var handID, villainsJSON string
+		if err := rows.Scan(&handID, &villainsJSON); err != nil {
+			rows.Close()
+			return err
+		}
+		var villains []models.Villain
+		if err := json.Unmarshal([]byte(villainsJSON), &villains); err != nil {
+			continue
+		}
+		for i := range villains {
+			if villains[i].PlayerID == id {
+				villains[i].PlayerID = ""
+			}
+		}
+		updates[handID] = marshalVillains(villains)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for handID, villainsJSON := range updates {
+		if _, err := tx.Exec(s.bind(`UPDATE hands SET villains = $1 WHERE id = $2`), villainsJSON, handID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(s.bind(`DELETE FROM hand_players WHERE player_id = $1`), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// resolveVillains 把 villain 連結到玩家：有 playerId 時確認存在，
+// 只有名稱時以名稱（與站點）找到玩家，找不到就新增；同名的玩家有多位時使用最早建立的
+func (s *sqlStore) resolveVillains(tx *sql.Tx, villains []models.Villain) ([]models.Villain, error) {
+	for i := range villains {
+		v := &villains[i]
+		v.Name = strings.TrimSpace(v.Name)
+		if v.PlayerID != "" {
+			var exists int
+			if err := tx.QueryRow(s.bind(`SELECT COUNT(*) FROM players WHERE id = $1 AND owner_id = $2`), v.PlayerID, s.owner).Scan(&exists); err != nil {
+				return nil, err
+			}
+			if exists == 0 {
+				return nil, fmt.Errorf("%w: player %s", ErrNotFound, v.PlayerID)
+			}
+			continue
+		}
+		if v.Name == "" {
+			continue
+		}
+
+		query, args := `SELECT id FROM players WHERE owner_id = $1 AND LOWER(name) = LOWER($2)`, []interface{}{s.owner, v.Name}
+		if v.Site != "" {
+			query += ` AND LOWER(COALESCE(site, '')) = LOWER($3)`
+			args = append(args, v.Site)
+		}
+		err := tx.QueryRow(s.bind(query+` ORDER BY created_at, id LIMIT 1`), args...).Scan(&v.PlayerID)
+		if errors.Is(err, sql.ErrNoRows) {
+			v.PlayerID = uuid.New().String()
+			_, err = tx.Exec(s.bind(`INSERT INTO players (id, name, site, owner_id) VALUES ($1, $2, $3, $4)`), v.PlayerID, v.Name, v.Site, s.owner)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return villains, nil
+}
+
+// setHandPlayers 依 villains 重建手牌與玩家的關聯
+func (s *sqlStore) setHandPlayers(tx *sql.Tx, handID string, villains []models.Villain) error {
+	if _, err := tx.Exec(s.bind(`DELETE FROM hand_players WHERE hand_id = $1`), handID); err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, v := range villains {
+		if v.PlayerID == "" || seen[v.PlayerID] {
+			continue
+		}
+		seen[v.PlayerID] = true
+		if _, err := tx.Exec(s.bind(`INSERT INTO hand_players (hand_id, player_id) VALUES ($1, $2)`), handID, v.PlayerID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachPlayerNames 以玩家檔案目前的名稱填入 villain 的 Name，玩家改名後手牌也會顯示新名稱
+func (s *sqlStore) attachPlayerNames(hands []models.Hand) error {
+	linked := false
+	for _, hand := range hands {
+		for _, v := range hand.Villains {
+			if v.PlayerID != "" {
+				linked = true
+			}
+		}
+	}
+	if !linked {
+		return nil
+	}
+
+	rows, err := s.query(`SELECT id, name, COALESCE(site, '') FROM players WHERE owner_id = $1`, s.owner)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	players := map[string]models.Player{}
+	for rows.Next() {
+		var p models.Player
+		if err := rows.Scan(&p.ID, &p.Name, &p.Site); err != nil {
+			return err
+		}
+		players[p.ID] = p
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range hands {
+		for j := range hands[i].Villains {
+			v := &hands[i].Villains[j]
+			if p, ok := players[v.PlayerID]; ok {
+				v.Name, v.Site = p.Name, p.Site
+			}
+		}
+	}
+	return nil
+}
+
+// attachHandRelations 填入手牌的標籤與玩家名稱
+func (s *sqlStore) attachHandRelations(hands []models.Hand, all bool) error {
+	if err := s.attachHandTags(hands, all); err != nil {
+		return err
+	}
+	return s.attachPlayerNames(hands)
+}
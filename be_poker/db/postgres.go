@@ -0,0 +1,28 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	_ "github.com/lib/pq" // PostgreSQL driver
+)
+
+func openPostgres(databaseURL string) (*sql.DB, error) {
+	// 使用 Railway PostgreSQL 資料庫
+	if databaseURL == "" {
+		return nil, fmt.Errorf("DATABASE_URL environment variable is not set")
+	}
+
+	log.Printf("🗄️  Using PostgreSQL database from Railway")
+
+	conn, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+
+	if err = conn.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %v", err)
+	}
+	return conn, nil
+}
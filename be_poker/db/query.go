@@ -0,0 +1,389 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"poker_tracker_backend/models"
+	"strings"
+)
+
+// ErrInvalidFilter 表示排序或游標參數不正確
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// MaxPageSize 是每頁最多回傳的筆數
+const MaxPageSize = 500
+
+// HandFilter 是 GET /hands 的篩選、排序與分頁條件，零值表示不篩選
+type HandFilter struct {
+	SessionID   string
+	DateFrom    string // YYYY-MM-DD，含當天
+	DateTo      string // YYYY-MM-DD，含當天
+	Position    string
+	Tags        []string // 標籤名稱，需同時帶有全部標籤
+	Favorite    *bool
+	MinResult   *int
+	MaxResult   *int
+	SmallBlind  int // 與 BigBlind 一起篩選所屬 session 的級別
+	BigBlind    int
+	Location    string // 所屬 session 的地點
+	PlayerID    string // 有這位玩家參與的手牌
+	HasAnalysis *bool
+	Sort        string // 例如 "-created"、"date"、"-result"
+	Limit       int    // 0 表示不分頁
+	Cursor      string
+}
+
+// SessionFilter 是 GET /sessions 的篩選、排序與分頁條件
+type SessionFilter struct {
+	DateFrom   string
+	DateTo     string
+	Location   string
+	Type       string
+	Tags       []string
+	SmallBlind int
+	BigBlind   int
+	Sort       string // 例如 "-date"、"created"
+	Limit      int
+	Cursor     string
+}
+
+// sortKey 是可排序的欄位，永遠再以 id 排序讓游標穩定
+type sortKey struct {
+	expr    string
+	numeric bool
+}
+
+var handSorts = map[string]sortKey{
+	"created": {expr: `COALESCE(CAST(created_at AS TEXT), '')`},
+	"date":    {expr: `COALESCE(date, '')`},
+	"result":  {expr: `COALESCE(result_amount, 0)`, numeric: true},
+}
+
+var sessionSorts = map[string]sortKey{
+	"created": {expr: `COALESCE(CAST(created_at AS TEXT), '')`},
+	"date":    {expr: `COALESCE(date, '')`},
+}
+
+// HandSorts 與 SessionSorts 列出可用的排序欄位，給 handlers 驗證參數
+func HandSorts() []string    { return sortNames(handSorts) }
+func SessionSorts() []string { return sortNames(sessionSorts) }
+
+func sortNames(m map[string]sortKey) []string {
+	names := []string{}
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
+
+// parseSort 解析 "-date" 這類排序參數，開頭的 - 表示由大到小
+func parseSort(sort, fallback string, keys map[string]sortKey) (sortKey, bool, error) {
+	if sort == "" {
+		sort = fallback
+	}
+	desc := strings.HasPrefix(sort, "-")
+	key, ok := keys[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return sortKey{}, false, fmt.Errorf("%w: unknown sort %q", ErrInvalidFilter, sort)
+	}
+	return key, desc, nil
+}
+
+// cursor 記錄上一頁最後一筆的排序值與 id
+type cursor struct {
+	Text string `json:"t,omitempty"`
+	Num  int64  `json:"n,omitempty"`
+	ID   string `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("%w: invalid cursor", ErrInvalidFilter)
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return c, fmt.Errorf("%w: invalid cursor", ErrInvalidFilter)
+	}
+	return c, nil
+}
+
+// conditions 組出 WHERE 子句，每個 ? 依序換成 $n
+type conditions struct {
+	clauses []string
+	args    []interface{}
+}
+
+func (c *conditions) add(clause string, args ...interface{}) {
+	for _, arg := range args {
+		c.args = append(c.args, arg)
+		clause = strings.Replace(clause, "?", fmt.Sprintf("$%d", len(c.args)), 1)
+	}
+	c.clauses = append(c.clauses, clause)
+}
+
+func (c *conditions) sql() string {
+	if len(c.clauses) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.clauses, " AND ")
+}
+
+// addCursor 加上游標條件：排序值在游標之後，或排序值相同且 id 在游標之後
+func (c *conditions) addCursor(key sortKey, desc bool, raw string) error {
+	if raw == "" {
+		return nil
+	}
+	cur, err := decodeCursor(raw)
+	if err != nil {
+		return err
+	}
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	var value interface{} = cur.Text
+	if key.numeric {
+		value = cur.Num
+	}
+	c.add(fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", key.expr, op, key.expr, op), value, value, cur.ID)
+	return nil
+}
+
+func orderBy(key sortKey, desc bool) string {
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, id %s", key.expr, dir, dir)
+}
+
+// sortValueScanner 在原本的欄位之後多讀一個排序值，用來產生下一頁的游標
+type sortValueScanner struct {
+	row   rowScanner
+	key   sortKey
+	value cursor
+}
+
+func (s *sortValueScanner) Scan(dest ...interface{}) error {
+	if s.key.numeric {
+		return s.row.Scan(append(dest, &s.value.Num)...)
+	}
+	return s.row.Scan(append(dest, &s.value.Text)...)
+}
+
+// dateDay 是 date 欄位的日期部分，與 YYYY-MM-DD 格式的篩選條件比較
+// session 的日期由前端寫成 YYYY/MM/DD HH:MM，手牌為 RFC3339，所以先把 / 換成 -
+const dateDay = `REPLACE(SUBSTR(COALESCE(date, ''), 1, 10), '/', '-')`
+
+func (f HandFilter) conditions(owner string) conditions {
+	var c conditions
+	c.add(`owner_id = ?`, owner)
+	if f.SessionID != "" {
+		c.add(`session_id = ?`, f.SessionID)
+	}
+	if f.DateFrom != "" {
+		c.add(dateDay+` >= ?`, f.DateFrom)
+	}
+	if f.DateTo != "" {
+		c.add(dateDay+` <= ?`, f.DateTo)
+	}
+	if f.Position != "" {
+		c.add(`position = ?`, f.Position)
+	}
+	for _, tag := range f.Tags {
+		c.add(tagCondition(handTagLinks, `id`), tag)
+	}
+	if f.Favorite != nil {
+		c.add(`COALESCE(is_favorite, false) = ?`, *f.Favorite)
+	}
+	if f.MinResult != nil {
+		c.add(`COALESCE(result_amount, 0) >= ?`, *f.MinResult)
+	}
+	if f.MaxResult != nil {
+		c.add(`COALESCE(result_amount, 0) <= ?`, *f.MaxResult)
+	}
+	if f.BigBlind > 0 {
+		c.add(`session_id IN (SELECT id FROM sessions WHERE small_blind = ? AND big_blind = ?)`, f.SmallBlind, f.BigBlind)
+	}
+	if f.Location != "" {
+		c.add(`session_id IN (SELECT id FROM sessions WHERE location = ?)`, f.Location)
+	}
+	if f.PlayerID != "" {
+		c.add(`id IN (SELECT hand_id FROM hand_players WHERE player_id = ?)`, f.PlayerID)
+	}
+	if f.HasAnalysis != nil {
+		if *f.HasAnalysis {
+			c.add(`COALESCE(analysis, '') <> ''`)
+		} else {
+			c.add(`COALESCE(analysis, '') = ''`)
+		}
+	}
+	return c
+}
+
+func (f SessionFilter) conditions(owner string) conditions {
+	var c conditions
+	c.add(`owner_id = ?`, owner)
+	if f.DateFrom != "" {
+		c.add(dateDay+` >= ?`, f.DateFrom)
+	}
+	if f.DateTo != "" {
+		c.add(dateDay+` <= ?`, f.DateTo)
+	}
+	if f.Location != "" {
+		c.add(`location = ?`, f.Location)
+	}
+	if f.Type != "" {
+		c.add(`COALESCE(session_type, 'cash') = ?`, f.Type)
+	}
+	for _, tag := range f.Tags {
+		c.add(tagCondition(sessionTagLinks, `CAST(id AS TEXT)`), tag)
+	}
+	if f.BigBlind > 0 {
+		c.add(`small_blind = ? AND big_blind = ?`, f.SmallBlind, f.BigBlind)
+	}
+	return c
+}
+
+func (s *sqlStore) QueryHands(f HandFilter) (models.HandPage, error) {
+	page := models.HandPage{Items: []models.Hand{}}
+	key, desc, err := parseSort(f.Sort, "-created", handSorts)
+	if err != nil {
+		return page, err
+	}
+
+	c := f.conditions(s.owner)
+	if err := s.queryRow(`SELECT COUNT(*) FROM hands`+c.sql(), c.args...).Scan(&page.Total); err != nil {
+		return page, err
+	}
+	if err := c.addCursor(key, desc, f.Cursor); err != nil {
+		return page, err
+	}
+
+	query := `SELECT ` + handColumns + `, ` + key.expr + ` FROM hands` + c.sql() + orderBy(key, desc)
+	if f.Limit > 0 {
+		// 多取一筆判斷是否還有下一頁
+		query += fmt.Sprintf(" LIMIT %d", f.Limit+1)
+	}
+	rows, err := s.query(query, c.args...)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+
+	cursors := []cursor{}
+	for rows.Next() {
+		scanner := &sortValueScanner{row: rows, key: key}
+		hand, err := scanHand(scanner)
+		if err != nil {
+			return page, err
+		}
+		scanner.value.ID = hand.ID
+		page.Items = append(page.Items, hand)
+		cursors = append(cursors, scanner.value)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	if f.Limit > 0 && len(page.Items) > f.Limit {
+		page.Items = page.Items[:f.Limit]
+		page.NextCursor = encodeCursor(cursors[f.Limit-1])
+	}
+	if err := s.attachHandRelations(page.Items, f.Limit == 0); err != nil {
+		return page, err
+	}
+	return page, nil
+}
+
+func (s *sqlStore) QuerySessions(f SessionFilter) (models.SessionPage, error) {
+	page := models.SessionPage{Items: []models.Session{}}
+	key, desc, err := parseSort(f.Sort, "-date", sessionSorts)
+	if err != nil {
+		return page, err
+	}
+
+	c := f.conditions(s.owner)
+	if err := s.queryRow(`SELECT COUNT(*) FROM sessions`+c.sql(), c.args...).Scan(&page.Total); err != nil {
+		return page, err
+	}
+	if err := c.addCursor(key, desc, f.Cursor); err != nil {
+		return page, err
+	}
+
+	query := `SELECT ` + sessionColumns + `, ` + key.expr + ` FROM sessions` + c.sql() + orderBy(key, desc)
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit+1)
+	}
+	rows, err := s.query(query, c.args...)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+
+	cursors := []cursor{}
+	for rows.Next() {
+		scanner := &sortValueScanner{row: rows, key: key}
+		session, err := scanSession(scanner)
+		if err != nil {
+			return page, err
+		}
+		scanner.value.ID = session.ID
+		page.Items = append(page.Items, session)
+		cursors = append(cursors, scanner.value)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+	rows.Close()
+
+	if f.Limit > 0 && len(page.Items) > f.Limit {
+		page.Items = page.Items[:f.Limit]
+		page.NextCursor = encodeCursor(cursors[f.Limit-1])
+	}
+	if err := s.attachTransactions(page.Items, f.Limit == 0); err != nil {
+		return page, err
+	}
+	if err := s.attachSessionTags(page.Items, f.Limit == 0); err != nil {
+		return page, err
+	}
+	return page, nil
+}
+
+// attachTransactions 讀取 list 中 session 的現金異動
+// all 表示 list 是全部的 session（不分頁），這時直接讀全部的異動，避免 IN 的參數過多
+func (s *sqlStore) attachTransactions(list []models.Session, all bool) error {
+	if len(list) == 0 {
+		return nil
+	}
+	var tc conditions
+	if !all {
+		ids := make([]interface{}, len(list))
+		marks := make([]string, len(list))
+		for i, session := range list {
+			ids[i] = session.ID
+			marks[i] = "?"
+		}
+		tc.add(`session_id IN (`+strings.Join(marks, ", ")+`)`, ids...)
+	}
+	transactions, err := s.listTransactions(tc.sql()+` ORDER BY occurred_at, id`, tc.args...)
+	if err != nil {
+		return err
+	}
+	bySession := map[string][]models.Transaction{}
+	for _, t := range transactions {
+		bySession[t.SessionID] = append(bySession[t.SessionID], t)
+	}
+	for i := range list {
+		list[i].Transactions = bySession[list[i].ID]
+	}
+	return nil
+}
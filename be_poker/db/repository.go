@@ -0,0 +1,761 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"poker_tracker_backend/models"
+	"strings"
+	"time"
+)
+
+// ErrNotFound 表示查詢的資料不存在
+var ErrNotFound = errors.New("not found")
+
+// Repository 定義 handlers 需要的所有資料存取操作
+// Postgres 與 SQLite 後端都實作此介面
+type Repository interface {
+	CreateSession(session models.Session) error
+	SessionByExternalID(externalID string) (models.Session, error)
+	ListSessions() ([]models.Session, error)
+	QuerySessions(f SessionFilter) (models.SessionPage, error)
+	GetSession(id string) (models.Session, error)
+	UpdateSession(id string, session models.Session) error
+	DeleteSession(id string) error
+	UpdateSessionClock(session models.Session, transaction *models.Transaction) error
+	LiveSession() (models.Session, error)
+	AddTransaction(t models.Transaction) error
+	DeleteTransaction(id string) error
+
+	CreateHand(hand models.Hand) error
+	CreateSessionWithHand(session models.Session, hand models.Hand) error
+	ListHands() ([]models.Hand, error)
+	QueryHands(f HandFilter) (models.HandPage, error)
+	GetHand(id string) (models.Hand, error)
+	UpdateHand(id string, hand models.Hand) error
+	DeleteHand(id string) error
+	ToggleFavorite(id string) (bool, error)
+	ExternalHandExists(externalID string) (bool, error)
+	SearchHands(f HandFilter, terms []string, limit int) ([]models.SearchHit, int, error)
+	SearchSessions(f SessionFilter, terms []string, limit int) ([]models.SearchHit, int, error)
+
+	ListExchangeRates() ([]models.ExchangeRate, error)
+	SaveExchangeRates(rates []models.ExchangeRate) error
+	GetSetting(key string) (string, error)
+	SetSetting(key, value string) error
+
+	ListTags() ([]models.Tag, error)
+	GetTag(id string) (models.Tag, error)
+	CreateTag(tag models.Tag) (models.Tag, error)
+	UpdateTag(tag models.Tag) error
+	DeleteTag(id string) error
+	MergeTags(targetID string, sourceIDs []string) error
+
+	ListPlayers() ([]models.Player, error)
+	GetPlayer(id string) (models.Player, error)
+	CreatePlayer(player models.Player) (models.Player, error)
+	UpdatePlayer(player models.Player) error
+	DeletePlayer(id string) error
+
+	CreateHandShare(share models.HandShare, tokenHash string) (models.HandShare, error)
+	ListHandShares(handID string) ([]models.HandShare, error)
+	RevokeHandShare(handID, id string) error
+	HandShareByToken(tokenHash string) (models.HandShare, error)
+
+	Snapshot() (*Snapshot, error)
+	Restore(opts RestoreOptions, read func(table string, fn func(BackupRow) error) error) (RestoreResult, error)
+
+	ForUser(userID string) Repository
+	CreateUser(email, passwordHash string) (models.User, error)
+	SetAdmin(userID string) error
+	ClaimUnowned(userID string) (int64, error)
+	GetUser(id string) (models.User, error)
+	UserByEmail(email string) (models.User, string, error)
+	CountUsers() (int, error)
+	CreateAuthToken(userID, tokenHash string, expiresAt time.Time) error
+	UserByToken(tokenHash string) (models.User, error)
+	DeleteAuthToken(tokenHash string) error
+}
+
+// sqlStore 是兩種後端共用的 SQL 實作
+// 所有查詢都以 Postgres 的 $n 佔位符撰寫，由 bind 轉換成各後端的語法
+// session、手牌、對手、標籤與設定只讀寫 owner 的資料，owner 由 ForUser 指定
+type sqlStore struct {
+	db     *sql.DB
+	bind   func(query string) string
+	driver string
+	owner  string
+}
+
+func (s *sqlStore) exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.db.Exec(s.bind(query), args...)
+}
+
+func (s *sqlStore) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.Query(s.bind(query), args...)
+}
+
+func (s *sqlStore) queryRow(query string, args ...interface{}) *sql.Row {
+	return s.db.QueryRow(s.bind(query), args...)
+}
+
+// 保留 Postgres 語法不變
+func bindDollar(query string) string {
+	return query
+}
+
+// 將 $1, $2 ... 轉換成 SQLite 的 ?1, ?2 ...
+func bindQuestion(query string) string {
+	var b strings.Builder
+	for i := 0; i < len(query); i++ {
+		if query[i] == '$' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9' {
+			b.WriteByte('?')
+			continue
+		}
+		b.WriteByte(query[i])
+	}
+	return b.String()
+}
+
+const sessionColumns = `
+	id,
+	COALESCE(location, ''),
+	COALESCE(date, ''),
+	COALESCE(small_blind, 0),
+	COALESCE(big_blind, 0),
+	COALESCE(currency, ''),
+	COALESCE(effective_stack, 0),
+	COALESCE(table_size, 6),
+	COALESCE(external_id, ''),
+	COALESCE(start_time, ''),
+	COALESCE(end_time, ''),
+	COALESCE(state, ''),
+	COALESCE(paused_at, ''),
+	COALESCE(paused_seconds, 0),
+	COALESCE(session_type, 'cash'),
+	COALESCE(tournament, '')`
+
+const handColumns = `
+	id,
+	COALESCE(session_id, ''),
+	COALESCE(position, ''),
+	COALESCE(hole_cards, ''),
+	COALESCE(details, ''),
+	COALESCE(result_amount, 0),
+	COALESCE(analysis, ''),
+	COALESCE(analysis_date, ''),
+	COALESCE(is_favorite, false),
+	COALESCE(board, ''),
+	COALESCE(note, ''),
+	COALESCE(villains, '[]'),
+	COALESCE(date, ''),
+	COALESCE(external_id, ''),
+	COALESCE(streets, '[]'),
+	COALESCE(level, 0),
+	COALESCE(ante, 0)`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSession(row rowScanner) (models.Session, error) {
+	var s models.Session
+	var tournamentJSON string
+	err := row.Scan(&s.ID, &s.Location, &s.Date, &s.SmallBlind, &s.BigBlind, &s.Currency, &s.EffectiveStack, &s.TableSize, &s.ExternalID, &s.StartTime, &s.EndTime, &s.State, &s.PausedAt, &s.PausedSeconds, &s.Type, &tournamentJSON)
+	if err != nil {
+		return s, err
+	}
+	if tournamentJSON != "" {
+		var t models.Tournament
+		if err := json.Unmarshal([]byte(tournamentJSON), &t); err == nil {
+			s.Tournament = &t
+		}
+	}
+	return s, nil
+}
+
+func scanHand(row rowScanner) (models.Hand, error) {
+	var h models.Hand
+	var villainsJSON, streetsJSON string
+	err := row.Scan(
+		&h.ID,
+		&h.SessionID,
+		&h.Position,
+		&h.HoleCards,
+		&h.Details,
+		&h.Result,
+		&h.Analysis,
+		&h.AnalysisDate,
+		&h.Favorite,
+		&h.Board,
+		&h.Note,
+		&villainsJSON,
+		&h.Date,
+		&h.ExternalID,
+		&streetsJSON,
+		&h.Level,
+		&h.Ante,
+	)
+	if err != nil {
+		return h, err
+	}
+
+	if streetsJSON != "" && streetsJSON != "[]" {
+		if err := json.Unmarshal([]byte(streetsJSON), &h.Streets); err != nil {
+			h.Streets = nil
+		}
+	}
+
+	// 解析villains JSON
+	if villainsJSON != "" && villainsJSON != "[]" {
+		if err := json.Unmarshal([]byte(villainsJSON), &h.Villains); err != nil {
+			h.Villains = []models.Villain{} // 如果解析失敗，設為空陣列
+		}
+	}
+	return h, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *sqlStore) CreateSession(session models.Session) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.insertSession(tx, session); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) insertSession(tx *sql.Tx, session models.Session) error {
+	if _, err := tx.Exec(s.bind(`INSERT INTO sessions (id, location, date, small_blind, big_blind, currency, effective_stack, table_size, external_id, start_time, end_time, session_type, tournament, owner_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`),
+		session.ID, session.Location, session.Date, session.SmallBlind, session.BigBlind, session.Currency, session.EffectiveStack, session.TableSize, session.ExternalID, session.StartTime, session.EndTime, sessionType(session), marshalTournament(session.Tournament), s.owner); err != nil {
+		return err
+	}
+	if err := s.insertTransactions(tx, session.ID, session.Transactions); err != nil {
+		return err
+	}
+	return s.setTags(tx, sessionTagLinks, session.ID, session.Tags)
+}
+
+// SessionByExternalID 回傳之前匯入、來源相同的 session，沒有時回傳 ErrNotFound
+func (s *sqlStore) SessionByExternalID(externalID string) (models.Session, error) {
+	session, err := scanSession(s.queryRow(`SELECT `+sessionColumns+` FROM sessions WHERE external_id = $1 AND owner_id = $2`, externalID, s.owner))
+	return session, notFound(err)
+}
+
+func (s *sqlStore) ListSessions() ([]models.Session, error) {
+	// 使用date欄位排序，Railway資料庫可能沒有created_at
+	rows, err := s.query(`SELECT `+sessionColumns+` FROM sessions WHERE owner_id = $1 ORDER BY date DESC`, s.owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	transactions, err := s.listTransactions(`WHERE session_id IN (SELECT CAST(id AS TEXT) FROM sessions WHERE owner_id = $1) ORDER BY occurred_at, id`, s.owner)
+	if err != nil {
+		return nil, err
+	}
+	bySession := map[string][]models.Transaction{}
+	for _, t := range transactions {
+		bySession[t.SessionID] = append(bySession[t.SessionID], t)
+	}
+	for i := range sessions {
+		sessions[i].Transactions = bySession[sessions[i].ID]
+	}
+	if err := s.attachSessionTags(sessions, true); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (s *sqlStore) GetSession(id string) (models.Session, error) {
+	session, err := scanSession(s.queryRow(`SELECT `+sessionColumns+` FROM sessions WHERE id = $1 AND owner_id = $2`, id, s.owner))
+	if err != nil {
+		return session, notFound(err)
+	}
+	session.Transactions, err = s.listTransactions(`WHERE session_id = $1 ORDER BY occurred_at, id`, id)
+	if err != nil {
+		return session, err
+	}
+	list := []models.Session{session}
+	err = s.attachSessionTags(list, false)
+	return list[0], err
+}
+
+// UpdateSession 更新 session；Transactions 或 Tags 為 nil 時保留原本的資料
+func (s *sqlStore) UpdateSession(id string, session models.Session) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(s.bind(`UPDATE sessions SET location = $1, date = $2, small_blind = $3, big_blind = $4, currency = $5, effective_stack = $6, table_size = $7, start_time = $8, end_time = $9, session_type = $10, tournament = $11 WHERE id = $12 AND owner_id = $13`),
+		session.Location, session.Date, session.SmallBlind, session.BigBlind, session.Currency, session.EffectiveStack, session.TableSize, session.StartTime, session.EndTime, sessionType(session), marshalTournament(session.Tournament), id, s.owner)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	if session.Transactions != nil {
+		if _, err := tx.Exec(s.bind(`DELETE FROM session_transactions WHERE session_id = $1`), id); err != nil {
+			return err
+		}
+		if err := s.insertTransactions(tx, id, session.Transactions); err != nil {
+			return err
+		}
+	}
+	if session.Tags != nil {
+		if err := s.setTags(tx, sessionTagLinks, id, session.Tags); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UpdateSessionClock 只更新進行中 session 的狀態與時間，不影響其他欄位
+// transaction 不為 nil 時（開始時的買入、結束時的兌現）在同一個 transaction 中一併新增
+func (s *sqlStore) UpdateSessionClock(session models.Session, transaction *models.Transaction) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if transaction != nil {
+		if err := s.insertTransactions(tx, session.ID, []models.Transaction{*transaction}); err != nil {
+			return err
+		}
+	}
+	result, err := tx.Exec(s.bind(`UPDATE sessions SET state = $1, start_time = $2, end_time = $3, paused_at = $4, paused_seconds = $5 WHERE id = $6 AND owner_id = $7`),
+		session.State, session.StartTime, session.EndTime, session.PausedAt, session.PausedSeconds, session.ID, s.owner)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return tx.Commit()
+}
+
+// LiveSession 回傳最近開始且尚未結束的 session
+func (s *sqlStore) LiveSession() (models.Session, error) {
+	var id string
+	err := s.queryRow(`SELECT id FROM sessions WHERE state IN ('live', 'paused') AND owner_id = $1 ORDER BY start_time DESC LIMIT 1`, s.owner).Scan(&id)
+	if err != nil {
+		return models.Session{}, notFound(err)
+	}
+	return s.GetSession(id)
+}
+
+func (s *sqlStore) DeleteSession(id string) error {
+	// 舊的 SQLite 檔案沒有 ON DELETE CASCADE，所以明確刪除所屬手牌、現金異動與標籤關聯
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 別人的 session 當作不存在；先確認再刪除所屬的資料
+	var owned int
+	if err := tx.QueryRow(s.bind(`SELECT COUNT(*) FROM sessions WHERE id = $1 AND owner_id = $2`), id, s.owner).Scan(&owned); err != nil {
+		return err
+	}
+	if owned == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.Exec(s.bind(`DELETE FROM hand_tags WHERE hand_id IN (SELECT id FROM hands WHERE session_id = $1)`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.bind(`DELETE FROM hand_players WHERE hand_id IN (SELECT id FROM hands WHERE session_id = $1)`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.bind(`DELETE FROM hand_shares WHERE hand_id IN (SELECT id FROM hands WHERE session_id = $1)`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.bind(`DELETE FROM session_tags WHERE session_id = $1`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.bind(`DELETE FROM hands WHERE session_id = $1`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.bind(`DELETE FROM session_transactions WHERE session_id = $1`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.bind(`DELETE FROM sessions WHERE id = $1`), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) insertTransactions(tx *sql.Tx, sessionID string, transactions []models.Transaction) error {
+	for _, t := range transactions {
+		if _, err := tx.Exec(s.bind(`INSERT INTO session_transactions (id, session_id, type, amount, occurred_at) VALUES ($1, $2, $3, $4, $5)`),
+			t.ID, sessionID, t.Type, t.Amount, t.Time); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqlStore) listTransactions(where string, args ...interface{}) ([]models.Transaction, error) {
+	rows, err := s.query(`SELECT id, session_id, type, amount, COALESCE(occurred_at, '') FROM session_transactions `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		var t models.Transaction
+		if err := rows.Scan(&t.ID, &t.SessionID, &t.Type, &t.Amount, &t.Time); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+// AddTransaction 新增現金異動，session 不存在或不屬於目前的使用者時回傳 ErrNotFound
+func (s *sqlStore) AddTransaction(t models.Transaction) error {
+	if err := s.checkSession(t.SessionID); err != nil {
+		return err
+	}
+	_, err := s.exec(`INSERT INTO session_transactions (id, session_id, type, amount, occurred_at) VALUES ($1, $2, $3, $4, $5)`,
+		t.ID, t.SessionID, t.Type, t.Amount, t.Time)
+	return err
+}
+
+func (s *sqlStore) DeleteTransaction(id string) error {
+	result, err := s.exec(`DELETE FROM session_transactions WHERE id = $1 AND session_id IN (SELECT CAST(id AS TEXT) FROM sessions WHERE owner_id = $2)`, id, s.owner)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// checkSession 確認 session 存在且屬於目前的使用者
+func (s *sqlStore) checkSession(id string) error {
+	var owned int
+	if err := s.queryRow(`SELECT COUNT(*) FROM sessions WHERE CAST(id AS TEXT) = $1 AND owner_id = $2`, id, s.owner).Scan(&owned); err != nil {
+		return err
+	}
+	if owned == 0 {
+		return fmt.Errorf("%w: session %s", ErrNotFound, id)
+	}
+	return nil
+}
+
+// sessionType 回傳 session 類型，沒有填時為現金局
+func sessionType(session models.Session) string {
+	if session.Type == "" {
+		return "cash"
+	}
+	return session.Type
+}
+
+func marshalTournament(t *models.Tournament) string {
+	if t == nil {
+		return ""
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func marshalVillains(villains []models.Villain) string {
+	villainsJSON := "[]"
+	if len(villains) > 0 {
+		villainsBytes, err := json.Marshal(villains)
+		if err == nil {
+			villainsJSON = string(villainsBytes)
+		}
+	}
+	return villainsJSON
+}
+
+func marshalStreets(streets []models.Street) string {
+	streetsJSON := "[]"
+	if len(streets) > 0 {
+		streetsBytes, err := json.Marshal(streets)
+		if err == nil {
+			streetsJSON = string(streetsBytes)
+		}
+	}
+	return streetsJSON
+}
+
+func (s *sqlStore) CreateHand(hand models.Hand) error {
+	if hand.SessionID != "" {
+		if err := s.checkSession(hand.SessionID); err != nil {
+			return err
+		}
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.insertHand(tx, hand); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateSessionWithHand 在同一個 transaction 中新增 session 與它的第一手牌，手牌存不進去時也不會留下空的 session
+func (s *sqlStore) CreateSessionWithHand(session models.Session, hand models.Hand) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.insertSession(tx, session); err != nil {
+		return err
+	}
+	hand.SessionID = session.ID
+	if err := s.insertHand(tx, hand); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) insertHand(tx *sql.Tx, hand models.Hand) error {
+	var err error
+	if hand.Villains, err = s.resolveVillains(tx, hand.Villains); err != nil {
+		return err
+	}
+	_, err = tx.Exec(s.bind(`
+		INSERT INTO hands (
+			id, session_id, position, hole_cards, details, result_amount,
+			analysis, analysis_date, is_favorite, board, note, villains, date, external_id, streets,
+			level, ante, owner_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+	`),
+		hand.ID,
+		hand.SessionID,
+		hand.Position,
+		hand.HoleCards,
+		hand.Details,
+		hand.Result,
+		hand.Analysis,
+		hand.AnalysisDate,
+		hand.Favorite,
+		hand.Board,
+		hand.Note,
+		marshalVillains(hand.Villains),
+		hand.Date,
+		hand.ExternalID,
+		marshalStreets(hand.Streets),
+		hand.Level,
+		hand.Ante,
+		s.owner,
+	)
+	if err != nil {
+		return err
+	}
+	if err := s.setTags(tx, handTagLinks, hand.ID, hand.Tags); err != nil {
+		return err
+	}
+	return s.setHandPlayers(tx, hand.ID, hand.Villains)
+}
+
+func (s *sqlStore) ListHands() ([]models.Hand, error) {
+	rows, err := s.query(`SELECT `+handColumns+` FROM hands WHERE owner_id = $1 ORDER BY created_at DESC`, s.owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hands := []models.Hand{}
+	for rows.Next() {
+		hand, err := scanHand(rows)
+		if err != nil {
+			return nil, err
+		}
+		hands = append(hands, hand)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.attachHandRelations(hands, true); err != nil {
+		return nil, err
+	}
+	return hands, nil
+}
+
+func (s *sqlStore) GetHand(id string) (models.Hand, error) {
+	hand, err := scanHand(s.queryRow(`SELECT `+handColumns+` FROM hands WHERE id = $1 AND owner_id = $2`, id, s.owner))
+	if err != nil {
+		return hand, notFound(err)
+	}
+	list := []models.Hand{hand}
+	err = s.attachHandRelations(list, false)
+	return list[0], err
+}
+
+// UpdateHand 更新手牌；Tags 為 nil 時保留原本的標籤
+func (s *sqlStore) UpdateHand(id string, hand models.Hand) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if hand.Villains, err = s.resolveVillains(tx, hand.Villains); err != nil {
+		return err
+	}
+	result, err := tx.Exec(s.bind(`UPDATE hands SET hole_cards = $1, board = $2, position = $3, details = $4, note = $5, result_amount = $6, date = $7, villains = $8, is_favorite = $9, analysis = $10, streets = $11, level = $12, ante = $13 WHERE id = $14 AND owner_id = $15`),
+		hand.HoleCards, hand.Board, hand.Position, hand.Details, hand.Note, hand.Result, hand.Date, marshalVillains(hand.Villains), hand.Favorite, hand.Analysis, marshalStreets(hand.Streets), hand.Level, hand.Ante, id, s.owner)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	if hand.Tags != nil {
+		if err := s.setTags(tx, handTagLinks, id, hand.Tags); err != nil {
+			return err
+		}
+	}
+	if err := s.setHandPlayers(tx, id, hand.Villains); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) DeleteHand(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var owned int
+	if err := tx.QueryRow(s.bind(`SELECT COUNT(*) FROM hands WHERE id = $1 AND owner_id = $2`), id, s.owner).Scan(&owned); err != nil {
+		return err
+	}
+	if owned == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.Exec(s.bind(`DELETE FROM hand_tags WHERE hand_id = $1`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.bind(`DELETE FROM hand_players WHERE hand_id = $1`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.bind(`DELETE FROM hand_shares WHERE hand_id = $1`), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.bind(`DELETE FROM hands WHERE id = $1`), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) ToggleFavorite(id string) (bool, error) {
+	// 獲取當前的 favorite 狀態
+	var currentFavorite bool
+	if err := s.queryRow(`SELECT COALESCE(is_favorite, false) FROM hands WHERE id = $1 AND owner_id = $2`, id, s.owner).Scan(&currentFavorite); err != nil {
+		return false, notFound(err)
+	}
+
+	// 切換 favorite 狀態
+	newFavorite := !currentFavorite
+	if _, err := s.exec(`UPDATE hands SET is_favorite = $1 WHERE id = $2 AND owner_id = $3`, newFavorite, id, s.owner); err != nil {
+		return false, err
+	}
+	return newFavorite, nil
+}
+
+func (s *sqlStore) ExternalHandExists(externalID string) (bool, error) {
+	var count int
+	if err := s.queryRow(`SELECT COUNT(*) FROM hands WHERE external_id = $1 AND owner_id = $2`, externalID, s.owner).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *sqlStore) ListExchangeRates() ([]models.ExchangeRate, error) {
+	rows, err := s.query(`SELECT currency, rate_date, rate FROM exchange_rates ORDER BY currency, rate_date`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []models.ExchangeRate{}
+	for rows.Next() {
+		var r models.ExchangeRate
+		if err := rows.Scan(&r.Currency, &r.Date, &r.Rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
+
+// SaveExchangeRates 新增匯率，同一幣別同一天的匯率會被覆蓋
+func (s *sqlStore) SaveExchangeRates(rates []models.ExchangeRate) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(s.bind(`INSERT INTO exchange_rates (currency, rate_date, rate) VALUES ($1, $2, $3)
+		ON CONFLICT (currency, rate_date) DO UPDATE SET rate = excluded.rate`))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range rates {
+		if _, err := stmt.Exec(r.Currency, r.Date, r.Rate); err != nil {
+			return fmt.Errorf("save rate %s %s: %v", r.Currency, r.Date, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetSetting 讀取設定，不存在時回傳 ErrNotFound
+func (s *sqlStore) GetSetting(key string) (string, error) {
+	var value string
+	err := s.queryRow(`SELECT value FROM settings WHERE key = $1 AND owner_id = $2`, key, s.owner).Scan(&value)
+	return value, notFound(err)
+}
+
+func (s *sqlStore) SetSetting(key, value string) error {
+	_, err := s.exec(`INSERT INTO settings (owner_id, key, value) VALUES ($1, $2, $3)
+		ON CONFLICT (owner_id, key) DO UPDATE SET value = excluded.value`, s.owner, key, value)
+	return err
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"poker_tracker_backend/models"
+	"testing"
+)
+
+// openTestRepository 在暫存目錄建立套用所有 migration 的 SQLite 資料庫
+func openTestRepository(t *testing.T) Repository {
+	t.Helper()
+	conn, err := Connect(Config{Driver: DriverSQLite, SQLitePath: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if _, err := Migrate(conn, DriverSQLite); err != nil {
+		t.Fatal(err)
+	}
+	return newRepository(conn, DriverSQLite)
+}
+
+func TestForUserIsolatesData(t *testing.T) {
+	repo := openTestRepository(t)
+	alice, err := repo.CreateUser("alice@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := repo.CreateUser("bob@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.Admin || bob.Admin {
+		t.Errorf("admin: got alice %v, bob %v; registering must not grant admin", alice.Admin, bob.Admin)
+	}
+	if _, err := repo.CreateUser("Alice@Example.com", "hash"); !errors.Is(err, ErrUserExists) {
+		t.Errorf("CreateUser with a registered email: got %v, want ErrUserExists", err)
+	}
+	asAlice, asBob := repo.ForUser(alice.ID), repo.ForUser(bob.ID)
+
+	session := models.Session{ID: "alice-session", Location: "Home", Date: "2024/05/01 20:00", SmallBlind: 1, BigBlind: 2, Currency: "USD"}
+	if err := asAlice.CreateSession(session); err != nil {
+		t.Fatal(err)
+	}
+	hand := models.Hand{ID: "alice-hand", SessionID: session.ID, Date: "2024-05-01T20:30:00Z", Result: 50}
+	if err := asAlice.CreateHand(hand); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bob 看不到、也改不到 Alice 的資料
+	if _, err := asBob.GetSession(session.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetSession: got %v, want ErrNotFound", err)
+	}
+	if _, err := asBob.GetHand(hand.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetHand: got %v, want ErrNotFound", err)
+	}
+	if sessions, err := asBob.ListSessions(); err != nil || len(sessions) != 0 {
+		t.Errorf("ListSessions: got %d sessions, %v", len(sessions), err)
+	}
+	if page, err := asBob.QueryHands(HandFilter{SessionID: session.ID}); err != nil || len(page.Items) != 0 {
+		t.Errorf("QueryHands: got %d hands, %v", len(page.Items), err)
+	}
+	if err := asBob.CreateHand(models.Hand{ID: "bob-hand", SessionID: session.ID}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("CreateHand in another user's session: got %v, want ErrNotFound", err)
+	}
+	if err := asBob.UpdateHand(hand.ID, models.Hand{Result: -1000}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateHand: got %v, want ErrNotFound", err)
+	}
+	if _, err := asBob.ToggleFavorite(hand.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ToggleFavorite: got %v, want ErrNotFound", err)
+	}
+	if err := asBob.DeleteHand(hand.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteHand: got %v, want ErrNotFound", err)
+	}
+	if err := asBob.DeleteSession(session.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteSession: got %v, want ErrNotFound", err)
+	}
+
+	got, err := asAlice.GetHand(hand.ID)
+	if err != nil {
+		t.Fatalf("alice lost her hand: %v", err)
+	}
+	if got.Result != 50 || got.Favorite {
+		t.Errorf("alice's hand was changed: result %d, favorite %v", got.Result, got.Favorite)
+	}
+	if _, err := asAlice.GetSession(session.ID); err != nil {
+		t.Errorf("alice lost her session: %v", err)
+	}
+}
+
+func TestAdminAndClaimAreExplicit(t *testing.T) {
+	repo := openTestRepository(t)
+	// 加上帳號功能之前的資料沒有擁有者
+	legacy := models.Session{ID: "legacy-session", Location: "Home", Date: "2024/05/01 20:00", SmallBlind: 1, BigBlind: 2, Currency: "USD"}
+	if err := repo.CreateSession(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	alice, err := repo.CreateUser("alice@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asAlice := repo.ForUser(alice.ID)
+	if _, err := asAlice.GetSession(legacy.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetSession before claim: got %v, want ErrNotFound", err)
+	}
+
+	claimed, err := repo.ClaimUnowned(alice.ID)
+	if err != nil || claimed != 1 {
+		t.Fatalf("ClaimUnowned: got %d, %v; want 1", claimed, err)
+	}
+	if _, err := asAlice.GetSession(legacy.ID); err != nil {
+		t.Errorf("GetSession after claim: %v", err)
+	}
+
+	if err := repo.SetAdmin(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if user, err := repo.GetUser(alice.ID); err != nil || !user.Admin {
+		t.Errorf("GetUser after SetAdmin: got admin %v, %v", user.Admin, err)
+	}
+	if err := repo.SetAdmin("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetAdmin for a missing user: got %v, want ErrNotFound", err)
+	}
+}
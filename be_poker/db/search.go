@@ -0,0 +1,243 @@
+package db
+
+import (
+	"fmt"
+	"poker_tracker_backend/models"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 搜尋欄位與權重：筆記最重要，其次是手牌描述，最後是 AI 分析
+// Postgres 以 setweight 標記 A/B/C，SQLite 依相同權重在程式中計分
+// searchColumn 的運算式只用於 SQLite 的 LIKE 比對
+const handSearchVector = `setweight(to_tsvector('simple', COALESCE(note, '')), 'A') || setweight(to_tsvector('simple', COALESCE(details, '')), 'B') || setweight(to_tsvector('simple', COALESCE(analysis, '')), 'C')`
+
+const sessionSearchVector = `setweight(to_tsvector('simple', COALESCE(location, '')), 'A') || setweight(to_tsvector('simple', COALESCE((` +
+	`SELECT string_agg(t.name, ' ') FROM session_tags l JOIN tags t ON t.id = l.tag_id WHERE l.session_id = CAST(sessions.id AS TEXT)), '')), 'B')`
+
+var handSearchColumns = []searchColumn{
+	{expr: `note`, weight: 1.0},
+	{expr: `details`, weight: 0.4},
+	{expr: `analysis`, weight: 0.2},
+}
+
+var sessionSearchColumns = []searchColumn{
+	{expr: `location`, weight: 1.0},
+	{expr: `(SELECT group_concat(t.name, ' ') FROM session_tags l JOIN tags t ON t.id = l.tag_id WHERE l.session_id = CAST(sessions.id AS TEXT))`, weight: 0.4},
+}
+
+type searchColumn struct {
+	expr   string
+	weight float64
+}
+
+// searchWords 把搜尋詞拆成字詞，只留下字母與數字
+func searchWords(term string) []string {
+	return strings.FieldsFunc(strings.ToLower(term), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// tsQuery 組出 to_tsquery 的字串：每個詞都是前綴比對，片語內的字詞需相鄰，詞與詞之間為 AND
+// 字詞只含字母與數字，不會混入 tsquery 運算子
+func tsQuery(terms []string) string {
+	parts := []string{}
+	for _, term := range terms {
+		words := searchWords(term)
+		if len(words) == 0 {
+			continue
+		}
+		for i := range words {
+			words[i] += ":*"
+		}
+		parts = append(parts, "("+strings.Join(words, " <-> ")+")")
+	}
+	return strings.Join(parts, " & ")
+}
+
+// likePattern 把搜尋詞轉成 LIKE 的 %term%，跳脫萬用字元
+func likePattern(term string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(strings.ToLower(term)) + "%"
+}
+
+// addTextMatch 加上 SQLite 的文字條件：每個搜尋詞都要出現在任一欄位
+func (c *conditions) addTextMatch(columns []searchColumn, terms []string) {
+	for _, term := range terms {
+		pattern := likePattern(term)
+		clauses := make([]string, len(columns))
+		args := make([]interface{}, len(columns))
+		for i, col := range columns {
+			clauses[i] = `LOWER(COALESCE(` + col.expr + `, '')) LIKE ? ESCAPE '\'`
+			args[i] = pattern
+		}
+		c.add("("+strings.Join(clauses, " OR ")+")", args...)
+	}
+}
+
+// textScore 是 SQLite 的計分：各欄位出現次數乘上權重
+func textScore(texts []string, columns []searchColumn, terms []string) float64 {
+	score := 0.0
+	for i, text := range texts {
+		lower := strings.ToLower(text)
+		for _, term := range terms {
+			score += float64(strings.Count(lower, strings.ToLower(term))) * columns[i].weight
+		}
+	}
+	return score
+}
+
+// rankScanner 在原本的欄位之後多讀一個分數
+type rankScanner struct {
+	row  rowScanner
+	rank float64
+}
+
+func (s *rankScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, &s.rank)...)
+}
+
+// searchTarget 描述一種可搜尋的資料
+type searchTarget struct {
+	table   string
+	columns string
+	vector  string
+	fields  []searchColumn
+	scan    func(row rowScanner) (models.SearchHit, error)
+	attach  func(hits []models.SearchHit) error // 補上標籤等關聯資料
+	texts   func(hit models.SearchHit) []string // SQLite 計分用的欄位文字，順序與 fields 相同
+}
+
+// SearchHands 在筆記、描述與分析中搜尋手牌，依相關程度排序
+// 沒有搜尋詞時只套用篩選條件，依建立時間由新到舊
+func (s *sqlStore) SearchHands(f HandFilter, terms []string, limit int) ([]models.SearchHit, int, error) {
+	return s.search(searchTarget{
+		table:   `hands`,
+		columns: handColumns,
+		vector:  handSearchVector,
+		fields:  handSearchColumns,
+		scan: func(row rowScanner) (models.SearchHit, error) {
+			hand, err := scanHand(row)
+			return models.SearchHit{Type: "hand", Hand: &hand}, err
+		},
+		attach: func(hits []models.SearchHit) error {
+			list := make([]models.Hand, len(hits))
+			for i, hit := range hits {
+				list[i] = *hit.Hand
+			}
+			if err := s.attachHandRelations(list, false); err != nil {
+				return err
+			}
+			for i := range hits {
+				hits[i].Hand = &list[i]
+			}
+			return nil
+		},
+		texts: func(hit models.SearchHit) []string {
+			note := ""
+			if hit.Hand.Note != nil {
+				note = *hit.Hand.Note
+			}
+			return []string{note, hit.Hand.Details, hit.Hand.Analysis}
+		},
+	}, f.conditions(s.owner), terms, limit)
+}
+
+// SearchSessions 在地點與標籤名稱中搜尋 session
+func (s *sqlStore) SearchSessions(f SessionFilter, terms []string, limit int) ([]models.SearchHit, int, error) {
+	return s.search(searchTarget{
+		table:   `sessions`,
+		columns: sessionColumns,
+		vector:  sessionSearchVector,
+		fields:  sessionSearchColumns,
+		scan: func(row rowScanner) (models.SearchHit, error) {
+			session, err := scanSession(row)
+			return models.SearchHit{Type: "session", Session: &session}, err
+		},
+		attach: func(hits []models.SearchHit) error {
+			list := make([]models.Session, len(hits))
+			for i, hit := range hits {
+				list[i] = *hit.Session
+			}
+			if err := s.attachTransactions(list, false); err != nil {
+				return err
+			}
+			if err := s.attachSessionTags(list, false); err != nil {
+				return err
+			}
+			for i := range hits {
+				hits[i].Session = &list[i]
+			}
+			return nil
+		},
+		texts: func(hit models.SearchHit) []string {
+			names := make([]string, len(hit.Session.Tags))
+			for i, tag := range hit.Session.Tags {
+				names[i] = tag.Name
+			}
+			return []string{hit.Session.Location, strings.Join(names, " ")}
+		},
+	}, f.conditions(s.owner), terms, limit)
+}
+
+// search 是兩種搜尋共用的流程
+// Postgres 用全文索引比對與 ts_rank 計分；SQLite 用 LIKE 比對，讀出後依 textScore 計分
+func (s *sqlStore) search(target searchTarget, c conditions, terms []string, limit int) ([]models.SearchHit, int, error) {
+	hits := []models.SearchHit{}
+	fullText := len(terms) > 0 && s.driver != DriverSQLite
+	scoreInGo := len(terms) > 0 && !fullText
+
+	rank, order := `0.0`, ` ORDER BY COALESCE(CAST(created_at AS TEXT), '') DESC, id`
+	if fullText {
+		c.add(target.vector+` @@ to_tsquery('simple', ?)`, tsQuery(terms))
+		rank = fmt.Sprintf(`ts_rank(%s, to_tsquery('simple', $%d))`, target.vector, len(c.args))
+		order = ` ORDER BY search_rank DESC, id`
+	} else if scoreInGo {
+		c.addTextMatch(target.fields, terms)
+	}
+
+	var total int
+	if err := s.queryRow(`SELECT COUNT(*) FROM `+target.table+c.sql(), c.args...).Scan(&total); err != nil {
+		return hits, 0, err
+	}
+
+	query := `SELECT ` + target.columns + `, ` + rank + ` AS search_rank FROM ` + target.table + c.sql() + order
+	// SQLite 要先讀出所有符合的資料才能計分排序
+	if limit > 0 && !scoreInGo {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := s.query(query, c.args...)
+	if err != nil {
+		return hits, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		scanner := &rankScanner{row: rows}
+		hit, err := target.scan(scanner)
+		if err != nil {
+			return hits, 0, err
+		}
+		hit.Score = scanner.rank
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return hits, 0, err
+	}
+	rows.Close()
+
+	if err := target.attach(hits); err != nil {
+		return hits, 0, err
+	}
+	if scoreInGo {
+		for i := range hits {
+			hits[i].Score = textScore(target.texts(hits[i]), target.fields, terms)
+		}
+		sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+		if limit > 0 && len(hits) > limit {
+			hits = hits[:limit]
+		}
+	}
+	return hits, total, nil
+}
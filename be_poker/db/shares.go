@@ -0,0 +1,75 @@
+package db
+
+import (
+	"fmt"
+	"poker_tracker_backend/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const shareColumns = `id, hand_id, owner_id, COALESCE(hide_hole_cards, false), COALESCE(hide_results, false), created_at, expires_at, revoked_at`
+
+func scanShare(row rowScanner) (models.HandShare, error) {
+	var share models.HandShare
+	err := row.Scan(&share.ID, &share.HandID, &share.OwnerID, &share.HideHoleCards, &share.HideResults, &share.CreatedAt, &share.ExpiresAt, &share.RevokedAt)
+	share.Active = share.RevokedAt == "" && share.ExpiresAt > time.Now().UTC().Format(time.RFC3339)
+	return share, err
+}
+
+// CreateHandShare 為目前使用者的手牌建立分享連結，資料庫只保存 token 的雜湊
+func (s *sqlStore) CreateHandShare(share models.HandShare, tokenHash string) (models.HandShare, error) {
+	var owned int
+	if err := s.queryRow(`SELECT COUNT(*) FROM hands WHERE id = $1 AND owner_id = $2`, share.HandID, s.owner).Scan(&owned); err != nil {
+		return share, err
+	}
+	if owned == 0 {
+		return share, fmt.Errorf("%w: hand %s", ErrNotFound, share.HandID)
+	}
+
+	share.ID = uuid.New().String()
+	share.OwnerID = s.owner
+	share.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	_, err := s.exec(`INSERT INTO hand_shares (id, token_hash, hand_id, owner_id, hide_hole_cards, hide_results, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		share.ID, tokenHash, share.HandID, share.OwnerID, share.HideHoleCards, share.HideResults, share.CreatedAt, share.ExpiresAt)
+	share.Active = true
+	return share, err
+}
+
+// ListHandShares 列出手牌的分享連結，包含已過期與已撤銷的
+func (s *sqlStore) ListHandShares(handID string) ([]models.HandShare, error) {
+	rows, err := s.query(`SELECT `+shareColumns+` FROM hand_shares WHERE hand_id = $1 AND owner_id = $2 ORDER BY created_at DESC, id`, handID, s.owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []models.HandShare{}
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+// RevokeHandShare 撤銷分享連結；已撤銷的連結維持原本的撤銷時間
+func (s *sqlStore) RevokeHandShare(handID, id string) error {
+	var owned int
+	if err := s.queryRow(`SELECT COUNT(*) FROM hand_shares WHERE id = $1 AND hand_id = $2 AND owner_id = $3`, id, handID, s.owner).Scan(&owned); err != nil {
+		return err
+	}
+	if owned == 0 {
+		return ErrNotFound
+	}
+	_, err := s.exec(`UPDATE hand_shares SET revoked_at = $1 WHERE id = $2 AND revoked_at = ''`, time.Now().UTC().Format(time.RFC3339), id)
+	return err
+}
+
+// HandShareByToken 以連結中 token 的雜湊找到分享，不限使用者；呼叫端要自行檢查 Active
+func (s *sqlStore) HandShareByToken(tokenHash string) (models.HandShare, error) {
+	share, err := scanShare(s.queryRow(`SELECT `+shareColumns+` FROM hand_shares WHERE token_hash = $1`, tokenHash))
+	return share, notFound(err)
+}
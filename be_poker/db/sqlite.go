@@ -0,0 +1,25 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	_ "modernc.org/sqlite" // 純 Go 的 SQLite driver，不需要 cgo
+)
+
+func openSQLite(path string) (*sql.DB, error) {
+	log.Printf("🗄️  Using embedded SQLite database: %s", path)
+
+	conn, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %v", err)
+	}
+	// SQLite 同時只允許一個寫入者
+	conn.SetMaxOpenConns(1)
+
+	if err = conn.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %v", err)
+	}
+	return conn, nil
+}
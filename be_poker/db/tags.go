@@ -0,0 +1,322 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"poker_tracker_backend/models"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrTagExists 表示已經有同名的標籤（名稱比對不分大小寫）
+var ErrTagExists = errors.New("tag already exists")
+
+// tagLinks 是手牌或 session 與標籤的關聯表
+type tagLinks struct {
+	table string
+	owner string
+}
+
+var (
+	handTagLinks    = tagLinks{table: "hand_tags", owner: "hand_id"}
+	sessionTagLinks = tagLinks{table: "session_tags", owner: "session_id"}
+)
+
+const tagColumns = `t.id, t.name, COALESCE(t.color, ''), COALESCE(t.category, ''),
+	(SELECT COUNT(*) FROM hand_tags WHERE tag_id = t.id),
+	(SELECT COUNT(*) FROM session_tags WHERE tag_id = t.id)`
+
+func scanTag(row rowScanner) (models.Tag, error) {
+	var t models.Tag
+	err := row.Scan(&t.ID, &t.Name, &t.Color, &t.Category, &t.Hands, &t.Sessions)
+	return t, err
+}
+
+// ListTags 列出所有標籤與使用次數，依分類與名稱排序
+func (s *sqlStore) ListTags() ([]models.Tag, error) {
+	rows, err := s.query(`SELECT `+tagColumns+` FROM tags t WHERE t.owner_id = $1 ORDER BY COALESCE(t.category, ''), t.name`, s.owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (s *sqlStore) GetTag(id string) (models.Tag, error) {
+	tag, err := scanTag(s.queryRow(`SELECT `+tagColumns+` FROM tags t WHERE t.id = $1 AND t.owner_id = $2`, id, s.owner))
+	return tag, notFound(err)
+}
+
+// tagIDByName 回傳同名標籤的 id，不存在時回傳空字串
+func (s *sqlStore) tagIDByName(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}, name string) (string, error) {
+	var id string
+	err := q.QueryRow(s.bind(`SELECT id FROM tags WHERE LOWER(name) = LOWER($1) AND owner_id = $2`), name, s.owner).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return id, err
+}
+
+// CreateTag 新增標籤並回傳含 id 的標籤
+func (s *sqlStore) CreateTag(tag models.Tag) (models.Tag, error) {
+	tag.Name = strings.TrimSpace(tag.Name)
+	existing, err := s.tagIDByName(s.db, tag.Name)
+	if err != nil {
+		return tag, err
+	}
+	if existing != "" {
+		return tag, ErrTagExists
+	}
+	tag.ID = uuid.New().String()
+	_, err = s.exec(`INSERT INTO tags (id, name, color, category, owner_id) VALUES ($1, $2, $3, $4, $5)`, tag.ID, tag.Name, tag.Color, tag.Category, s.owner)
+	return tag, err
+}
+
+// UpdateTag 修改標籤名稱、顏色與分類；改名成已存在的名稱時回傳 ErrTagExists，應改用合併
+func (s *sqlStore) UpdateTag(tag models.Tag) error {
+	tag.Name = strings.TrimSpace(tag.Name)
+	existing, err := s.tagIDByName(s.db, tag.Name)
+	if err != nil {
+		return err
+	}
+	if existing != "" && existing != tag.ID {
+		return ErrTagExists
+	}
+	result, err := s.exec(`UPDATE tags SET name = $1, color = $2, category = $3 WHERE id = $4 AND owner_id = $5`, tag.Name, tag.Color, tag.Category, tag.ID, s.owner)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteTag 刪除標籤，並從所有手牌與 session 移除
+func (s *sqlStore) DeleteTag(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(s.bind(`DELETE FROM tags WHERE id = $1 AND owner_id = $2`), id, s.owner)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	for _, links := range []tagLinks{handTagLinks, sessionTagLinks} {
+		if _, err := tx.Exec(s.bind(`DELETE FROM `+links.table+` WHERE tag_id = $1`), id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// MergeTags 把 sourceIDs 的標籤併入 targetID：關聯移到目標標籤，來源標籤刪除
+func (s *sqlStore) MergeTags(targetID string, sourceIDs []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(s.bind(`SELECT COUNT(*) FROM tags WHERE id = $1 AND owner_id = $2`), targetID, s.owner).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return fmt.Errorf("%w: tag %s", ErrNotFound, targetID)
+	}
+
+	for _, sourceID := range sourceIDs {
+		if sourceID == targetID {
+			continue
+		}
+		result, err := tx.Exec(s.bind(`DELETE FROM tags WHERE id = $1 AND owner_id = $2`), sourceID, s.owner)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 0 {
+			return fmt.Errorf("%w: tag %s", ErrNotFound, sourceID)
+		}
+		for _, links := range []tagLinks{handTagLinks, sessionTagLinks} {
+			// 已經有目標標籤的不重複加入
+			if _, err := tx.Exec(s.bind(`INSERT INTO `+links.table+` (`+links.owner+`, tag_id, position)
+				SELECT `+links.owner+`, $1, position FROM `+links.table+`
+				WHERE tag_id = $2 AND `+links.owner+` NOT IN (SELECT `+links.owner+` FROM `+links.table+` WHERE tag_id = $3)`),
+				targetID, sourceID, targetID); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(s.bind(`DELETE FROM `+links.table+` WHERE tag_id = $1`), sourceID); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// resolveTags 把寫入的標籤對應到既有標籤：有 id 用 id，否則用名稱，找不到的名稱會新增
+func (s *sqlStore) resolveTags(tx *sql.Tx, tags []models.Tag) ([]models.Tag, error) {
+	resolved := []models.Tag{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag.Name = strings.TrimSpace(tag.Name)
+		if tag.ID != "" {
+			err := tx.QueryRow(s.bind(`SELECT name, COALESCE(color, ''), COALESCE(category, '') FROM tags WHERE id = $1 AND owner_id = $2`), tag.ID, s.owner).
+				Scan(&tag.Name, &tag.Color, &tag.Category)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("%w: tag %s", ErrNotFound, tag.ID)
+			}
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			if tag.Name == "" {
+				continue
+			}
+			id, err := s.tagIDByName(tx, tag.Name)
+			if err != nil {
+				return nil, err
+			}
+			if id != "" {
+				if err := tx.QueryRow(s.bind(`SELECT name, COALESCE(color, ''), COALESCE(category, '') FROM tags WHERE id = $1`), id).
+					Scan(&tag.Name, &tag.Color, &tag.Category); err != nil {
+					return nil, err
+				}
+				tag.ID = id
+			} else {
+				tag.ID = uuid.New().String()
+				if _, err := tx.Exec(s.bind(`INSERT INTO tags (id, name, color, category, owner_id) VALUES ($1, $2, $3, $4, $5)`), tag.ID, tag.Name, tag.Color, tag.Category, s.owner); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if seen[tag.ID] {
+			continue
+		}
+		seen[tag.ID] = true
+		resolved = append(resolved, models.Tag{ID: tag.ID, Name: tag.Name, Color: tag.Color, Category: tag.Category})
+	}
+	return resolved, nil
+}
+
+// setTags 以 tags 取代 ownerID 原本的標籤，順序會保留
+func (s *sqlStore) setTags(tx *sql.Tx, links tagLinks, ownerID string, tags []models.Tag) error {
+	resolved, err := s.resolveTags(tx, tags)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.bind(`DELETE FROM `+links.table+` WHERE `+links.owner+` = $1`), ownerID); err != nil {
+		return err
+	}
+	for i, tag := range resolved {
+		if _, err := tx.Exec(s.bind(`INSERT INTO `+links.table+` (`+links.owner+`, tag_id, position) VALUES ($1, $2, $3)`), ownerID, tag.ID, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTags 讀取 ids 的標籤；ids 為 nil 時讀取全部
+func (s *sqlStore) loadTags(links tagLinks, ids []string) (map[string][]models.Tag, error) {
+	byOwner := map[string][]models.Tag{}
+	if ids != nil && len(ids) == 0 {
+		return byOwner, nil
+	}
+	var c conditions
+	c.add(`t.owner_id = ?`, s.owner)
+	if ids != nil {
+		args := make([]interface{}, len(ids))
+		marks := make([]string, len(ids))
+		for i, id := range ids {
+			args[i] = id
+			marks[i] = "?"
+		}
+		c.add(`l.`+links.owner+` IN (`+strings.Join(marks, ", ")+`)`, args...)
+	}
+	rows, err := s.query(`SELECT l.`+links.owner+`, t.id, t.name, COALESCE(t.color, ''), COALESCE(t.category, '')
+		FROM `+links.table+` l JOIN tags t ON t.id = l.tag_id`+c.sql()+` ORDER BY l.position, t.name`, c.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var owner string
+		var t models.Tag
+		if err := rows.Scan(&owner, &t.ID, &t.Name, &t.Color, &t.Category); err != nil {
+			return nil, err
+		}
+		byOwner[owner] = append(byOwner[owner], t)
+	}
+	return byOwner, rows.Err()
+}
+
+// attachHandTags 填入手牌的標籤；all 為 true 時一次讀取全部關聯，不逐一列出 id
+func (s *sqlStore) attachHandTags(hands []models.Hand, all bool) error {
+	var ids []string
+	if !all {
+		ids = make([]string, len(hands))
+		for i, hand := range hands {
+			ids[i] = hand.ID
+		}
+	}
+	byHand, err := s.loadTags(handTagLinks, ids)
+	if err != nil {
+		return err
+	}
+	for i := range hands {
+		hands[i].Tags = byHand[hands[i].ID]
+		hands[i].Tag = legacyTag(hands[i].Tags)
+	}
+	return nil
+}
+
+// attachSessionTags 填入 session 的標籤
+func (s *sqlStore) attachSessionTags(list []models.Session, all bool) error {
+	var ids []string
+	if !all {
+		ids = make([]string, len(list))
+		for i, session := range list {
+			ids[i] = session.ID
+		}
+	}
+	bySession, err := s.loadTags(sessionTagLinks, ids)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		list[i].Tags = bySession[list[i].ID]
+		list[i].Tag = legacyTag(list[i].Tags)
+	}
+	return nil
+}
+
+// legacyTag 是舊版單一 tag 欄位的值
+func legacyTag(tags []models.Tag) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return tags[0].Name
+}
+
+// tagCondition 篩選帶有指定名稱標籤的資料
+func tagCondition(links tagLinks, idColumn string) string {
+	return idColumn + ` IN (SELECT l.` + links.owner + ` FROM ` + links.table + ` l JOIN tags t ON t.id = l.tag_id WHERE LOWER(t.name) = LOWER(?))`
+}
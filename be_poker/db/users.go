@@ -0,0 +1,114 @@
+package db
+
+import (
+	"errors"
+	"poker_tracker_backend/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrUserExists 表示 email 已經註冊過（比對不分大小寫）
+var ErrUserExists = errors.New("user already exists")
+
+// ownedTables 是以 owner_id 區分使用者的資料表
+var ownedTables = []string{"sessions", "hands", "players", "tags", "settings"}
+
+// ForUser 回傳只讀寫 userID 資料的 Repository
+func (s *sqlStore) ForUser(userID string) Repository {
+	scoped := *s
+	scoped.owner = userID
+	return &scoped
+}
+
+// CreateUser 新增一般帳號；管理者與既有資料的擁有者由 SetAdmin、ClaimUnowned 另外指定
+func (s *sqlStore) CreateUser(email, passwordHash string) (models.User, error) {
+	user := models.User{ID: uuid.New().String(), Email: strings.TrimSpace(email)}
+
+	// email 有不分大小寫的唯一索引，同時註冊同一個 email 時只有一個會成功
+	if _, err := s.exec(`INSERT INTO users (id, email, password_hash) VALUES ($1, $2, $3)`, user.ID, user.Email, passwordHash); err != nil {
+		if _, _, lookupErr := s.UserByEmail(user.Email); lookupErr == nil {
+			return user, ErrUserExists
+		}
+		return user, err
+	}
+	return s.GetUser(user.ID)
+}
+
+// SetAdmin 將帳號設為管理者，可以修改所有人共用的匯率
+func (s *sqlStore) SetAdmin(userID string) error {
+	result, err := s.exec(`UPDATE users SET is_admin = TRUE WHERE id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ClaimUnowned 將還沒有擁有者的資料（加上帳號功能之前的資料）交給 userID，回傳接手的筆數
+func (s *sqlStore) ClaimUnowned(userID string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var claimed int64
+	for _, table := range ownedTables {
+		result, err := tx.Exec(s.bind(`UPDATE `+table+` SET owner_id = $1 WHERE COALESCE(owner_id, '') = ''`), userID)
+		if err != nil {
+			return 0, err
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			claimed += n
+		}
+	}
+	return claimed, tx.Commit()
+}
+
+func (s *sqlStore) GetUser(id string) (models.User, error) {
+	var user models.User
+	err := s.queryRow(`SELECT id, email, COALESCE(CAST(created_at AS TEXT), ''), COALESCE(is_admin, FALSE) FROM users WHERE id = $1`, id).
+		Scan(&user.ID, &user.Email, &user.CreatedAt, &user.Admin)
+	return user, notFound(err)
+}
+
+// UserByEmail 回傳帳號與密碼雜湊，給登入驗證使用
+func (s *sqlStore) UserByEmail(email string) (models.User, string, error) {
+	var user models.User
+	var passwordHash string
+	err := s.queryRow(`SELECT id, email, COALESCE(CAST(created_at AS TEXT), ''), COALESCE(is_admin, FALSE), password_hash FROM users WHERE LOWER(email) = LOWER($1)`, strings.TrimSpace(email)).
+		Scan(&user.ID, &user.Email, &user.CreatedAt, &user.Admin, &passwordHash)
+	return user, passwordHash, notFound(err)
+}
+
+func (s *sqlStore) CountUsers() (int, error) {
+	var count int
+	err := s.queryRow(`SELECT COUNT(*) FROM users`).Scan(&count)
+	return count, err
+}
+
+// CreateAuthToken 儲存登入 token 的雜湊，資料庫裡不保存 token 本身
+func (s *sqlStore) CreateAuthToken(userID, tokenHash string, expiresAt time.Time) error {
+	_, err := s.exec(`INSERT INTO auth_tokens (token_hash, user_id, created_at, expires_at) VALUES ($1, $2, $3, $4)`,
+		tokenHash, userID, time.Now().UTC().Format(time.RFC3339), expiresAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+// UserByToken 回傳 token 所屬的帳號，token 不存在或已過期時回傳 ErrNotFound
+func (s *sqlStore) UserByToken(tokenHash string) (models.User, error) {
+	var user models.User
+	err := s.queryRow(`SELECT u.id, u.email, COALESCE(CAST(u.created_at AS TEXT), ''), COALESCE(u.is_admin, FALSE) FROM auth_tokens t JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = $1 AND t.expires_at > $2`, tokenHash, time.Now().UTC().Format(time.RFC3339)).
+		Scan(&user.ID, &user.Email, &user.CreatedAt, &user.Admin)
+	return user, notFound(err)
+}
+
+// DeleteAuthToken 撤銷 token，順便清除已過期的 token
+func (s *sqlStore) DeleteAuthToken(tokenHash string) error {
+	_, err := s.exec(`DELETE FROM auth_tokens WHERE token_hash = $1 OR expires_at <= $2`, tokenHash, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
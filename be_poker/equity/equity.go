@@ -0,0 +1,294 @@
+package equity
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"poker_tracker_backend/cards"
+	"poker_tracker_backend/evaluator"
+)
+
+// MaxExactEvaluations 是完整列舉的上限（手牌組合數 × 公共牌組合數），超過時改用 Monte Carlo
+const MaxExactEvaluations = 3000000
+
+// DefaultIterations 是 Monte Carlo 預設的模擬次數
+const DefaultIterations = 100000
+
+// MaxIterations 是 API 可以要求的最大模擬次數
+const MaxIterations = 1000000
+
+// checkEvery 是檢查 Context 是否已取消的間隔（樣本數）
+const checkEvery = 4096
+
+// Options 控制計算方式
+type Options struct {
+	Iterations int   // Monte Carlo 模擬次數
+	Seed       int64 // 相同的 seed 會得到相同的結果
+	MaxExact   int   // 完整列舉的上限，0 表示使用 MaxExactEvaluations
+	// Context 取消時（例如使用者離開）停止計算並回傳 ctx.Err()；nil 表示不會取消
+	Context context.Context
+}
+
+// PlayerResult 是單一玩家的勝率
+type PlayerResult struct {
+	Win    float64 // 獨贏的比例
+	Tie    float64 // 平分的比例
+	Lose   float64
+	Equity float64 // 獨贏加上平分時分到的份額
+}
+
+// Result 是一次計算的結果
+type Result struct {
+	Players []PlayerResult
+	Method  string // exact 或 monte_carlo
+	Samples int
+	Seed    int64
+}
+
+// Calculate 計算每位玩家的勝率
+// 每位玩家可以是一組手牌或一個範圍；board 可以是 0、3、4 或 5 張
+func Calculate(players [][]Combo, board []cards.Card, opts Options) (Result, error) {
+	if len(players) < 2 {
+		return Result{}, fmt.Errorf("at least two players are required")
+	}
+	if len(board) > 5 || len(board) == 1 || len(board) == 2 {
+		return Result{}, fmt.Errorf("board must have 0, 3, 4 or 5 cards")
+	}
+
+	var boardMask uint64
+	for _, c := range board {
+		if boardMask&(1<<uint(c)) != 0 {
+			return Result{}, fmt.Errorf("duplicate board card %s", c)
+		}
+		boardMask |= 1 << uint(c)
+	}
+
+	// 移除與公共牌衝突的組合
+	filtered := make([][]Combo, len(players))
+	for i, combos := range players {
+		for _, c := range combos {
+			if !c.conflicts(boardMask) && c[0] != c[1] {
+				filtered[i] = append(filtered[i], c)
+			}
+		}
+		if len(filtered[i]) == 0 {
+			return Result{}, fmt.Errorf("player %d has no hands left after removing board cards", i+1)
+		}
+	}
+
+	maxExact := float64(opts.MaxExact)
+	if maxExact <= 0 {
+		maxExact = MaxExactEvaluations
+	}
+
+	missing := 5 - len(board)
+	deckLeft := 52 - len(board) - 2*len(players)
+	total := float64(binomial(deckLeft, missing))
+	for _, combos := range filtered {
+		total *= float64(len(combos))
+		if total > maxExact {
+			break
+		}
+	}
+
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	acc := newAccumulator(len(players))
+	if total <= maxExact {
+		if err := enumerate(ctx, filtered, board, boardMask, acc); err != nil {
+			return Result{}, err
+		}
+		if acc.samples == 0 {
+			return Result{}, fmt.Errorf("players' hands share cards")
+		}
+		return acc.result("exact", 0), nil
+	}
+
+	iterations := opts.Iterations
+	if iterations <= 0 {
+		iterations = DefaultIterations
+	}
+	if err := simulate(ctx, filtered, board, boardMask, iterations, opts.Seed, acc); err != nil {
+		return Result{}, err
+	}
+	return acc.result("monte_carlo", opts.Seed), nil
+}
+
+type accumulator struct {
+	wins, ties, shares []float64
+	samples            int
+	values             []evaluator.Value
+	hand               [7]cards.Card
+}
+
+func newAccumulator(n int) *accumulator {
+	return &accumulator{
+		wins:   make([]float64, n),
+		ties:   make([]float64, n),
+		shares: make([]float64, n),
+		values: make([]evaluator.Value, n),
+	}
+}
+
+// record 比較所有玩家在一個完整牌面的牌力
+func (a *accumulator) record(hands []Combo, board []cards.Card) {
+	best := evaluator.Value(0)
+	copy(a.hand[2:], board)
+	for i, h := range hands {
+		a.hand[0], a.hand[1] = h[0], h[1]
+		a.values[i] = evaluator.Evaluate(a.hand[:2+len(board)])
+		if a.values[i] > best {
+			best = a.values[i]
+		}
+	}
+	winners := 0
+	for _, v := range a.values {
+		if v == best {
+			winners++
+		}
+	}
+	for i, v := range a.values {
+		if v != best {
+			continue
+		}
+		if winners == 1 {
+			a.wins[i]++
+		} else {
+			a.ties[i]++
+		}
+		a.shares[i] += 1 / float64(winners)
+	}
+	a.samples++
+}
+
+func (a *accumulator) result(method string, seed int64) Result {
+	r := Result{Method: method, Samples: a.samples}
+	if method == "monte_carlo" {
+		r.Seed = seed
+	}
+	n := float64(a.samples)
+	for i := range a.wins {
+		p := PlayerResult{}
+		if n > 0 {
+			p.Win = a.wins[i] / n
+			p.Tie = a.ties[i] / n
+			p.Lose = 1 - p.Win - p.Tie
+			p.Equity = a.shares[i] / n
+		}
+		r.Players = append(r.Players, p)
+	}
+	return r
+}
+
+// enumerate 列舉所有不衝突的手牌組合與剩下的公共牌
+func enumerate(ctx context.Context, players [][]Combo, board []cards.Card, boardMask uint64, acc *accumulator) error {
+	var err error
+	hands := make([]Combo, len(players))
+	full := make([]cards.Card, 5)
+	copy(full, board)
+
+	var dealBoard func(start, idx int, used uint64)
+	dealBoard = func(start, idx int, used uint64) {
+		if err != nil {
+			return
+		}
+		if idx == 5 {
+			acc.record(hands, full)
+			if acc.samples%checkEvery == 0 {
+				err = ctx.Err()
+			}
+			return
+		}
+		for c := start; c < 52; c++ {
+			if used&(1<<uint(c)) != 0 {
+				continue
+			}
+			full[idx] = cards.Card(c)
+			dealBoard(c+1, idx+1, used)
+		}
+	}
+
+	var assign func(i int, used uint64)
+	assign = func(i int, used uint64) {
+		if i == len(players) {
+			dealBoard(0, len(board), used)
+			return
+		}
+		for _, c := range players[i] {
+			if err != nil {
+				return
+			}
+			if c.conflicts(used) {
+				continue
+			}
+			hands[i] = c
+			assign(i+1, used|c.mask())
+		}
+	}
+	assign(0, boardMask)
+	return err
+}
+
+// simulate 以固定 seed 隨機抽樣手牌組合與公共牌
+func simulate(ctx context.Context, players [][]Combo, board []cards.Card, boardMask uint64, iterations int, seed int64, acc *accumulator) error {
+	rng := rand.New(rand.NewSource(seed))
+	hands := make([]Combo, len(players))
+	full := make([]cards.Card, 5)
+	copy(full, board)
+	deck := make([]cards.Card, 0, 52)
+
+	failures := 0
+	for acc.samples < iterations {
+		if acc.samples%checkEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		used := boardMask
+		ok := true
+		for i, combos := range players {
+			c := combos[rng.Intn(len(combos))]
+			if c.conflicts(used) {
+				ok = false
+				break
+			}
+			hands[i] = c
+			used |= c.mask()
+		}
+		if !ok {
+			// 範圍之間互相衝突太多時避免無窮迴圈
+			failures++
+			if failures > iterations*100 {
+				return fmt.Errorf("ranges overlap too much to sample")
+			}
+			continue
+		}
+
+		deck = deck[:0]
+		for c := 0; c < 52; c++ {
+			if used&(1<<uint(c)) == 0 {
+				deck = append(deck, cards.Card(c))
+			}
+		}
+		for idx := len(board); idx < 5; idx++ {
+			j := idx - len(board) + rng.Intn(len(deck)-(idx-len(board)))
+			deck[idx-len(board)], deck[j] = deck[j], deck[idx-len(board)]
+			full[idx] = deck[idx-len(board)]
+		}
+		acc.record(hands, full)
+	}
+	return nil
+}
+
+func binomial(n, k int) int {
+	if k < 0 || k > n {
+		return 0
+	}
+	result := 1
+	for i := 1; i <= k; i++ {
+		result = result * (n - k + i) / i
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+package equity
+
+import (
+	"context"
+	"math"
+	"poker_tracker_backend/cards"
+	"testing"
+)
+
+func mustRange(t *testing.T, s string) []Combo {
+	t.Helper()
+	combos, err := ParseRange(s)
+	if err != nil {
+		t.Fatalf("ParseRange(%q): %v", s, err)
+	}
+	return combos
+}
+
+func mustBoard(t *testing.T, s string) []cards.Card {
+	t.Helper()
+	board, err := cards.ParseList(s)
+	if err != nil {
+		t.Fatalf("ParseList(%q): %v", s, err)
+	}
+	return board
+}
+
+func TestCalculateKnownMatchups(t *testing.T) {
+	tests := []struct {
+		name    string
+		hero    string
+		villain string
+		board   string
+		equity  float64 // Hero 的勝率
+	}{
+		{"AA vs KK", "AhAd", "KsKc", "", 0.82},
+		{"AKo vs QQ", "AhKd", "QsQc", "", 0.43},
+		{"set vs overpair on the flop", "7h7d", "AsAc", "7c2s9h", 0.91},
+		{"flush draw vs set on the turn", "AhKh", "QsQc", "Qh7h2s3d", 7.0 / 44}, // 2h、3h 讓對手成葫蘆
+	}
+	for _, tt := range tests {
+		result, err := Calculate([][]Combo{mustRange(t, tt.hero), mustRange(t, tt.villain)}, mustBoard(t, tt.board), Options{Iterations: 200000, Seed: 1})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		hero, villain := result.Players[0].Equity, result.Players[1].Equity
+		if math.Abs(hero-tt.equity) > 0.01 {
+			t.Errorf("%s: hero equity %.4f, want about %.2f (%s)", tt.name, hero, tt.equity, result.Method)
+		}
+		if math.Abs(hero+villain-1) > 1e-9 {
+			t.Errorf("%s: equities sum to %.6f", tt.name, hero+villain)
+		}
+	}
+}
+
+func TestCalculateMonteCarloIsReproducible(t *testing.T) {
+	// 兩個範圍的組合數乘上公共牌組合數超過 MaxExactEvaluations，改用 Monte Carlo
+	players := [][]Combo{mustRange(t, "AA"), mustRange(t, "KK")}
+	opts := Options{Iterations: 20000, Seed: 42}
+	a, err := Calculate(players, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Calculate(players, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Method != "monte_carlo" || a.Players[0].Equity != b.Players[0].Equity {
+		t.Errorf("got %s %.4f and %.4f, want the same Monte Carlo result", a.Method, a.Players[0].Equity, b.Players[0].Equity)
+	}
+	if math.Abs(a.Players[0].Equity-0.82) > 0.02 {
+		t.Errorf("hero equity %.4f, want about 0.82", a.Players[0].Equity)
+	}
+}
+
+func TestCalculateStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	players := [][]Combo{mustRange(t, "AhAd"), mustRange(t, "KsKc")}
+	if _, err := Calculate(players, nil, Options{Context: ctx}); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,199 @@
+package equity
+
+import (
+	"fmt"
+	"poker_tracker_backend/cards"
+	"strings"
+)
+
+// Combo 是一組兩張的起手牌
+type Combo [2]cards.Card
+
+func (c Combo) conflicts(used uint64) bool {
+	return used&(1<<uint(c[0])) != 0 || used&(1<<uint(c[1])) != 0
+}
+
+func (c Combo) mask() uint64 {
+	return 1<<uint(c[0]) | 1<<uint(c[1])
+}
+
+const rankOrder = "23456789TJQKA"
+
+// ParseRange 解析手牌範圍，例如 "TT+, AQs+, KJo, 76s, A5s-A2s, AsKs"
+// 也接受單一組具體的手牌，例如 "A♠ K♠"
+func ParseRange(s string) ([]Combo, error) {
+	// 先嘗試當作具體的兩張牌
+	if list, err := cards.ParseList(s); err == nil && len(list) == 2 {
+		if list[0] == list[1] {
+			return nil, fmt.Errorf("duplicate card in %q", s)
+		}
+		return []Combo{{list[0], list[1]}}, nil
+	}
+
+	seen := map[[2]cards.Card]bool{}
+	combos := []Combo{}
+	add := func(c Combo) {
+		key := [2]cards.Card{c[0], c[1]}
+		if c[0] > c[1] {
+			key = [2]cards.Card{c[1], c[0]}
+		}
+		if !seen[key] {
+			seen[key] = true
+			combos = append(combos, c)
+		}
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		list, err := expandToken(part)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range list {
+			add(c)
+		}
+	}
+	if len(combos) == 0 {
+		return nil, fmt.Errorf("empty range %q", s)
+	}
+	return combos, nil
+}
+
+// handClass 是 "AKs"、"QQ"、"T9o" 這類不分花色的手牌
+type handClass struct {
+	high, low int    // 點數 2-14
+	kind      string // "s" 同花、"o" 不同花、"" 兩者皆可（對子固定為 ""）
+}
+
+func parseClass(s string) (handClass, error) {
+	if len(s) < 2 || len(s) > 3 {
+		return handClass{}, fmt.Errorf("invalid hand %q", s)
+	}
+	a := strings.IndexByte(rankOrder, upper(s[0]))
+	b := strings.IndexByte(rankOrder, upper(s[1]))
+	if a < 0 || b < 0 {
+		return handClass{}, fmt.Errorf("invalid hand %q", s)
+	}
+	hc := handClass{high: a + 2, low: b + 2}
+	if hc.low > hc.high {
+		hc.high, hc.low = hc.low, hc.high
+	}
+	if len(s) == 3 {
+		switch s[2] {
+		case 's', 'S':
+			hc.kind = "s"
+		case 'o', 'O':
+			hc.kind = "o"
+		default:
+			return handClass{}, fmt.Errorf("invalid suitedness in %q", s)
+		}
+	}
+	if hc.high == hc.low && hc.kind != "" {
+		return handClass{}, fmt.Errorf("pairs cannot be suited or offsuit: %q", s)
+	}
+	return hc, nil
+}
+
+func upper(b byte) byte {
+	if b >= 'a' && b <= 'z' {
+		return b - 'a' + 'A'
+	}
+	return b
+}
+
+// expandToken 展開單一範圍項目
+func expandToken(tok string) ([]Combo, error) {
+	// 具體的一組牌，例如 AsKs
+	if list, err := cards.ParseList(tok); err == nil && len(list) == 2 {
+		return []Combo{{list[0], list[1]}}, nil
+	}
+
+	classes := []handClass{}
+	switch {
+	case strings.HasSuffix(tok, "+"):
+		hc, err := parseClass(strings.TrimSuffix(tok, "+"))
+		if err != nil {
+			return nil, err
+		}
+		if hc.high == hc.low {
+			// TT+ → TT, JJ, ..., AA
+			for r := hc.high; r <= 14; r++ {
+				classes = append(classes, handClass{high: r, low: r})
+			}
+		} else {
+			// A9s+ → A9s, ATs, ..., AKs
+			for r := hc.low; r < hc.high; r++ {
+				classes = append(classes, handClass{high: hc.high, low: r, kind: hc.kind})
+			}
+		}
+	case strings.Contains(tok, "-"):
+		ends := strings.SplitN(tok, "-", 2)
+		from, err := parseClass(strings.TrimSpace(ends[0]))
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseClass(strings.TrimSpace(ends[1]))
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case from.high == from.low && to.high == to.low:
+			// 22-55
+			lo, hi := from.high, to.high
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			for r := lo; r <= hi; r++ {
+				classes = append(classes, handClass{high: r, low: r})
+			}
+		case from.high == to.high && from.kind == to.kind:
+			// A2s-A5s
+			lo, hi := from.low, to.low
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			for r := lo; r <= hi; r++ {
+				classes = append(classes, handClass{high: from.high, low: r, kind: from.kind})
+			}
+		default:
+			return nil, fmt.Errorf("invalid range %q", tok)
+		}
+	default:
+		hc, err := parseClass(tok)
+		if err != nil {
+			return nil, err
+		}
+		classes = append(classes, hc)
+	}
+
+	combos := []Combo{}
+	for _, hc := range classes {
+		combos = append(combos, hc.combos()...)
+	}
+	return combos, nil
+}
+
+func (hc handClass) combos() []Combo {
+	list := []Combo{}
+	for s1 := 0; s1 < 4; s1++ {
+		for s2 := 0; s2 < 4; s2++ {
+			if hc.high == hc.low {
+				if s2 <= s1 {
+					continue
+				}
+			} else {
+				if hc.kind == "s" && s1 != s2 {
+					continue
+				}
+				if hc.kind == "o" && s1 == s2 {
+					continue
+				}
+			}
+			list = append(list, Combo{cards.New(hc.high, s1), cards.New(hc.low, s2)})
+		}
+	}
+	return list
+}
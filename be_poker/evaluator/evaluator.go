@@ -0,0 +1,209 @@
+package evaluator
+
+import (
+	"fmt"
+	"math/bits"
+	"poker_tracker_backend/cards"
+)
+
+// Category 是牌型
+type Category int
+
+const (
+	HighCard Category = iota
+	OnePair
+	TwoPair
+	ThreeOfAKind
+	Straight
+	Flush
+	FullHouse
+	FourOfAKind
+	StraightFlush
+)
+
+var categoryNames = []string{
+	"High Card",
+	"One Pair",
+	"Two Pair",
+	"Three of a Kind",
+	"Straight",
+	"Flush",
+	"Full House",
+	"Four of a Kind",
+	"Straight Flush",
+}
+
+func (c Category) String() string {
+	if c < 0 || int(c) >= len(categoryNames) {
+		return "Unknown"
+	}
+	return categoryNames[c]
+}
+
+// Value 是可直接比較大小的牌力，數值越大越強
+// 編碼方式：牌型 << 20，接著五個 4-bit 的點數（由高到低）
+type Value uint32
+
+// Category 回傳牌型
+func (v Value) Category() Category {
+	return Category(v >> 20)
+}
+
+// Ranks 回傳決定勝負的五個點數，由重要到次要
+func (v Value) Ranks() []int {
+	ranks := make([]int, 0, 5)
+	for shift := 16; shift >= 0; shift -= 4 {
+		if r := int(v>>uint(shift)) & 0xF; r != 0 {
+			ranks = append(ranks, r)
+		}
+	}
+	return ranks
+}
+
+func makeValue(c Category, ranks ...int) Value {
+	v := Value(c) << 20
+	shift := 16
+	for _, r := range ranks {
+		v |= Value(r) << uint(shift)
+		shift -= 4
+	}
+	return v
+}
+
+// Evaluate 計算 5 到 7 張牌中最好的五張牌牌力
+func Evaluate(hand []cards.Card) Value {
+	var suitMasks [4]uint16
+	var counts [15]int
+	var rankMask uint16
+	for _, c := range hand {
+		r := c.Rank()
+		suitMasks[c.Suit()] |= 1 << uint(r)
+		counts[r]++
+		rankMask |= 1 << uint(r)
+	}
+
+	// 同花與同花順
+	for _, mask := range suitMasks {
+		if bits.OnesCount16(mask) >= 5 {
+			if high := straightHigh(mask); high > 0 {
+				return makeValue(StraightFlush, high)
+			}
+			return makeValue(Flush, topRanks(mask, 5)...)
+		}
+	}
+
+	var quads, trips, pairs []int
+	for r := 14; r >= 2; r-- {
+		switch counts[r] {
+		case 4:
+			quads = append(quads, r)
+		case 3:
+			trips = append(trips, r)
+		case 2:
+			pairs = append(pairs, r)
+		}
+	}
+
+	if len(quads) > 0 {
+		return makeValue(FourOfAKind, append([]int{quads[0]}, topRanks(rankMask&^(1<<uint(quads[0])), 1)...)...)
+	}
+	if len(trips) > 0 && (len(trips) > 1 || len(pairs) > 0) {
+		pair := 0
+		if len(pairs) > 0 {
+			pair = pairs[0]
+		}
+		// 兩組三條時，第二組當作對子
+		if len(trips) > 1 && trips[1] > pair {
+			pair = trips[1]
+		}
+		return makeValue(FullHouse, trips[0], pair)
+	}
+	if high := straightHigh(rankMask); high > 0 {
+		return makeValue(Straight, high)
+	}
+	if len(trips) > 0 {
+		return makeValue(ThreeOfAKind, append([]int{trips[0]}, topRanks(rankMask&^(1<<uint(trips[0])), 2)...)...)
+	}
+	if len(pairs) >= 2 {
+		rest := rankMask &^ (1<<uint(pairs[0]) | 1<<uint(pairs[1]))
+		return makeValue(TwoPair, pairs[0], pairs[1], topRanks(rest, 1)[0])
+	}
+	if len(pairs) == 1 {
+		return makeValue(OnePair, append([]int{pairs[0]}, topRanks(rankMask&^(1<<uint(pairs[0])), 3)...)...)
+	}
+	return makeValue(HighCard, topRanks(rankMask, 5)...)
+}
+
+// straightHigh 回傳順子的最大點數，沒有順子時回傳 0；A 可以當 1 用
+func straightHigh(mask uint16) int {
+	if mask&(1<<14) != 0 {
+		mask |= 1 << 1
+	}
+	for high := 14; high >= 5; high-- {
+		window := uint16(0x1F) << uint(high-4)
+		if mask&window == window {
+			return high
+		}
+	}
+	return 0
+}
+
+// topRanks 由大到小取出 n 個點數
+func topRanks(mask uint16, n int) []int {
+	ranks := make([]int, 0, n)
+	for r := 14; r >= 2 && len(ranks) < n; r-- {
+		if mask&(1<<uint(r)) != 0 {
+			ranks = append(ranks, r)
+		}
+	}
+	return ranks
+}
+
+var rankNames = map[int][2]string{
+	2:  {"Two", "Twos"},
+	3:  {"Three", "Threes"},
+	4:  {"Four", "Fours"},
+	5:  {"Five", "Fives"},
+	6:  {"Six", "Sixes"},
+	7:  {"Seven", "Sevens"},
+	8:  {"Eight", "Eights"},
+	9:  {"Nine", "Nines"},
+	10: {"Ten", "Tens"},
+	11: {"Jack", "Jacks"},
+	12: {"Queen", "Queens"},
+	13: {"King", "Kings"},
+	14: {"Ace", "Aces"},
+}
+
+// Describe 回傳可讀的牌型描述，例如 "Full House, Kings full of Sevens"
+func (v Value) Describe() string {
+	r := v.Ranks()
+	if len(r) == 0 {
+		return v.Category().String()
+	}
+	one := func(i int) string { return rankNames[r[i]][0] }
+	many := func(i int) string { return rankNames[r[i]][1] }
+
+	switch v.Category() {
+	case StraightFlush:
+		if r[0] == 14 {
+			return "Royal Flush"
+		}
+		return fmt.Sprintf("Straight Flush, %s high", one(0))
+	case FourOfAKind:
+		return fmt.Sprintf("Four of a Kind, %s", many(0))
+	case FullHouse:
+		return fmt.Sprintf("Full House, %s full of %s", many(0), many(1))
+	case Flush:
+		return fmt.Sprintf("Flush, %s high", one(0))
+	case Straight:
+		return fmt.Sprintf("Straight, %s high", one(0))
+	case ThreeOfAKind:
+		return fmt.Sprintf("Three of a Kind, %s", many(0))
+	case TwoPair:
+		return fmt.Sprintf("Two Pair, %s and %s", many(0), many(1))
+	case OnePair:
+		return fmt.Sprintf("Pair of %s", many(0))
+	}
+	return fmt.Sprintf("High Card, %s", one(0))
+}
@@ -0,0 +1,83 @@
+package evaluator
+
+import (
+	"poker_tracker_backend/cards"
+	"testing"
+)
+
+// 52 張牌所有 2,598,960 種五張牌組合的牌型數量
+func TestEvaluateFiveCardCategoryCounts(t *testing.T) {
+	want := map[Category]int{
+		StraightFlush: 40,
+		FourOfAKind:   624,
+		FullHouse:     3744,
+		Flush:         5108,
+		Straight:      10200,
+		ThreeOfAKind:  54912,
+		TwoPair:       123552,
+		OnePair:       1098240,
+		HighCard:      1302540,
+	}
+
+	deck := cards.Deck()
+	got := map[Category]int{}
+	hand := make([]cards.Card, 5)
+	for a := 0; a < len(deck); a++ {
+		for b := a + 1; b < len(deck); b++ {
+			for c := b + 1; c < len(deck); c++ {
+				for d := c + 1; d < len(deck); d++ {
+					for e := d + 1; e < len(deck); e++ {
+						hand[0], hand[1], hand[2], hand[3], hand[4] = deck[a], deck[b], deck[c], deck[d], deck[e]
+						got[Evaluate(hand).Category()]++
+					}
+				}
+			}
+		}
+	}
+
+	for category, n := range want {
+		if got[category] != n {
+			t.Errorf("%s: got %d hands, want %d", category, got[category], n)
+		}
+	}
+}
+
+func TestEvaluateBestFiveOfSeven(t *testing.T) {
+	tests := []struct {
+		hand     string
+		category Category
+		ranks    []int
+	}{
+		{"AhKhQhJhTh2c3d", StraightFlush, []int{14}},
+		{"Ah2c3d4s5h9cKd", Straight, []int{5}},
+		{"AhAdAcKsKdQhQd", FullHouse, []int{14, 13}},
+		{"2h4h6h8hTh3hKs", Flush, []int{10, 8, 6, 4, 3}},
+		{"9s9d8c8h7s7dAc", TwoPair, []int{9, 8, 14}},
+	}
+	for _, tt := range tests {
+		hand, err := cards.ParseList(tt.hand)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.hand, err)
+		}
+		v := Evaluate(hand)
+		if v.Category() != tt.category {
+			t.Errorf("%s: got %s, want %s", tt.hand, v.Category(), tt.category)
+			continue
+		}
+		if ranks := v.Ranks(); len(ranks) < len(tt.ranks) || !equalInts(ranks[:len(tt.ranks)], tt.ranks) {
+			t.Errorf("%s: got ranks %v, want %v", tt.hand, ranks, tt.ranks)
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
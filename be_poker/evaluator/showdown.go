@@ -0,0 +1,112 @@
+package evaluator
+
+import (
+	"fmt"
+	"poker_tracker_backend/cards"
+	"poker_tracker_backend/models"
+)
+
+// Showdown 計算手牌中 Hero 與已知手牌的對手在攤牌時的牌型與贏家
+// 公共牌不足五張時只回傳目前的牌型，不判定贏家
+func Showdown(hand models.Hand) (models.Showdown, error) {
+	result := models.Showdown{HandID: hand.ID, Players: []models.PlayerHand{}, Winners: []string{}}
+
+	board := []cards.Card{}
+	if hand.Board != nil && *hand.Board != "" {
+		list, err := cards.ParseList(*hand.Board)
+		if err != nil {
+			return result, fmt.Errorf("board: %v", err)
+		}
+		board = list
+	}
+	result.Board = cards.Format(board)
+
+	type contender struct {
+		player models.PlayerHand
+		value  Value
+	}
+	contenders := []contender{}
+
+	add := func(name, position, holeCards string) error {
+		if holeCards == "" {
+			return nil
+		}
+		list, err := cards.ParseList(holeCards)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		player := models.PlayerHand{Player: name, Position: position, HoleCards: cards.Format(list)}
+		if len(list)+len(board) < 5 {
+			contenders = append(contenders, contender{player: player})
+			return nil
+		}
+		value := Evaluate(append(append([]cards.Card{}, list...), board...))
+		player.Category = value.Category().String()
+		player.MadeHand = value.Describe()
+		player.Value = uint32(value)
+		contenders = append(contenders, contender{player: player, value: value})
+		return nil
+	}
+
+	heroPosition := ""
+	if hand.Position != nil {
+		heroPosition = *hand.Position
+	}
+	heroCards := ""
+	if hand.HoleCards != nil {
+		heroCards = *hand.HoleCards
+	}
+	if err := add("hero", heroPosition, heroCards); err != nil {
+		return result, err
+	}
+	for i, v := range hand.Villains {
+		name := v.ID
+		if name == "" {
+			name = fmt.Sprintf("villain_%d", i+1)
+		}
+		if err := add(name, v.Position, v.HoleCards); err != nil {
+			return result, err
+		}
+	}
+
+	for _, c := range contenders {
+		result.Players = append(result.Players, c.player)
+	}
+
+	// 至少兩位玩家有手牌且公共牌發完才判定贏家
+	if len(board) < 5 || len(contenders) < 2 {
+		return result, nil
+	}
+	result.Complete = true
+
+	best := Value(0)
+	for _, c := range contenders {
+		if c.value > best {
+			best = c.value
+		}
+	}
+	heroWon := false
+	for i, c := range contenders {
+		if c.value == best {
+			result.Players[i].Winner = true
+			result.Winners = append(result.Winners, c.player.Player)
+			if c.player.Player == "hero" {
+				heroWon = true
+			}
+		}
+	}
+	result.Split = len(result.Winners) > 1
+
+	// 記錄的輸贏方向與攤牌結果相反時提出警告；平分底池時不檢查
+	if contenders[0].player.Player == "hero" && contenders[0].value != 0 && !result.Split {
+		switch {
+		case heroWon && hand.Result < 0:
+			result.ResultMismatch = true
+			result.Warning = "hero wins the showdown but the recorded result is a loss"
+		case !heroWon && hand.Result > 0:
+			result.ResultMismatch = true
+			result.Warning = "hero loses the showdown but the recorded result is a win"
+		}
+	}
+	return result, nil
+}
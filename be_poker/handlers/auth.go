@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"poker_tracker_backend/auth"
+	"poker_tracker_backend/db"
+	"poker_tracker_backend/models"
+	"time"
+)
+
+// userRepo 回傳只讀寫登入使用者資料的 Repository
+func userRepo(r *http.Request) db.Repository {
+	user, _ := auth.User(r)
+	return db.Repo.ForUser(user.ID)
+}
+
+type credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// issueToken 產生新的登入 token 並回傳給客戶端
+func issueToken(w http.ResponseWriter, user models.User, status int) {
+	token, tokenHash, err := auth.NewToken()
+	if err != nil {
+		http.Error(w, "Token error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	expiresAt := time.Now().Add(auth.TokenLifetime).UTC()
+	if err := db.Repo.CreateAuthToken(user.ID, tokenHash, expiresAt); err != nil {
+		http.Error(w, "Token error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.AuthResponse{Token: token, ExpiresAt: expiresAt.Format(time.RFC3339), User: user})
+}
+
+// Register 建立帳號並登入
+func Register(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var c credentials
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var errs models.ValidationErrors
+	if _, err := mail.ParseAddress(c.Email); err != nil {
+		errs.Add("email", "must be a valid email address")
+	}
+	if len([]rune(c.Password)) < auth.MinPasswordLength {
+		errs.Add("password", fmt.Sprintf("must be at least %d characters", auth.MinPasswordLength))
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	passwordHash, err := auth.HashPassword(c.Password)
+	if err != nil {
+		http.Error(w, "Password error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	user, err := db.Repo.CreateUser(c.Email, passwordHash)
+	if errors.Is(err, db.ErrUserExists) {
+		http.Error(w, "Email is already registered", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Insert error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	issueToken(w, user, http.StatusCreated)
+}
+
+// Login 以 email 與密碼登入，回傳新的 token
+func Login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var c credentials
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, passwordHash, err := db.Repo.UserByEmail(c.Email)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "Query error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// 帳號不存在與密碼錯誤回傳相同的訊息
+	if err != nil || !auth.CheckPassword(c.Password, passwordHash) {
+		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+		return
+	}
+	issueToken(w, user, http.StatusOK)
+}
+
+// Logout 撤銷目前使用的 token
+func Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := db.Repo.DeleteAuthToken(auth.HashToken(auth.BearerToken(r))); err != nil {
+		http.Error(w, "Delete error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCurrentUser 回傳登入的使用者
+func GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.User(r)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
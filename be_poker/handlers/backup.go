@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"poker_tracker_backend/auth"
+	"poker_tracker_backend/backup"
+	"poker_tracker_backend/db"
+	"poker_tracker_backend/models"
+	"strings"
+	"time"
+)
+
+// GetBackup 處理 GET /backup，下載目前帳號的完整備份（zip）
+func GetBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	version, err := db.SchemaVersion(db.DB)
+	if err != nil {
+		http.Error(w, "Error reading schema version: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	snapshot, err := userRepo(r).Snapshot()
+	if err != nil {
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer snapshot.Close()
+	user, _ := auth.User(r)
+
+	// 開始串流後無法再回傳錯誤
+	startZip(w, "poker-tracker-backup-"+time.Now().UTC().Format("20060102")+".zip")
+	backup.Write(w, snapshot, backup.Manifest{SchemaVersion: version, Driver: db.Driver, Account: user.Email})
+}
+
+// RestoreBackup 處理 POST /restore，上傳 GET /backup 下載的 zip（request body 或 multipart 的 "file" 欄位）
+// mode=merge（預設）保留現有資料，mode=replace 先刪除帳號現有的資料
+// onConflict 決定備份中的 id 已經是自己的資料時怎麼處理：skip（預設）、overwrite、copy
+func RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	opts := db.RestoreOptions{
+		Mode:       strings.TrimSpace(r.URL.Query().Get("mode")),
+		OnConflict: strings.TrimSpace(r.URL.Query().Get("onConflict")),
+	}
+	if opts.Mode == "" {
+		opts.Mode = db.RestoreMerge
+	}
+	if opts.OnConflict == "" {
+		opts.OnConflict = db.ConflictSkip
+	}
+	var errs models.ValidationErrors
+	if opts.Mode != db.RestoreMerge && opts.Mode != db.RestoreReplace {
+		errs.Add("mode", "must be merge or replace")
+	}
+	if opts.OnConflict != db.ConflictSkip && opts.OnConflict != db.ConflictOverwrite && opts.OnConflict != db.ConflictCopy {
+		errs.Add("onConflict", "must be skip, overwrite or copy")
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	// zip 需要隨機讀取，先存成暫存檔
+	r.Body = http.MaxBytesReader(w, r.Body, backup.MaxArchiveSize)
+	upload, size, err := saveUpload(r)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("Backup is larger than %d bytes", backup.MaxArchiveSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer os.Remove(upload.Name())
+	defer upload.Close()
+
+	version, err := db.SchemaVersion(db.DB)
+	if err != nil {
+		http.Error(w, "Error reading schema version: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	archive, err := backup.Open(upload, size, version)
+	if errors.Is(err, backup.ErrInvalidArchive) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error reading backup: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// 匯率是所有使用者共用的，只有管理者的備份會還原匯率
+	user, _ := auth.User(r)
+	opts.ExchangeRates = user.Admin
+	result, err := archive.Restore(userRepo(r), opts)
+	if err != nil {
+		http.Error(w, "Restore failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Manifest backup.Manifest  `json:"manifest"`
+		Result   db.RestoreResult `json:"result"`
+	}{archive.Manifest, result})
+}
+
+// saveUpload 把上傳的備份存到暫存檔，呼叫端負責關閉與刪除
+func saveUpload(r *http.Request) (*os.File, int64, error) {
+	var source io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, 0, fmt.Errorf("Missing file field: %w", err)
+		}
+		defer f.Close()
+		source = f
+	}
+
+	tmp, err := os.CreateTemp("", "poker-restore-*.zip")
+	if err != nil {
+		return nil, 0, fmt.Errorf("Failed to store upload: %v", err)
+	}
+	size, err := io.Copy(tmp, source)
+	if err == nil && size == 0 {
+		err = errors.New("empty body")
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, 0, fmt.Errorf("Failed to read upload: %w", err)
+	}
+	return tmp, size, nil
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"poker_tracker_backend/auth"
+	"poker_tracker_backend/currency"
+	"poker_tracker_backend/db"
+	"poker_tracker_backend/models"
+	"strings"
+)
+
+// reportingCurrency 決定統計使用的幣別，?currency= 參數優先於設定
+func reportingCurrency(r *http.Request) (string, error) {
+	if code, _ := currency.Parse(strings.ToUpper(r.URL.Query().Get("currency"))); code != "" {
+		return code, nil
+	}
+	return savedReportingCurrency(r)
+}
+
+// savedReportingCurrency 依序使用 settings 表、REPORTING_CURRENCY 環境變數、USD
+func savedReportingCurrency(r *http.Request) (string, error) {
+	value, err := userRepo(r).GetSetting(currency.SettingKey)
+	if err == nil && value != "" {
+		return value, nil
+	}
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return "", err
+	}
+	if code, _ := currency.Parse(os.Getenv("REPORTING_CURRENCY")); code != "" {
+		return code, nil
+	}
+	return currency.Base, nil
+}
+
+// newConverter 以匯率表建立換算成報表幣別的換算器
+func newConverter(r *http.Request) (*currency.Converter, error) {
+	to, err := reportingCurrency(r)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := db.Repo.ListExchangeRates()
+	if err != nil {
+		return nil, err
+	}
+	return currency.NewConverter(to, rates), nil
+}
+
+// GetSettings 回傳使用者設定
+func GetSettings(w http.ResponseWriter, r *http.Request) {
+	code, err := savedReportingCurrency(r)
+	if err != nil {
+		http.Error(w, "Error loading settings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.Settings{ReportingCurrency: code})
+}
+
+// UpdateSettings 更新使用者設定
+func UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var settings models.Settings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	code, _ := currency.Parse(strings.ToUpper(settings.ReportingCurrency))
+	if code == "" {
+		writeValidationErrors(w, models.ValidationErrors{{Field: "reportingCurrency", Message: "unknown currency"}})
+		return
+	}
+	if err := userRepo(r).SetSetting(currency.SettingKey, code); err != nil {
+		http.Error(w, "Error saving settings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.Settings{ReportingCurrency: code})
+}
+
+// GetExchangeRates 列出所有匯率
+func GetExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := db.Repo.ListExchangeRates()
+	if err != nil {
+		http.Error(w, "Error querying exchange rates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rates)
+}
+
+// ImportExchangeRates 匯入匯率 CSV（date,currency,rate），同一天的匯率會被覆蓋
+// 匯率是所有使用者共用的，只有管理者可以匯入
+func ImportExchangeRates(w http.ResponseWriter, r *http.Request) {
+	if user, _ := auth.User(r); !user.Admin {
+		http.Error(w, "Only an administrator can change exchange rates", http.StatusForbidden)
+		return
+	}
+	source, closeUpload, err := uploadedBody(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer closeUpload()
+
+	rates, err := currency.ParseCSV(source)
+	if err != nil {
+		http.Error(w, "Invalid CSV: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := db.Repo.SaveExchangeRates(rates); err != nil {
+		http.Error(w, "Error saving exchange rates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"imported": len(rates)})
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"poker_tracker_backend/actions"
+	"poker_tracker_backend/cards"
+	"poker_tracker_backend/equity"
+	"poker_tracker_backend/models"
+)
+
+// CalculateEquity 計算 Hero 與對手的全下勝率
+// 可以直接提供手牌與範圍，或以 handId 使用已儲存的手牌
+func CalculateEquity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.EquityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := models.EquityResult{HandID: req.HandID}
+	if req.HandID != "" {
+		hand, err := userRepo(r).GetHand(req.HandID)
+		if err != nil {
+			http.Error(w, "Hand not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+		street, board := allInBoard(hand)
+		result.AllInStreet = street
+		if req.HoleCards == "" && req.HeroRange == "" && hand.HoleCards != nil {
+			req.HoleCards = *hand.HoleCards
+		}
+		if req.Board == "" {
+			req.Board = board
+		}
+		if len(req.Villains) == 0 {
+			req.Villains = hand.Villains
+		}
+	}
+
+	var errs models.ValidationErrors
+	players := [][]equity.Combo{}
+	result.Players = []models.PlayerEquity{}
+
+	heroHand := req.HoleCards
+	if heroHand == "" {
+		heroHand = req.HeroRange
+	}
+	if heroHand == "" {
+		errs.Add("holeCards", "hero hole cards or range are required")
+	} else if combos, err := equity.ParseRange(heroHand); err != nil {
+		errs.Add("holeCards", err.Error())
+	} else {
+		players = append(players, combos)
+		result.Players = append(result.Players, models.PlayerEquity{Player: "hero", Hand: heroHand})
+	}
+
+	for i, v := range req.Villains {
+		villainHand := v.HoleCards
+		if villainHand == "" {
+			villainHand = v.Range
+		}
+		if villainHand == "" {
+			continue
+		}
+		combos, err := equity.ParseRange(villainHand)
+		if err != nil {
+			errs.Add(fmt.Sprintf("villains[%d]", i), err.Error())
+			continue
+		}
+		name := v.ID
+		if name == "" {
+			name = fmt.Sprintf("villain_%d", i+1)
+		}
+		players = append(players, combos)
+		result.Players = append(result.Players, models.PlayerEquity{Player: name, Position: v.Position, Hand: villainHand})
+	}
+	if len(players) < 2 && len(errs) == 0 {
+		errs.Add("villains", "at least one villain with hole cards or a range is required")
+	}
+
+	board, err := cards.ParseList(req.Board)
+	if err != nil {
+		errs.Add("board", err.Error())
+	}
+	if req.Iterations < 0 || req.Iterations > equity.MaxIterations {
+		errs.Add("iterations", fmt.Sprintf("must be between 0 and %d", equity.MaxIterations))
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	// 使用者離開後就停止計算
+	calc, err := equity.Calculate(players, board, equity.Options{Iterations: req.Iterations, Seed: req.Seed, Context: r.Context()})
+	if r.Context().Err() != nil {
+		return
+	}
+	if err != nil {
+		http.Error(w, "Equity calculation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result.Board = cards.Format(board)
+	result.Method = calc.Method
+	result.Samples = calc.Samples
+	result.Seed = calc.Seed
+	for i, p := range calc.Players {
+		result.Players[i].Win = p.Win
+		result.Players[i].Tie = p.Tie
+		result.Players[i].Lose = p.Lose
+		result.Players[i].Equity = p.Equity
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// allInBoard 回傳全下的街以及當時已發出的公共牌
+// 沒有結構化動作或沒有全下時，視為翻牌前全下
+func allInBoard(hand models.Hand) (string, string) {
+	street, ok := actions.AllInStreet(hand.Streets)
+	if !ok || hand.Board == nil {
+		return street, ""
+	}
+	board, err := cards.ParseList(*hand.Board)
+	if err != nil {
+		return street, ""
+	}
+	if n := actions.BoardSize(street); n < len(board) {
+		board = board[:n]
+	}
+	return street, cards.Format(board)
+}
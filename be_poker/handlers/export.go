@@ -0,0 +1,506 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"poker_tracker_backend/db"
+	"poker_tracker_backend/models"
+	"poker_tracker_backend/phh"
+	"poker_tracker_backend/sessions"
+	"poker_tracker_backend/stats"
+	"poker_tracker_backend/xlsx"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExportPHH 處理 GET /export/phh，把手牌匯出成 PHH 檔並以 zip 串流回傳
+// handId 匯出單手；其他參數與 GET /hands 相同（例如 sessionId、tag、dateFrom），limit 與 cursor 不適用
+func ExportPHH(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	repo := userRepo(r)
+	cache := map[string]models.Session{}
+	sessionFor := func(id string) models.Session {
+		if id == "" {
+			return models.Session{}
+		}
+		if s, ok := cache[id]; ok {
+			return s
+		}
+		s, _ := repo.GetSession(id)
+		cache[id] = s
+		return s
+	}
+
+	// 單手匯出：寫不出來時直接回傳錯誤，不回傳空的 zip
+	if id := r.URL.Query().Get("handId"); id != "" {
+		hand, err := repo.GetHand(id)
+		if err != nil {
+			http.Error(w, "Hand not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+		data, err := phh.Encode(hand, sessionFor(hand.SessionID))
+		if err != nil {
+			http.Error(w, "Cannot export hand: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		zw := startZip(w, "hand-"+hand.ID+".zip")
+		if f, err := zw.Create(phhFileName(hand)); err == nil {
+			f.Write(data)
+		}
+		zw.Close()
+		return
+	}
+
+	filter, _, errs := parseHandFilter(r)
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	name := "hands-phh.zip"
+	if filter.SessionID != "" {
+		if _, err := repo.GetSession(filter.SessionID); err != nil {
+			http.Error(w, "Session not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+		name = "session-" + filter.SessionID + "-phh.zip"
+	}
+	filter.Limit, filter.Cursor = db.MaxPageSize, ""
+	page, err := repo.QueryHands(filter)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	// 開始串流後已無法改變狀態碼，無法匯出的手牌與中途的錯誤寫進 zip 的 errors.txt
+	zw := startZip(w, name)
+	defer zw.Close()
+	skipped := []string{}
+	for {
+		for _, hand := range page.Items {
+			data, err := phh.Encode(hand, sessionFor(hand.SessionID))
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("%s: %v", hand.ID, err))
+				continue
+			}
+			f, err := zw.Create(phhFileName(hand))
+			if err != nil {
+				return
+			}
+			if _, err := f.Write(data); err != nil {
+				return
+			}
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+		if page, err = repo.QueryHands(filter); err != nil {
+			skipped = append(skipped, "export stopped early: "+err.Error())
+			break
+		}
+	}
+	if len(skipped) > 0 {
+		if f, err := zw.Create("errors.txt"); err == nil {
+			f.Write([]byte(strings.Join(skipped, "\n") + "\n"))
+		}
+	}
+}
+
+// startZip 設定下載用的標頭並開始串流 zip
+func startZip(w http.ResponseWriter, filename string) *zip.Writer {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition")
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	return zip.NewWriter(w)
+}
+
+// phhFileName 以日期開頭讓檔案依時間排序，例如 2024-05-01_<id>.phh
+func phhFileName(hand models.Hand) string {
+	if len(hand.Date) >= 10 {
+		return models.DateDay(hand.Date) + "_" + hand.ID + ".phh"
+	}
+	return hand.ID + ".phh"
+}
+
+// rowWriter 是 CSV 與 XLSX 共用的逐列輸出
+type rowWriter interface {
+	WriteRow(values ...interface{}) error
+}
+
+// csvRows 把一列值寫成 CSV；文字以 = + - @ 開頭時加上單引號，避免試算表當成公式執行
+type csvRows struct {
+	w *csv.Writer
+}
+
+func (c csvRows) WriteRow(values ...interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+				v = "'" + v
+			}
+			record[i] = v
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return c.w.Write(record)
+}
+
+var sessionExportColumns = []interface{}{
+	"id", "date", "location", "type", "smallBlind", "bigBlind", "currency", "effectiveStack", "tableSize", "tags",
+	"startTime", "endTime", "durationMinutes", "state", "pausedAt", "pausedSeconds",
+	"buyIns", "cashOuts", "cashResult", "transactions",
+	"tournamentName", "tournamentBuyIn", "tournamentFee", "reEntries", "fieldSize", "place", "prize", "bounties",
+}
+
+// sessionExportRow 的欄位順序與 sessionExportColumns 相同；session 需先經過 sessions.Summarize
+func sessionExportRow(s models.Session) []interface{} {
+	buyIns, cashOuts := 0, 0
+	transactions := []string{}
+	for _, t := range s.Transactions {
+		if t.Type == sessions.CashOut {
+			cashOuts += t.Amount
+		} else {
+			buyIns += t.Amount
+		}
+		transactions = append(transactions, strings.TrimSpace(fmt.Sprintf("%s %d %s", t.Type, t.Amount, t.Time)))
+	}
+	var cashResult interface{}
+	if s.CashResult != nil {
+		cashResult = *s.CashResult
+	}
+	row := []interface{}{
+		s.ID, s.Date, s.Location, s.Type, s.SmallBlind, s.BigBlind, s.Currency, s.EffectiveStack, s.TableSize, tagNames(s.Tags),
+		s.StartTime, s.EndTime, s.DurationMinutes, s.State, s.PausedAt, s.PausedSeconds,
+		buyIns, cashOuts, cashResult, strings.Join(transactions, "; "),
+	}
+	if t := s.Tournament; t != nil {
+		return append(row, t.Name, t.BuyIn, t.Fee, t.ReEntries, t.FieldSize, t.Place, t.Prize, t.Bounties)
+	}
+	return append(row, nil, nil, nil, nil, nil, nil, nil, nil)
+}
+
+var handExportColumns = []interface{}{
+	"id", "sessionId", "date", "smallBlind", "bigBlind", "currency", "location", "position", "holeCards", "board",
+	"result", "favorite", "tags", "villains", "details", "note", "analysis", "analysisDate",
+	"externalId", "level", "ante", "streets",
+}
+
+// handExportRow 的欄位順序與 handExportColumns 相同；級別、幣別與地點來自所屬的 session，streets 以 JSON 輸出
+func handExportRow(h models.Hand, s models.Session) []interface{} {
+	villains := []string{}
+	for _, v := range h.Villains {
+		text := strings.TrimSpace(v.Position + " " + v.HoleCards)
+		if v.HoleCards == "" && v.Range != "" {
+			text += " [" + v.Range + "]"
+		}
+		if v.Name != "" {
+			text += " (" + v.Name + ")"
+		}
+		villains = append(villains, strings.TrimSpace(text))
+	}
+	streets := ""
+	if len(h.Streets) > 0 {
+		data, _ := json.Marshal(h.Streets)
+		streets = string(data)
+	}
+	date := h.Date
+	if date == "" {
+		date = s.Date
+	}
+	return []interface{}{
+		h.ID, h.SessionID, date, s.SmallBlind, s.BigBlind, s.Currency, s.Location, deref(h.Position), deref(h.HoleCards), deref(h.Board),
+		h.Result, h.Favorite, tagNames(h.Tags), strings.Join(villains, "; "), h.Details, deref(h.Note), h.Analysis, h.AnalysisDate,
+		h.ExternalID, h.Level, h.Ante, streets,
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func tagNames(tags []models.Tag) string {
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.Name
+	}
+	return strings.Join(names, "; ")
+}
+
+// exportFilters 解析匯出的篩選參數，與列表 API 相同；匯出全部符合的資料，limit 與 cursor 不適用
+func exportFilters(r *http.Request) (db.HandFilter, db.SessionFilter, models.ValidationErrors) {
+	handFilter, _, errs := parseHandFilter(r)
+	sessionFilter, _, sessionErrs := parseSessionFilter(r)
+	// 兩者共用 dateFrom、stakes 等參數，避免同一個錯誤回報兩次
+	if len(errs) == 0 {
+		errs = sessionErrs
+	}
+	handFilter.Limit, handFilter.Cursor = db.MaxPageSize, ""
+	sessionFilter.Limit, sessionFilter.Cursor = db.MaxPageSize, ""
+	return handFilter, sessionFilter, errs
+}
+
+// writeQueryError 回傳查詢第一頁時的錯誤，篩選條件不正確時為 400
+func writeQueryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, db.ErrInvalidFilter) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "Database query error: "+err.Error(), http.StatusInternalServerError)
+}
+
+// streamSessions 從已查好的第一頁開始逐頁寫出 session，每頁寫完呼叫 flush
+func streamSessions(repo db.Repository, filter db.SessionFilter, page models.SessionPage, out rowWriter, flush func(), each func(models.Session)) error {
+	for {
+		for _, s := range page.Items {
+			sessions.Summarize(&s)
+			if err := out.WriteRow(sessionExportRow(s)...); err != nil {
+				return err
+			}
+			if each != nil {
+				each(s)
+			}
+		}
+		flush()
+		if page.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = page.NextCursor
+		var err error
+		if page, err = repo.QuerySessions(filter); err != nil {
+			return err
+		}
+	}
+}
+
+// streamHands 與 streamSessions 相同，所屬 session 只查詢一次
+func streamHands(repo db.Repository, filter db.HandFilter, page models.HandPage, out rowWriter, flush func(), each func(models.Hand)) error {
+	cache := map[string]models.Session{}
+	for {
+		for _, h := range page.Items {
+			s, ok := cache[h.SessionID]
+			if !ok && h.SessionID != "" {
+				s, _ = repo.GetSession(h.SessionID)
+				cache[h.SessionID] = s
+			}
+			if err := out.WriteRow(handExportRow(h, s)...); err != nil {
+				return err
+			}
+			if each != nil {
+				each(h)
+			}
+		}
+		flush()
+		if page.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = page.NextCursor
+		var err error
+		if page, err = repo.QueryHands(filter); err != nil {
+			return err
+		}
+	}
+}
+
+// startCSV 設定下載用的標頭；加上 UTF-8 BOM 讓 Excel 正確顯示中文與花色符號
+func startCSV(w http.ResponseWriter, filename string) (csvRows, func()) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition")
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write([]byte("\uFEFF"))
+	cw := csv.NewWriter(w)
+	return csvRows{w: cw}, func() {
+		cw.Flush()
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+// ExportSessionsCSV 處理 GET /export/sessions.csv，篩選參數與 GET /sessions 相同
+func ExportSessionsCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	filter, _, errs := parseSessionFilter(r)
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	filter.Limit, filter.Cursor = db.MaxPageSize, ""
+	repo := userRepo(r)
+	page, err := repo.QuerySessions(filter)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	// 開始串流後已無法回傳錯誤，只能提早結束
+	out, flush := startCSV(w, "sessions.csv")
+	out.WriteRow(sessionExportColumns...)
+	streamSessions(repo, filter, page, out, flush, nil)
+}
+
+// ExportHandsCSV 處理 GET /export/hands.csv，篩選參數與 GET /hands 相同
+func ExportHandsCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	filter, _, errs := parseHandFilter(r)
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	filter.Limit, filter.Cursor = db.MaxPageSize, ""
+	repo := userRepo(r)
+	page, err := repo.QueryHands(filter)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	out, flush := startCSV(w, "hands.csv")
+	out.WriteRow(handExportColumns...)
+	streamHands(repo, filter, page, out, flush, nil)
+}
+
+// ExportWorkbook 處理 GET /export/workbook.xlsx：Sessions、Hands 與 Summary 三張工作表
+// 篩選參數同時套用到 session 與手牌，Summary 是篩選後資料的 GET /stats 統計
+func ExportWorkbook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	handFilter, sessionFilter, errs := exportFilters(r)
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	repo := userRepo(r)
+	sessionPage, err := repo.QuerySessions(sessionFilter)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	handPage, err := repo.QueryHands(handFilter)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	conv, err := newConverter(r)
+	if err != nil {
+		http.Error(w, "Error loading exchange rates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition")
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Disposition", `attachment; filename="poker-tracker.xlsx"`)
+	flush := func() {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+
+	// 統計需要全部的資料；只保留計算用得到的欄位，長文字不留在記憶體
+	wb := xlsx.NewWorkbook(w)
+	keptSessions, keptHands := []models.Session{}, []models.Hand{}
+	wb.AddSheet("Sessions")
+	wb.WriteRow(sessionExportColumns...)
+	if err := streamSessions(repo, sessionFilter, sessionPage, wb, flush, func(s models.Session) {
+		keptSessions = append(keptSessions, s)
+	}); err != nil {
+		return
+	}
+	wb.AddSheet("Hands")
+	wb.WriteRow(handExportColumns...)
+	if err := streamHands(repo, handFilter, handPage, wb, flush, func(h models.Hand) {
+		h.Details, h.Analysis, h.Note = "", "", nil
+		keptHands = append(keptHands, h)
+	}); err != nil {
+		return
+	}
+	wb.AddSheet("Summary")
+	writeSummarySheet(wb, stats.Compute(keptSessions, keptHands, conv))
+	wb.Close()
+}
+
+// writeSummarySheet 寫出總計，再依級別、地點與標籤分組
+func writeSummarySheet(out rowWriter, s models.Stats) {
+	out.WriteRow("metric", "value")
+	out.WriteRow("currency", s.Currency)
+	out.WriteRow("totalProfit", s.TotalProfit)
+	out.WriteRow("totalSessions", s.TotalSessions)
+	out.WriteRow("winRate", s.WinRate)
+	out.WriteRow("avgSession", s.AvgSession)
+	out.WriteRow("totalHands", s.TotalHands)
+	out.WriteRow("totalBb", s.TotalBB)
+	out.WriteRow("bbPer100", s.BBPer100)
+	out.WriteRow("allInEvProfit", s.AllInEVProfit)
+	out.WriteRow("allInHands", s.AllInHands)
+	out.WriteRow("luck", s.Luck)
+	out.WriteRow("cashProfit", s.CashProfit)
+	out.WriteRow("cashSessions", s.CashSessions)
+	out.WriteRow("hoursPlayed", s.HoursPlayed)
+	out.WriteRow("hourlyRate", s.HourlyRate)
+	out.WriteRow("bbPerHour", s.BBPerHour)
+	out.WriteRow("tournaments", s.Tournaments.Count)
+	out.WriteRow("tournamentProfit", s.Tournaments.Profit)
+	out.WriteRow("tournamentRoi", s.Tournaments.ROI)
+	out.WriteRow("tournamentItmRate", s.Tournaments.ITMRate)
+	if len(s.MissingRates) > 0 {
+		out.WriteRow("missingRates", strings.Join(s.MissingRates, ", "))
+	}
+
+	out.WriteRow()
+	out.WriteRow("stakes", "profit", "allInEvProfit", "totalBb")
+	for _, key := range sortedKeys(s.ByStakes) {
+		out.WriteRow(key, s.ByStakes[key], s.ByStakesAllInEV[key], s.ByStakesBB[key])
+	}
+	out.WriteRow()
+	out.WriteRow("location", "profit", "allInEvProfit", "totalBb")
+	for _, key := range sortedKeys(s.ByLocation) {
+		out.WriteRow(key, s.ByLocation[key], s.ByLocationAllInEV[key], s.ByLocationBB[key])
+	}
+	out.WriteRow()
+	out.WriteRow("tag", "hands", "profit", "totalBb", "bbPer100")
+	tags := make([]string, 0, len(s.ByTag))
+	for key := range s.ByTag {
+		tags = append(tags, key)
+	}
+	sort.Strings(tags)
+	for _, key := range tags {
+		t := s.ByTag[key]
+		out.WriteRow(key, t.Hands, t.Profit, t.TotalBB, t.BBPer100)
+	}
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
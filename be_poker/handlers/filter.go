@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"poker_tracker_backend/db"
+	"poker_tracker_backend/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 沒有指定 limit 但有 cursor 時的每頁筆數
+const defaultPageSize = 50
+
+// queryParser 解析查詢參數並累計欄位錯誤
+type queryParser struct {
+	values url.Values
+	errs   models.ValidationErrors
+}
+
+func (p *queryParser) str(name string) string {
+	return strings.TrimSpace(p.values.Get(name))
+}
+
+// list 讀取可重複或以逗號分隔的參數，例如 tag=a&tag=b 或 tag=a,b
+func (p *queryParser) list(name string) []string {
+	values := []string{}
+	for _, v := range p.values[name] {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+func (p *queryParser) date(name string) string {
+	v := p.str(name)
+	if v == "" {
+		return ""
+	}
+	if _, err := time.Parse("2006-01-02", v); err != nil {
+		p.errs.Add(name, "must be a date in YYYY-MM-DD format")
+		return ""
+	}
+	return v
+}
+
+func (p *queryParser) boolean(name string) *bool {
+	v := p.str(name)
+	if v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		p.errs.Add(name, "must be true or false")
+		return nil
+	}
+	return &b
+}
+
+func (p *queryParser) integer(name string) *int {
+	v := p.str(name)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		p.errs.Add(name, "must be an integer")
+		return nil
+	}
+	return &n
+}
+
+// stakes 解析 "1/2" 格式的級別
+func (p *queryParser) stakes(name string) (int, int) {
+	v := strings.TrimPrefix(p.str(name), "$")
+	if v == "" {
+		return 0, 0
+	}
+	parts := strings.Split(v, "/")
+	if len(parts) == 2 {
+		sb, err1 := strconv.Atoi(parts[0])
+		bb, err2 := strconv.Atoi(parts[1])
+		if err1 == nil && err2 == nil && sb >= 0 && bb > 0 {
+			return sb, bb
+		}
+	}
+	p.errs.Add(name, "must look like 1/2")
+	return 0, 0
+}
+
+// page 解析 limit 與 cursor；兩者都沒給時不分頁
+func (p *queryParser) page() (limit int, cursor string, paged bool) {
+	cursor = p.str("cursor")
+	n := p.integer("limit")
+	switch {
+	case n != nil && (*n < 1 || *n > db.MaxPageSize):
+		p.errs.Add("limit", "must be between 1 and "+strconv.Itoa(db.MaxPageSize))
+	case n != nil:
+		limit = *n
+	case cursor != "":
+		limit = defaultPageSize
+	}
+	return limit, cursor, n != nil || cursor != ""
+}
+
+func (p *queryParser) sort(allowed []string) string {
+	v := p.str("sort")
+	if v == "" {
+		return ""
+	}
+	name := strings.TrimPrefix(v, "-")
+	for _, a := range allowed {
+		if a == name {
+			return v
+		}
+	}
+	p.errs.Add("sort", "must be one of "+strings.Join(allowed, ", ")+" (prefix with - for descending)")
+	return ""
+}
+
+// parseHandFilter 解析 GET /hands 的查詢參數
+func parseHandFilter(r *http.Request) (db.HandFilter, bool, models.ValidationErrors) {
+	p := &queryParser{values: r.URL.Query()}
+	f := db.HandFilter{
+		SessionID:   p.str("sessionId"),
+		DateFrom:    p.date("dateFrom"),
+		DateTo:      p.date("dateTo"),
+		Position:    p.str("position"),
+		Tags:        p.list("tag"),
+		Location:    p.str("location"),
+		PlayerID:    p.str("playerId"),
+		Favorite:    p.boolean("favorite"),
+		MinResult:   p.integer("minResult"),
+		MaxResult:   p.integer("maxResult"),
+		HasAnalysis: p.boolean("hasAnalysis"),
+		Sort:        p.sort(db.HandSorts()),
+	}
+	f.SmallBlind, f.BigBlind = p.stakes("stakes")
+	var paged bool
+	f.Limit, f.Cursor, paged = p.page()
+	return f, paged, p.errs
+}
+
+// parseSessionFilter 解析 GET /sessions 的查詢參數
+func parseSessionFilter(r *http.Request) (db.SessionFilter, bool, models.ValidationErrors) {
+	p := &queryParser{values: r.URL.Query()}
+	f := db.SessionFilter{
+		DateFrom: p.date("dateFrom"),
+		DateTo:   p.date("dateTo"),
+		Location: p.str("location"),
+		Type:     p.str("type"),
+		Tags:     p.list("tag"),
+		Sort:     p.sort(db.SessionSorts()),
+	}
+	f.SmallBlind, f.BigBlind = p.stakes("stakes")
+	var paged bool
+	f.Limit, f.Cursor, paged = p.page()
+	return f, paged, p.errs
+}
+
+// writeTotal 讓沒有分頁的回應也能取得總筆數
+func writeTotal(w http.ResponseWriter, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count")
+}
@@ -1,8 +1,8 @@
 package handlers
 
 import (
+	"errors"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"time"
 	"poker_tracker_backend/db"
@@ -21,132 +21,60 @@ func CreateHand(w http.ResponseWriter, r *http.Request) {
 	
 	hand.ID = uuid.New().String()
 	
-	// 調試信息
-	holeCardsStr := ""
-	positionStr := ""
-	if hand.HoleCards != nil {
-		holeCardsStr = *hand.HoleCards
-	}
-	if hand.Position != nil {
-		positionStr = *hand.Position
+	// 沒有指定 session 時掛到進行中的 session
+	if hand.SessionID == "" {
+		if live, err := userRepo(r).LiveSession(); err == nil {
+			hand.SessionID = live.ID
+		}
 	}
-	fmt.Printf("DEBUG CreateHand: HoleCards='%s', Position='%s'\n", holeCardsStr, positionStr)
 	
-	// 使用新的資料庫結構欄位
-	stmt, err := db.DB.Prepare(`
-		INSERT INTO hands (
-			id, session_id, position, hole_cards, details, result_amount, 
-			analysis, analysis_date, is_favorite, tag, board, note, villains, date
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
-	`)
-	if err != nil {
-		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+	hand.Tags = legacyTags(hand.Tags, hand.Tag, nil)
+	if errs := append(validateHand(&hand, true), validateTags(hand.Tags)...); len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
-	defer stmt.Close()
-	
-	// 處理villains JSON
-	villainsJSON := "[]"
-	if hand.Villains != nil && len(hand.Villains) > 0 {
-		villainsBytes, err := json.Marshal(hand.Villains)
-		if err == nil {
-			villainsJSON = string(villainsBytes)
-		}
-	}
 	
-	_, err = stmt.Exec(
-		hand.ID, 
-		hand.SessionID, 
-		hand.Position, 
-		hand.HoleCards, 
-		hand.Details, 
-		hand.Result, 
-		hand.Analysis, 
-		hand.AnalysisDate, 
-		hand.Favorite, 
-		"", // tag
-		hand.Board, 
-		hand.Note, 
-		villainsJSON, 
-		hand.Date,
-	)
-	
-	if err != nil {
-		http.Error(w, "Insert error: "+err.Error(), http.StatusInternalServerError)
+	if err := userRepo(r).CreateHand(hand); err != nil {
+		writeSaveError(w, "Insert error: ", err)
 		return
 	}
+	// 回傳含標籤 id 的手牌
+	if saved, err := userRepo(r).GetHand(hand.ID); err == nil {
+		hand = saved
+	}
 	
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(hand)
 }
 
+// GetHands 列出手牌，可篩選與排序
+// 有 limit 或 cursor 參數時回傳分頁物件，否則維持回傳陣列並以 X-Total-Count 提供總數
 func GetHands(w http.ResponseWriter, r *http.Request) {
-	rows, err := db.DB.Query(`
-		SELECT 
-			id, 
-			COALESCE(session_id, ''), 
-			COALESCE(position, ''), 
-			COALESCE(hole_cards, ''), 
-			COALESCE(details, ''), 
-			COALESCE(result_amount, 0), 
-			COALESCE(analysis, ''), 
-			COALESCE(analysis_date, ''), 
-			COALESCE(is_favorite, false), 
-			COALESCE(tag, ''), 
-			COALESCE(board, ''), 
-			COALESCE(note, ''), 
-			COALESCE(villains, '[]'), 
-			COALESCE(date, '')
-		FROM hands 
-		ORDER BY created_at DESC
-	`)
-	if err != nil {
-		http.Error(w, "Query error: "+err.Error(), http.StatusInternalServerError)
+	filter, paged, errs := parseHandFilter(r)
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
-	defer rows.Close()
 	
-	hands := []models.Hand{}
-	for rows.Next() {
-		var h models.Hand
-		var villainsJSON string
-		
-		err := rows.Scan(
-			&h.ID, 
-			&h.SessionID, 
-			&h.Position, 
-			&h.HoleCards, 
-			&h.Details, 
-			&h.Result, 
-			&h.Analysis, 
-			&h.AnalysisDate, 
-			&h.Favorite, 
-			&h.Tag, 
-			&h.Board, 
-			&h.Note, 
-			&villainsJSON, 
-			&h.Date,
-		)
-		
-		if err != nil {
-			continue // 跳過錯誤的行
-		}
-		
-		// 解析villains JSON
-		if villainsJSON != "" && villainsJSON != "[]" {
-			err := json.Unmarshal([]byte(villainsJSON), &h.Villains)
-			if err != nil {
-				h.Villains = []models.Villain{} // 如果解析失敗，設為空陣列
-			}
-		}
-		
-		hands = append(hands, h)
+	page, err := userRepo(r).QueryHands(filter)
+	if errors.Is(err, db.ErrInvalidFilter) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Query error: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 	
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(hands)
+	writeTotal(w, page.Total)
+	if paged {
+		json.NewEncoder(w).Encode(page)
+		return
+	}
+	json.NewEncoder(w).Encode(page.Items)
 }
 
 func GetHand(w http.ResponseWriter, r *http.Request) {
@@ -156,59 +84,12 @@ func GetHand(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	row := db.DB.QueryRow(`
-		SELECT 
-			id, 
-			COALESCE(session_id, ''), 
-			COALESCE(position, ''), 
-			COALESCE(hole_cards, ''), 
-			COALESCE(details, ''), 
-			COALESCE(result_amount, 0), 
-			COALESCE(analysis, ''), 
-			COALESCE(analysis_date, ''), 
-			COALESCE(is_favorite, false), 
-			COALESCE(tag, ''), 
-			COALESCE(board, ''), 
-			COALESCE(note, ''), 
-			COALESCE(villains, '[]'), 
-			COALESCE(date, '')
-		FROM hands 
-		WHERE id = $1
-	`, id)
-	
-	var h models.Hand
-	var villainsJSON string
-	
-	err := row.Scan(
-		&h.ID, 
-		&h.SessionID, 
-		&h.Position, 
-		&h.HoleCards, 
-		&h.Details, 
-		&h.Result, 
-		&h.Analysis, 
-		&h.AnalysisDate, 
-		&h.Favorite, 
-		&h.Tag, 
-		&h.Board, 
-		&h.Note, 
-		&villainsJSON, 
-		&h.Date,
-	)
-	
+	h, err := userRepo(r).GetHand(id)
 	if err != nil {
 		http.Error(w, "Hand not found: "+err.Error(), http.StatusNotFound)
 		return
 	}
 	
-	// 解析villains JSON
-	if villainsJSON != "" && villainsJSON != "[]" {
-		err := json.Unmarshal([]byte(villainsJSON), &h.Villains)
-		if err != nil {
-			h.Villains = []models.Villain{} // 如果解析失敗，設為空陣列
-		}
-	}
-	
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(h)
@@ -222,46 +103,35 @@ func UpdateHand(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// 將 villains 序列化為 JSON
-	villainsJSON, err := json.Marshal(hand.Villains)
-	if err != nil {
-		http.Error(w, "Failed to serialize villains: "+err.Error(), http.StatusInternalServerError)
+	// 舊版客戶端不會送 streets 與 tags，保留資料庫中原本的結構化動作與標籤
+	streetsProvided := hand.Streets != nil
+	existing, err := userRepo(r).GetHand(id)
+	if errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "Hand not found", http.StatusNotFound)
 		return
 	}
-	
-	stmt, err := db.DB.Prepare(`UPDATE hands SET hole_cards = $1, board = $2, position = $3, details = $4, note = $5, result_amount = $6, date = $7, villains = $8, is_favorite = $9, tag = $10, analysis = $11 WHERE id = $12`)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err == nil && !streetsProvided {
+		hand.Streets = existing.Streets
+	}
+	hand.Tags = legacyTags(hand.Tags, hand.Tag, existing.Tags)
+	keepVillainPlayers(hand.Villains, existing.Villains)
+	if errs := append(validateHand(&hand, streetsProvided), validateTags(hand.Tags)...); len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
-	defer stmt.Close()
 	
-	_, err = stmt.Exec(hand.HoleCards, hand.Board, hand.Position, hand.Details, hand.Note, hand.Result, hand.Date, string(villainsJSON), hand.Favorite, "", hand.Analysis, id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := userRepo(r).UpdateHand(id, hand); err != nil {
+		writeSaveError(w, "", err)
 		return
 	}
 	
 	// 返回更新後的手牌
-	row := db.DB.QueryRow(`SELECT id, COALESCE(session_id, '') as session_id, COALESCE(hole_cards, '') as hole_cards, COALESCE(board, '') as board, COALESCE(position, '') as position, COALESCE(details, '') as details, COALESCE(note, '') as note, COALESCE(result_amount, 0) as result_amount, COALESCE(date, '') as date, COALESCE(villains, '[]') as villains, COALESCE(analysis, '') as analysis, COALESCE(is_favorite, false) as is_favorite, COALESCE(tag, '') as tag FROM hands WHERE id = $1`, id)
-	var updatedHand models.Hand
-	var updatedVillainsJSON string
-	var tag string
-	err = row.Scan(&updatedHand.ID, &updatedHand.SessionID, &updatedHand.HoleCards, &updatedHand.Board, &updatedHand.Position, &updatedHand.Details, &updatedHand.Note, &updatedHand.Result, &updatedHand.Date, &updatedVillainsJSON, &updatedHand.Analysis, &updatedHand.Favorite, &tag)
+	updatedHand, err := userRepo(r).GetHand(id)
 	if err != nil {
 		http.Error(w, "Failed to retrieve updated hand", http.StatusInternalServerError)
 		return
 	}
-	
-	// 設置空的 analysis_date
-	updatedHand.AnalysisDate = ""
-	
-	// 反序列化 villains JSON
-	if updatedVillainsJSON != "" && updatedVillainsJSON != "[]" {
-		if err := json.Unmarshal([]byte(updatedVillainsJSON), &updatedHand.Villains); err != nil {
-			updatedHand.Villains = []models.Villain{} // 如果解析失敗，設為空陣列
-		}
-	} else {
+	if updatedHand.Villains == nil {
 		updatedHand.Villains = []models.Villain{}
 	}
 	
@@ -275,8 +145,11 @@ func DeleteHand(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	_, err := db.DB.Exec(`DELETE FROM hands WHERE id = $1`, id)
-	if err != nil {
+	if err := userRepo(r).DeleteHand(id); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			http.Error(w, "Hand not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, "Delete error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -293,6 +166,7 @@ func AnalyzeHand(w http.ResponseWriter, r *http.Request) {
 
 	var request struct {
 		Hand models.Hand `json:"hand"`
+		ICM  *models.ICMRequest `json:"icm,omitempty"` // 錦標賽手牌可附上籌碼與獎金結構
 	}
 	
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -315,8 +189,27 @@ func AnalyzeHand(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 錦標賽手牌附上 ICM 資訊
+	details := hand.Details
+	if request.ICM != nil {
+		if errs := validateICMRequest(*request.ICM); len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+		var icmHand *models.Hand
+		if len(hand.Streets) > 0 {
+			icmHand = &hand
+		}
+		result, err := computeICM(*request.ICM, icmHand)
+		if err != nil {
+			http.Error(w, "ICM calculation failed: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		details += "\n\n" + icmContext(result)
+	}
+
 	// 調用 OpenAI API 進行分析
-	analysis, err := openaiService.AnalyzeHand(hand.Details, hand.Result)
+	analysis, err := openaiService.AnalyzeHand(details, hand.Result)
 	if err != nil {
 		http.Error(w, "Analysis failed: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -348,17 +241,11 @@ func ToggleFavorite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 獲取當前的 favorite 狀態
-	var currentFavorite bool
-	row := db.DB.QueryRow(`SELECT COALESCE(is_favorite, false) FROM hands WHERE id = $1`, id)
-	if err := row.Scan(&currentFavorite); err != nil {
+	newFavorite, err := userRepo(r).ToggleFavorite(id)
+	if errors.Is(err, db.ErrNotFound) {
 		http.Error(w, "Hand not found", http.StatusNotFound)
 		return
 	}
-
-	// 切換 favorite 狀態
-	newFavorite := !currentFavorite
-	_, err := db.DB.Exec(`UPDATE hands SET is_favorite = $1 WHERE id = $2`, newFavorite, id)
 	if err != nil {
 		http.Error(w, "Failed to update favorite status: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"poker_tracker_backend/equity"
+	"poker_tracker_backend/icm"
+	"poker_tracker_backend/models"
+	"strings"
+)
+
+// CalculateICM 計算每位玩家的 ICM $EV
+// 提供 handId 時另外比較 Hero 面對全下時跟注與棄牌的 $EV
+func CalculateICM(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.ICMRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errs := validateICMRequest(req); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	var hand *models.Hand
+	if req.HandID != "" {
+		h, err := userRepo(r).GetHand(req.HandID)
+		if err != nil {
+			http.Error(w, "Hand not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+		hand = &h
+	}
+
+	result, err := computeICM(req, hand)
+	if err != nil {
+		http.Error(w, "ICM calculation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func validateICMRequest(req models.ICMRequest) models.ValidationErrors {
+	var errs models.ValidationErrors
+	if len(req.Stacks) == 0 {
+		errs.Add("stacks", "at least one stack is required")
+	}
+	if len(req.Stacks) > icm.MaxPlayers {
+		errs.Add("stacks", fmt.Sprintf("at most %d players are supported", icm.MaxPlayers))
+	}
+	seen := map[string]bool{}
+	for i, s := range req.Stacks {
+		if s.Stack < 0 {
+			errs.Add(fmt.Sprintf("stacks[%d].stack", i), "must not be negative")
+		}
+		if s.Player != "" && seen[s.Player] {
+			errs.Add(fmt.Sprintf("stacks[%d].player", i), "duplicate player "+s.Player)
+		}
+		seen[s.Player] = true
+	}
+	if len(req.Payouts) == 0 {
+		errs.Add("payouts", "at least one payout is required")
+	}
+	for i, p := range req.Payouts {
+		if p < 0 {
+			errs.Add(fmt.Sprintf("payouts[%d]", i), "must not be negative")
+		}
+	}
+	if req.Iterations < 0 || req.Iterations > equity.MaxIterations {
+		errs.Add("iterations", fmt.Sprintf("must be between 0 and %d", equity.MaxIterations))
+	}
+	return errs
+}
+
+// computeICM 計算 $EV；有手牌時也計算跟注與棄牌的比較
+func computeICM(req models.ICMRequest, hand *models.Hand) (models.ICMResult, error) {
+	players, err := icm.Players(req.Stacks, req.Payouts)
+	if err != nil {
+		return models.ICMResult{}, err
+	}
+	result := models.ICMResult{Players: players}
+	if hand == nil {
+		return result, nil
+	}
+
+	decision, err := icm.HandDecision(*hand, req.Stacks, req.Payouts, req.VillainRange, equity.Options{Iterations: req.Iterations, Seed: req.Seed})
+	if err != nil {
+		return result, err
+	}
+	result.Decision = &decision
+	return result, nil
+}
+
+// icmContext 把 ICM 結果整理成給 AI 分析的文字
+func icmContext(result models.ICMResult) string {
+	lines := []string{"ICM context (tournament payouts, $EV per player):"}
+	for _, p := range result.Players {
+		lines = append(lines, fmt.Sprintf("- %s: %d chips (%.1f%% of chips), $EV %.2f", p.Player, p.Stack, p.ChipShare*100, p.Equity))
+	}
+	if d := result.Decision; d != nil {
+		lines = append(lines, fmt.Sprintf("Facing %s's all-in, hero risks %d chips with %.1f%% to win and %.1f%% to tie.", d.Villain, d.Risk, d.Win*100, d.Tie*100))
+		lines = append(lines, fmt.Sprintf("Call $EV %.2f vs fold $EV %.2f: %s is better under ICM (required equity %.1f%% under ICM vs %.1f%% in chips).",
+			d.CallEV, d.FoldEV, d.Best, d.RequiredEquity*100, d.ChipEquity*100))
+		if d.Actual != "" {
+			lines = append(lines, "Hero actually chose to "+d.Actual+".")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"poker_tracker_backend/importer"
+	"strings"
+)
+
+// ImportHands 匯入 PokerStars 手牌歷史
+// 可以直接以文字作為 request body，或以 multipart 上傳一個或多個 "file" 欄位
+func ImportHands(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	source, closeUpload, err := uploadedBody(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer closeUpload()
+
+	result, err := importer.ImportPokerStars(userRepo(r), source)
+	if err != nil {
+		http.Error(w, "Import failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// uploadedBody 回傳上傳的內容：直接使用 request body，或合併 multipart 的 "file" 欄位
+// 讀完後呼叫回傳的 close 關閉上傳的檔案
+func uploadedBody(r *http.Request) (io.Reader, func(), error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.Body, func() {}, nil
+	}
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		return nil, nil, fmt.Errorf("Invalid upload: %v", err)
+	}
+	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("Missing file field")
+	}
+	readers := []io.Reader{}
+	opened := []io.Closer{}
+	closeAll := func() {
+		for _, f := range opened {
+			f.Close()
+		}
+	}
+	for _, header := range files {
+		f, err := header.Open()
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("Failed to read upload: %v", err)
+		}
+		opened = append(opened, f)
+		// 檔案之間補一個換行，避免上一個檔案最後一行和下一手牌黏在一起
+		readers = append(readers, f, strings.NewReader("\n"))
+	}
+	return io.MultiReader(readers...), closeAll, nil
+}
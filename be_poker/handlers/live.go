@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"poker_tracker_backend/db"
+	"poker_tracker_backend/models"
+	"poker_tracker_backend/sessions"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// liveRequest 是 start/end 可選的 body：開始時的買入、結束時的兌現
+type liveRequest struct {
+	BuyIn   int  `json:"buyIn"`
+	CashOut *int `json:"cashOut"`
+}
+
+// SessionLive 處理 /sessions/{id}/start|pause|resume|end 與 GET /sessions/{id}/status
+// GET /sessions/live/status 回傳目前進行中的 session
+func SessionLive(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/sessions/"), "/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	id, action := parts[0], parts[1]
+
+	if action == "status" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		getLiveStatus(w, r, id)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req liveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, err := userRepo(r).GetSession(id)
+	if err != nil {
+		http.Error(w, "Session not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	var transaction *models.Transaction
+	switch action {
+	case "start":
+		// 同一時間只能有一個進行中的 session，新手牌才知道要掛在哪裡
+		if live, err := userRepo(r).LiveSession(); err == nil && live.ID != id {
+			http.Error(w, "Session "+live.ID+" is already live", http.StatusConflict)
+			return
+		} else if err != nil && !errors.Is(err, db.ErrNotFound) {
+			http.Error(w, "Database query error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		err = sessions.Start(&session, now)
+		if err == nil && req.BuyIn > 0 {
+			transaction = &models.Transaction{Type: sessions.BuyIn, Amount: req.BuyIn}
+		}
+	case "pause":
+		err = sessions.Pause(&session, now)
+	case "resume":
+		err = sessions.Resume(&session, now)
+	case "end":
+		err = sessions.End(&session, now)
+		if err == nil && req.CashOut != nil {
+			transaction = &models.Transaction{Type: sessions.CashOut, Amount: *req.CashOut}
+		}
+	default:
+		http.Error(w, "Unknown action: "+action, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	if transaction != nil {
+		if errs := sessions.ValidateTransaction("transaction", *transaction); len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+		transaction.ID = uuid.New().String()
+		transaction.SessionID = id
+		transaction.Time = now.UTC().Format(time.RFC3339)
+	}
+	if err := userRepo(r).UpdateSessionClock(session, transaction); err != nil {
+		http.Error(w, "Database update error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	getLiveStatus(w, r, id)
+}
+
+func getLiveStatus(w http.ResponseWriter, r *http.Request, id string) {
+	var session models.Session
+	var err error
+	if id == "live" {
+		session, err = userRepo(r).LiveSession()
+	} else {
+		session, err = userRepo(r).GetSession(id)
+	}
+	if err != nil {
+		http.Error(w, "Session not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// 每次輪詢只讀這個 session 的手牌
+	page, err := userRepo(r).QueryHands(db.HandFilter{SessionID: session.ID})
+	if err != nil {
+		http.Error(w, "Database query error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions.Status(session, page.Items, time.Now()))
+}
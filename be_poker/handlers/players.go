@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"poker_tracker_backend/db"
+	"poker_tracker_backend/models"
+	"poker_tracker_backend/stats"
+	"strings"
+)
+
+// MaxPlayerNameLength 是玩家名稱的最大長度，現場玩家常以外觀描述命名
+const MaxPlayerNameLength = 100
+
+// keepVillainPlayers 舊版客戶端編輯手牌時不會送 playerId，依 villain ID 保留原本的玩家連結
+func keepVillainPlayers(villains, existing []models.Villain) {
+	linked := map[string]string{}
+	for _, v := range existing {
+		if v.ID != "" && v.PlayerID != "" {
+			linked[v.ID] = v.PlayerID
+		}
+	}
+	for i := range villains {
+		v := &villains[i]
+		if v.PlayerID == "" && v.Name == "" {
+			v.PlayerID = linked[v.ID]
+		}
+	}
+}
+
+func validatePlayer(player models.Player) models.ValidationErrors {
+	var errs models.ValidationErrors
+	name := strings.TrimSpace(player.Name)
+	if name == "" {
+		errs.Add("name", "is required")
+	}
+	if len([]rune(name)) > MaxPlayerNameLength {
+		errs.Add("name", fmt.Sprintf("must be at most %d characters", MaxPlayerNameLength))
+	}
+	if player.Color != "" && !colorPattern.MatchString(player.Color) {
+		errs.Add("color", "must be a hex colour like #ff8800")
+	}
+	return errs
+}
+
+// GetPlayers 列出所有玩家與一起玩過的手數
+func GetPlayers(w http.ResponseWriter, r *http.Request) {
+	players, err := userRepo(r).ListPlayers()
+	if err != nil {
+		http.Error(w, "Query error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(players)
+}
+
+func CreatePlayer(w http.ResponseWriter, r *http.Request) {
+	var player models.Player
+	if err := json.NewDecoder(r.Body).Decode(&player); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errs := validatePlayer(player); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	created, err := userRepo(r).CreatePlayer(player)
+	if err != nil {
+		http.Error(w, "Insert error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func GetPlayer(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	player, err := userRepo(r).GetPlayer(id)
+	if err != nil {
+		http.Error(w, "Player not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(player)
+}
+
+// UpdatePlayer 修改名稱、站點、筆記或顏色，沒有送的欄位保留原值
+func UpdatePlayer(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	player, err := userRepo(r).GetPlayer(id)
+	if err != nil {
+		http.Error(w, "Player not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&player); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	player.ID = id
+	if errs := validatePlayer(player); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	if err := userRepo(r).UpdatePlayer(player); err != nil {
+		http.Error(w, "Update error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	updated, err := userRepo(r).GetPlayer(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve updated player", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeletePlayer 刪除玩家，手牌中的 villain 保留但取消連結
+func DeletePlayer(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	err := userRepo(r).DeletePlayer(id)
+	if errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "Player not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Delete error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PlayerDetail 處理 GET /players/{id}/hands、/players/{id}/stats 與 /players/{id}/hud
+// hands 接受與 GET /hands 相同的篩選與分頁參數
+func PlayerDetail(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/players/"), "/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, view := parts[0], parts[1]
+
+	player, err := userRepo(r).GetPlayer(id)
+	if err != nil {
+		http.Error(w, "Player not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	switch view {
+	case "hands":
+		getPlayerHands(w, r, player)
+	case "stats":
+		getPlayerSummary(w, r, player)
+	case "hud":
+		getPlayerHUD(w, r, player)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+func getPlayerHands(w http.ResponseWriter, r *http.Request, player models.Player) {
+	filter, paged, errs := parseHandFilter(r)
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	filter.PlayerID = player.ID
+
+	page, err := userRepo(r).QueryHands(filter)
+	if errors.Is(err, db.ErrInvalidFilter) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Query error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	writeTotal(w, page.Total)
+	if paged {
+		json.NewEncoder(w).Encode(page)
+		return
+	}
+	json.NewEncoder(w).Encode(page.Items)
+}
+
+// getPlayerSummary 回傳對上這位玩家的手數與 hero 的淨輸贏，金額換算成報表幣別
+func getPlayerSummary(w http.ResponseWriter, r *http.Request, player models.Player) {
+	page, err := userRepo(r).QueryHands(db.HandFilter{PlayerID: player.ID})
+	if err != nil {
+		http.Error(w, "Error querying hands: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sessionList, err := userRepo(r).ListSessions()
+	if err != nil {
+		http.Error(w, "Error querying sessions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	conv, err := newConverter(r)
+	if err != nil {
+		http.Error(w, "Error loading exchange rates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats.AgainstPlayer(player, sessionList, page.Items, conv))
+}
+
+// getPlayerHUD 回傳這位玩家的 VPIP、PFR、攻擊性、WTSD、W$SD 與面對持續下注棄牌率，附上樣本數
+func getPlayerHUD(w http.ResponseWriter, r *http.Request, player models.Player) {
+	page, err := userRepo(r).QueryHands(db.HandFilter{PlayerID: player.ID})
+	if err != nil {
+		http.Error(w, "Error querying hands: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats.HUDFor(player, page.Items))
+}
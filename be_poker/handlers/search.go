@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"poker_tracker_backend/db"
+	"poker_tracker_backend/models"
+	"poker_tracker_backend/search"
+	"poker_tracker_backend/sessions"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 搜尋結果預設筆數
+const defaultSearchLimit = 20
+
+// Search 處理 GET /search?q=，同時搜尋手牌與 session，依相關程度合併排序
+func Search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p := &queryParser{values: r.URL.Query()}
+	raw := p.str("q")
+	if raw == "" {
+		p.errs.Add("q", "is required")
+	}
+	limit := defaultSearchLimit
+	if n := p.integer("limit"); n != nil {
+		if *n < 1 || *n > db.MaxPageSize {
+			p.errs.Add("limit", "must be between 1 and "+strconv.Itoa(db.MaxPageSize))
+		} else {
+			limit = *n
+		}
+	}
+	query, err := search.Parse(raw)
+	if err != nil {
+		p.errs.Add("q", err.Error())
+	}
+	if len(p.errs) > 0 {
+		writeValidationErrors(w, p.errs)
+		return
+	}
+
+	result := models.SearchResult{Query: raw, Terms: query.Terms, Hits: []models.SearchHit{}}
+	if query.SearchHands {
+		hits, total, err := userRepo(r).SearchHands(query.Hands, query.Terms, limit)
+		if err != nil {
+			http.Error(w, "Search error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for i := range hits {
+			hand := hits[i].Hand
+			note := ""
+			if hand.Note != nil {
+				note = *hand.Note
+			}
+			hits[i].Highlights = highlights(query.Terms, map[string]string{
+				"note":     note,
+				"details":  hand.Details,
+				"analysis": hand.Analysis,
+			})
+		}
+		result.Hits = append(result.Hits, hits...)
+		result.Total += total
+	}
+	if query.SearchSessions {
+		hits, total, err := userRepo(r).SearchSessions(query.Sessions, query.Terms, limit)
+		if err != nil {
+			http.Error(w, "Search error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for i := range hits {
+			sessions.Summarize(hits[i].Session)
+			hits[i].Highlights = highlights(query.Terms, map[string]string{
+				"location": hits[i].Session.Location,
+				"tag":      hits[i].Session.Tag,
+			})
+		}
+		result.Hits = append(result.Hits, hits...)
+		result.Total += total
+	}
+
+	sort.SliceStable(result.Hits, func(i, j int) bool { return result.Hits[i].Score > result.Hits[j].Score })
+	if len(result.Hits) > limit {
+		result.Hits = result.Hits[:limit]
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// highlights 回傳有符合搜尋詞的欄位片段
+func highlights(terms []string, fields map[string]string) map[string]string {
+	if len(terms) == 0 {
+		return nil
+	}
+	out := map[string]string{}
+	for name, text := range fields {
+		if snippet := search.Highlight(strings.TrimSpace(text), terms); snippet != "" {
+			out[name] = snippet
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
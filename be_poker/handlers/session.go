@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"poker_tracker_backend/db"
 	"poker_tracker_backend/models"
+	"poker_tracker_backend/sessions"
+	"time"
 	"github.com/google/uuid"
 )
 
@@ -16,74 +18,61 @@ func CreateSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// 調試信息
-	fmt.Printf("DEBUG CreateSession: Received session data: %+v\n", session)
-	fmt.Printf("DEBUG CreateSession: Tag value: '%s'\n", session.Tag)
-	
-	// 只有當前端沒有提供ID時才生成新的UUID
-	if session.ID == "" {
-		session.ID = uuid.New().String()
-	}
-	
-	stmt, err := db.DB.Prepare(`INSERT INTO sessions (id, location, date, small_blind, big_blind, currency, effective_stack, table_size, tag) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`)
-	if err != nil {
-		http.Error(w, "Database prepare error: "+err.Error(), http.StatusInternalServerError)
+	// id 一律由伺服器產生，客戶端指定的 id 可能與既有的資料衝突
+	session.ID = uuid.New().String()
+	prepareTransactions(&session, nil)
+	session.Tags = legacyTags(session.Tags, session.Tag, nil)
+	if errs := append(sessions.Validate(&session), validateTags(session.Tags)...); len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
-	defer stmt.Close()
 	
-	fmt.Printf("DEBUG CreateSession: Executing INSERT with tag: '%s'\n", session.Tag)
-	_, err = stmt.Exec(session.ID, session.Location, session.Date, session.SmallBlind, session.BigBlind, session.Currency, session.EffectiveStack, session.TableSize, session.Tag)
-	if err != nil {
-		http.Error(w, "Database insert error: "+err.Error(), http.StatusInternalServerError)
+	if err := userRepo(r).CreateSession(session); err != nil {
+		writeSaveError(w, "Database insert error: ", err)
 		return
 	}
+	// 回傳含標籤 id 的 session
+	if saved, err := userRepo(r).GetSession(session.ID); err == nil {
+		session = saved
+	}
 	
 	// 設置Content-Type頭和CORS
+	sessions.Summarize(&session)
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(session)
 }
 
+// GetSessions 列出 session，參數與分頁方式和 GetHands 相同
 func GetSessions(w http.ResponseWriter, r *http.Request) {
-	// 移除ORDER BY created_at，因為Railway資料庫可能沒有這個欄位
-	// 改用date欄位排序
-	rows, err := db.DB.Query(`
-		SELECT 
-			id, 
-			COALESCE(location, ''), 
-			COALESCE(date, ''), 
-			COALESCE(small_blind, 0), 
-			COALESCE(big_blind, 0), 
-			COALESCE(currency, ''), 
-			COALESCE(effective_stack, 0), 
-			COALESCE(table_size, 6), 
-			COALESCE(tag, '') 
-		FROM sessions 
-		ORDER BY date DESC
-	`)
+	filter, paged, errs := parseSessionFilter(r)
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	
+	page, err := userRepo(r).QuerySessions(filter)
+	if errors.Is(err, db.ErrInvalidFilter) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Database query error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-	
-	sessions := []models.Session{}
-	for rows.Next() {
-		var s models.Session
-		err := rows.Scan(&s.ID, &s.Location, &s.Date, &s.SmallBlind, &s.BigBlind, &s.Currency, &s.EffectiveStack, &s.TableSize, &s.Tag)
-		if err != nil {
-			// 記錄錯誤但繼續處理其他行
-			fmt.Printf("Error scanning session row: %v\n", err)
-			continue
-		}
-		sessions = append(sessions, s)
+	for i := range page.Items {
+		sessions.Summarize(&page.Items[i])
 	}
 	
 	// 設置CORS和Content-Type頭
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(sessions)
+	writeTotal(w, page.Total)
+	if paged {
+		json.NewEncoder(w).Encode(page)
+		return
+	}
+	json.NewEncoder(w).Encode(page.Items)
 }
 
 func GetSession(w http.ResponseWriter, r *http.Request) {
@@ -93,27 +82,12 @@ func GetSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	row := db.DB.QueryRow(`
-		SELECT 
-			id, 
-			COALESCE(location, ''), 
-			COALESCE(date, ''), 
-			COALESCE(small_blind, 0), 
-			COALESCE(big_blind, 0), 
-			COALESCE(currency, ''), 
-			COALESCE(effective_stack, 0), 
-			COALESCE(table_size, 6), 
-			COALESCE(tag, '') 
-		FROM sessions 
-		WHERE id = $1
-	`, id)
-	
-	var s models.Session
-	err := row.Scan(&s.ID, &s.Location, &s.Date, &s.SmallBlind, &s.BigBlind, &s.Currency, &s.EffectiveStack, &s.TableSize, &s.Tag)
+	s, err := userRepo(r).GetSession(id)
 	if err != nil {
 		http.Error(w, "Session not found: "+err.Error(), http.StatusNotFound)
 		return
 	}
+	sessions.Summarize(&s)
 	
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
@@ -127,47 +101,56 @@ func UpdateSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	var session models.Session
-	if err := json.NewDecoder(r.Body).Decode(&session); err != nil {
+	// endTime 以指標區分沒有送出（保留原本的值）與送出空字串（清除結束時間）
+	var body struct {
+		models.Session
+		EndTime *string `json:"endTime"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	session := body.Session
 	
-	stmt, err := db.DB.Prepare(`UPDATE sessions SET location = $1, date = $2, small_blind = $3, big_blind = $4, currency = $5, effective_stack = $6, table_size = $7, tag = $8 WHERE id = $9`)
+	// 舊版客戶端不會送時間、現金異動與 tags，保留資料庫中原本的值
+	existing, err := userRepo(r).GetSession(id)
 	if err != nil {
-		http.Error(w, "Database prepare error: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Session not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	if session.StartTime == "" {
+		session.StartTime = existing.StartTime
+	}
+	session.EndTime = existing.EndTime
+	if body.EndTime != nil {
+		session.EndTime = *body.EndTime
+	}
+	if session.Type == "" {
+		session.Type = existing.Type
+	}
+	if session.Tournament == nil && sessions.IsTournament(session) {
+		session.Tournament = existing.Tournament
+	}
+	session.Tags = legacyTags(session.Tags, session.Tag, existing.Tags)
+	session.ID = id
+	prepareTransactions(&session, existing.Transactions)
+	if errs := append(sessions.Validate(&session), validateTags(session.Tags)...); len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
-	defer stmt.Close()
 	
-	_, err = stmt.Exec(session.Location, session.Date, session.SmallBlind, session.BigBlind, session.Currency, session.EffectiveStack, session.TableSize, session.Tag, id)
-	if err != nil {
-		http.Error(w, "Database update error: "+err.Error(), http.StatusInternalServerError)
+	if err := userRepo(r).UpdateSession(id, session); err != nil {
+		writeSaveError(w, "Database update error: ", err)
 		return
 	}
 	
 	// 返回更新後的session
-	row := db.DB.QueryRow(`
-		SELECT 
-			id, 
-			COALESCE(location, ''), 
-			COALESCE(date, ''), 
-			COALESCE(small_blind, 0), 
-			COALESCE(big_blind, 0), 
-			COALESCE(currency, ''), 
-			COALESCE(effective_stack, 0), 
-			COALESCE(table_size, 6), 
-			COALESCE(tag, '') 
-		FROM sessions 
-		WHERE id = $1
-	`, id)
-	
-	var updatedSession models.Session
-	err = row.Scan(&updatedSession.ID, &updatedSession.Location, &updatedSession.Date, &updatedSession.SmallBlind, &updatedSession.BigBlind, &updatedSession.Currency, &updatedSession.EffectiveStack, &updatedSession.TableSize, &updatedSession.Tag)
+	updatedSession, err := userRepo(r).GetSession(id)
 	if err != nil {
 		http.Error(w, "Failed to retrieve updated session: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	sessions.Summarize(&updatedSession)
 	
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
@@ -181,12 +164,96 @@ func DeleteSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	_, err := db.DB.Exec(`DELETE FROM sessions WHERE id = $1`, id)
-	if err != nil {
+	if err := userRepo(r).DeleteSession(id); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, "Database delete error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 	
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.WriteHeader(http.StatusNoContent)
-} 
\ No newline at end of file
+} 
+// prepareTransactions 為現金異動產生 ID 並指定所屬 session
+// 只保留 existing（這個 session 原本的異動）中的 ID，其他一律換成新的 ID，避免與別的 session 的資料衝突
+func prepareTransactions(session *models.Session, existing []models.Transaction) {
+	kept := map[string]bool{}
+	for _, t := range existing {
+		kept[t.ID] = true
+	}
+	for i := range session.Transactions {
+		t := &session.Transactions[i]
+		if !kept[t.ID] {
+			t.ID = uuid.New().String()
+		}
+		delete(kept, t.ID)
+		t.SessionID = session.ID
+	}
+}
+
+// AddSessionTransaction 新增一筆買入、補碼或兌現，回傳更新後的 session
+func AddSessionTransaction(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("sessionId")
+	if sessionID == "" {
+		http.Error(w, "Missing sessionId parameter", http.StatusBadRequest)
+		return
+	}
+	if _, err := userRepo(r).GetSession(sessionID); err != nil {
+		http.Error(w, "Session not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var t models.Transaction
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errs := sessions.ValidateTransaction("transaction", t); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	t.ID = uuid.New().String()
+	t.SessionID = sessionID
+	if t.Time == "" {
+		t.Time = time.Now().UTC().Format(time.RFC3339)
+	}
+	if err := userRepo(r).AddTransaction(t); err != nil {
+		http.Error(w, "Database insert error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	session, err := userRepo(r).GetSession(sessionID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve updated session: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sessions.Summarize(&session)
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// DeleteSessionTransaction 刪除一筆現金異動
+func DeleteSessionTransaction(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	if err := userRepo(r).DeleteTransaction(id); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			http.Error(w, "Transaction not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database delete error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"poker_tracker_backend/auth"
+	"poker_tracker_backend/db"
+	"poker_tracker_backend/models"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultShareHours 是分享連結預設的有效時數
+	DefaultShareHours = 7 * 24
+	// MaxShareHours 是分享連結最長的有效時數
+	MaxShareHours = 90 * 24
+)
+
+// HandDetail 處理手牌的分享連結：
+// POST /hands/{id}/share 建立、GET /hands/{id}/shares 列出、DELETE /hands/{id}/shares/{shareId} 撤銷
+func HandDetail(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/hands/"), "/"), "/")
+	if len(parts) < 2 || parts[0] == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	id := parts[0]
+
+	switch {
+	case len(parts) == 2 && parts[1] == "share":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		createHandShare(w, r, id)
+	case len(parts) == 2 && parts[1] == "shares":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		listHandShares(w, r, id)
+	case len(parts) == 3 && parts[1] == "shares":
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		revokeHandShare(w, r, id, parts[2])
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// shareURL 是分享連結的完整網址，Railway 等反向代理以 X-Forwarded-Proto 告知 https
+func shareURL(r *http.Request, token string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/shared/%s", scheme, r.Host, token)
+}
+
+func createHandShare(w http.ResponseWriter, r *http.Request, handID string) {
+	var request struct {
+		ExpiresInHours int  `json:"expiresInHours"`
+		HideHoleCards  bool `json:"hideHoleCards"`
+		HideResults    bool `json:"hideResults"`
+	}
+	// 沒有 body 時使用預設值
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.ExpiresInHours == 0 {
+		request.ExpiresInHours = DefaultShareHours
+	}
+	var errs models.ValidationErrors
+	if request.ExpiresInHours < 1 || request.ExpiresInHours > MaxShareHours {
+		errs.Add("expiresInHours", fmt.Sprintf("must be between 1 and %d", MaxShareHours))
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	token, tokenHash, err := auth.NewToken()
+	if err != nil {
+		http.Error(w, "Token error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	share, err := userRepo(r).CreateHandShare(models.HandShare{
+		HandID:        handID,
+		HideHoleCards: request.HideHoleCards,
+		HideResults:   request.HideResults,
+		ExpiresAt:     time.Now().Add(time.Duration(request.ExpiresInHours) * time.Hour).UTC().Format(time.RFC3339),
+	}, tokenHash)
+	if errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "Hand not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Insert error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// 連結只在建立時回傳一次，之後無法從資料庫還原
+	share.Token = token
+	share.URL = shareURL(r, token)
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(share)
+}
+
+func listHandShares(w http.ResponseWriter, r *http.Request, handID string) {
+	if _, err := userRepo(r).GetHand(handID); err != nil {
+		http.Error(w, "Hand not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	shares, err := userRepo(r).ListHandShares(handID)
+	if err != nil {
+		http.Error(w, "Query error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shares)
+}
+
+func revokeHandShare(w http.ResponseWriter, r *http.Request, handID, shareID string) {
+	err := userRepo(r).RevokeHandShare(handID, shareID)
+	if errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "Share not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Update error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSharedHand 處理公開的 GET /shared/{token}，不需要登入
+// 過期或撤銷的連結回傳 410
+func GetSharedHand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := strings.Trim(strings.TrimPrefix(r.URL.Path, "/shared/"), "/")
+	if token == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	share, err := db.Repo.HandShareByToken(auth.HashToken(token))
+	if err != nil {
+		http.Error(w, "Shared hand not found", http.StatusNotFound)
+		return
+	}
+	if !share.Active {
+		http.Error(w, "This link has expired or been revoked", http.StatusGone)
+		return
+	}
+
+	// 以分享者的資料讀取手牌，與 GetHand 回傳的內容相同
+	repo := db.Repo.ForUser(share.OwnerID)
+	hand, err := repo.GetHand(share.HandID)
+	if err != nil {
+		http.Error(w, "Shared hand not found", http.StatusNotFound)
+		return
+	}
+	var session models.Session
+	if hand.SessionID != "" {
+		session, _ = repo.GetSession(hand.SessionID)
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(sharedHand(hand, session, share))
+}
+
+// sharedHand 把手牌轉成公開的內容：不含筆記、標籤與對手檔案
+// 隱藏手牌時也不附上文字描述與分析，因為其中常會寫到手牌
+// 隱藏結果時同樣不附上描述與分析，也不公開對手在攤牌時亮出的手牌，看了公共牌就知道誰贏
+func sharedHand(hand models.Hand, session models.Session, share models.HandShare) models.SharedHand {
+	shared := models.SharedHand{
+		Position:      hand.Position,
+		HoleCards:     hand.HoleCards,
+		Board:         hand.Board,
+		Details:       hand.Details,
+		Villains:      []models.Villain{},
+		Streets:       hand.Streets,
+		Analysis:      hand.Analysis,
+		Date:          hand.Date,
+		SmallBlind:    session.SmallBlind,
+		BigBlind:      session.BigBlind,
+		Currency:      session.Currency,
+		Level:         hand.Level,
+		Ante:          hand.Ante,
+		HideHoleCards: share.HideHoleCards,
+		HideResults:   share.HideResults,
+		ExpiresAt:     share.ExpiresAt,
+	}
+	if hand.Date == "" {
+		shared.Date = session.Date
+	}
+	for _, v := range hand.Villains {
+		villain := models.Villain{Position: v.Position, HoleCards: v.HoleCards, Range: v.Range}
+		if share.HideHoleCards || share.HideResults {
+			villain.HoleCards = ""
+		}
+		shared.Villains = append(shared.Villains, villain)
+	}
+	if share.HideHoleCards {
+		shared.HoleCards = nil
+		shared.Details = ""
+		shared.Analysis = ""
+	}
+	if share.HideResults {
+		shared.Details = ""
+		shared.Analysis = ""
+	} else {
+		result := hand.Result
+		shared.Result = &result
+	}
+	return shared
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"poker_tracker_backend/evaluator"
+	"poker_tracker_backend/models"
+)
+
+// GetHandShowdown 回傳手牌以及每位玩家的牌型與攤牌贏家
+func GetHandShowdown(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	hand, err := userRepo(r).GetHand(id)
+	if err != nil {
+		http.Error(w, "Hand not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	showdown, err := evaluator.Showdown(hand)
+	if err != nil {
+		http.Error(w, "Invalid cards: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	response := struct {
+		models.Hand
+		Showdown models.Showdown `json:"showdown"`
+	}{hand, showdown}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -2,89 +2,53 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
-	"poker_tracker_backend/db"
-	"poker_tracker_backend/models"
+	"poker_tracker_backend/stats"
 )
 
 func GetStats(w http.ResponseWriter, r *http.Request) {
-	handsRows, err := db.DB.Query(`SELECT COALESCE(result_amount, 0), COALESCE(session_id, '') FROM hands`)
+	hands, err := userRepo(r).ListHands()
 	if err != nil {
 		http.Error(w, "Error querying hands: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer handsRows.Close()
 	
-	sessionsRows, err := db.DB.Query(`SELECT id, COALESCE(location, ''), COALESCE(small_blind, 0), COALESCE(big_blind, 0) FROM sessions`)
+	sessions, err := userRepo(r).ListSessions()
 	if err != nil {
 		http.Error(w, "Error querying sessions: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer sessionsRows.Close()
 
-	totalProfit := 0
-	sessionProfits := map[string]int{}
-	byStakes := map[string]int{}
-	byLocation := map[string]int{}
-	sessionCount := 0
-	winSessions := 0
-
-	for handsRows.Next() {
-		var result int
-		var sessionId string
-		err := handsRows.Scan(&result, &sessionId)
-		if err != nil {
-			continue
-		}
-		totalProfit += result
-		if _, ok := sessionProfits[sessionId]; !ok {
-			sessionProfits[sessionId] = 0
-		}
-		sessionProfits[sessionId] += result
+	conv, err := newConverter(r)
+	if err != nil {
+		http.Error(w, "Error loading exchange rates: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	for sessionsRows.Next() {
-		var id, location string
-		var sb, bb int
-		err := sessionsRows.Scan(&id, &location, &sb, &bb)
-		if err != nil {
-			continue
-		}
-		sessionCount++
-		profit := sessionProfits[id]
-		if profit > 0 {
-			winSessions++
-		}
-		stakeKey := "$" + itoa(sb) + "/" + itoa(bb)
-		byStakes[stakeKey] += profit
-		byLocation[location] += profit
-	}
+	result := stats.Compute(sessions, hands, conv)
+	
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	
+	json.NewEncoder(w).Encode(result)
+}
 
-	avgSession := 0.0
-	if sessionCount > 0 {
-		avgSession = float64(totalProfit) / float64(sessionCount)
-	}
-	winRate := 0
-	if sessionCount > 0 {
-		winRate = int(float64(winSessions) / float64(sessionCount) * 100)
+// GetPlayerStats 回傳 Hero 的 VPIP、PFR、3-bet 等統計，依位置與級別分組
+func GetPlayerStats(w http.ResponseWriter, r *http.Request) {
+	hands, err := userRepo(r).ListHands()
+	if err != nil {
+		http.Error(w, "Error querying hands: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	stats := models.Stats{
-		TotalProfit:    totalProfit,
-		TotalSessions:  sessionCount,
-		WinRate:        winRate,
-		AvgSession:     avgSession,
-		ByStakes:       byStakes,
-		ByLocation:     byLocation,
+	sessions, err := userRepo(r).ListSessions()
+	if err != nil {
+		http.Error(w, "Error querying sessions: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
-	
+
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
-	
-	json.NewEncoder(w).Encode(stats)
-}
 
-func itoa(i int) string {
-	return fmt.Sprintf("%d", i)
-} 
\ No newline at end of file
+	json.NewEncoder(w).Encode(stats.PlayerStatsFor(sessions, hands))
+}
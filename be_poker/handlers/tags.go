@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"poker_tracker_backend/db"
+	"poker_tracker_backend/models"
+	"regexp"
+	"strings"
+)
+
+// MaxTagNameLength 是標籤名稱的最大長度
+const MaxTagNameLength = 50
+
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// legacyTags 處理只送舊版 tag 欄位的客戶端：
+// 有 tags 時直接使用；只有 tag 時把它放到第一個，保留原本其他標籤；兩者都沒有時回傳 nil 表示不變
+func legacyTags(tags []models.Tag, tag string, existing []models.Tag) []models.Tag {
+	tag = strings.TrimSpace(tag)
+	if tags != nil || tag == "" {
+		return tags
+	}
+	if len(existing) > 0 && strings.EqualFold(existing[0].Name, tag) {
+		return nil
+	}
+	merged := []models.Tag{{Name: tag}}
+	for _, t := range existing {
+		if !strings.EqualFold(t.Name, tag) {
+			merged = append(merged, models.Tag{ID: t.ID})
+		}
+	}
+	return merged
+}
+
+// validateTag 驗證標籤的名稱與顏色；requireName 為 false 時可以只給 id
+func validateTag(errs *models.ValidationErrors, field string, tag models.Tag, requireName bool) {
+	name := strings.TrimSpace(tag.Name)
+	if name == "" && (requireName || tag.ID == "") {
+		errs.Add(field+".name", "is required")
+	}
+	if len([]rune(name)) > MaxTagNameLength {
+		errs.Add(field+".name", fmt.Sprintf("must be at most %d characters", MaxTagNameLength))
+	}
+	if tag.Color != "" && !colorPattern.MatchString(tag.Color) {
+		errs.Add(field+".color", "must be a hex colour like #ff8800")
+	}
+}
+
+func validateTags(tags []models.Tag) models.ValidationErrors {
+	var errs models.ValidationErrors
+	for i, tag := range tags {
+		validateTag(&errs, fmt.Sprintf("tags[%d]", i), tag, false)
+	}
+	return errs
+}
+
+// writeSaveError 回傳寫入手牌或 session 的錯誤，引用不存在的標籤或玩家 id 視為請求錯誤
+func writeSaveError(w http.ResponseWriter, prefix string, err error) {
+	if errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "Invalid reference: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, prefix+err.Error(), http.StatusInternalServerError)
+}
+
+// GetTags 列出所有標籤與使用次數
+func GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := userRepo(r).ListTags()
+	if err != nil {
+		http.Error(w, "Query error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// CreateTag 新增標籤，同名的標籤已存在時回傳 409
+func CreateTag(w http.ResponseWriter, r *http.Request) {
+	var tag models.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	tag.ID = ""
+	var errs models.ValidationErrors
+	validateTag(&errs, "tag", tag, true)
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	created, err := userRepo(r).CreateTag(tag)
+	if errors.Is(err, db.ErrTagExists) {
+		http.Error(w, "Tag already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Insert error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func GetTag(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := userRepo(r).GetTag(id)
+	if err != nil {
+		http.Error(w, "Tag not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
+
+// UpdateTag 改名或修改顏色、分類；改成已存在的名稱時回傳 409，請改用 /tags/merge
+func UpdateTag(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := userRepo(r).GetTag(id)
+	if err != nil {
+		http.Error(w, "Tag not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	// 沒有送的欄位保留原值
+	tag := existing
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	tag.ID = id
+	var errs models.ValidationErrors
+	validateTag(&errs, "tag", tag, true)
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	err = userRepo(r).UpdateTag(tag)
+	if errors.Is(err, db.ErrTagExists) {
+		http.Error(w, "Another tag already has this name; merge the tags instead", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Update error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := userRepo(r).GetTag(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve updated tag", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteTag 刪除標籤並從所有手牌與 session 移除
+func DeleteTag(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	err := userRepo(r).DeleteTag(id)
+	if errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Delete error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MergeTags 把 sourceIds 的標籤併入 targetId，回傳合併後的目標標籤
+func MergeTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		TargetID  string   `json:"targetId"`
+		SourceIDs []string `json:"sourceIds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var errs models.ValidationErrors
+	if request.TargetID == "" {
+		errs.Add("targetId", "is required")
+	}
+	if len(request.SourceIDs) == 0 {
+		errs.Add("sourceIds", "must list at least one tag")
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	err := userRepo(r).MergeTags(request.TargetID, request.SourceIDs)
+	if errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "Tag not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Merge error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	merged, err := userRepo(r).GetTag(request.TargetID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve merged tag", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merged)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"poker_tracker_backend/actions"
+	"poker_tracker_backend/cards"
+	"poker_tracker_backend/models"
+)
+
+// validateHand 驗證手牌內容並將牌轉成標準格式
+// checkActions 為 true 時驗證結構化動作並重新產生 Details
+func validateHand(hand *models.Hand, checkActions bool) models.ValidationErrors {
+	errs := cards.ValidateHand(hand)
+	if hand.Level < 0 {
+		errs.Add("level", "must not be negative")
+	}
+	if hand.Ante < 0 {
+		errs.Add("ante", "must not be negative")
+	}
+
+	if checkActions && len(hand.Streets) > 0 {
+		errs = append(errs, actions.Validate(hand.Streets)...)
+		if len(errs) == 0 {
+			position := ""
+			if hand.Position != nil {
+				position = *hand.Position
+			}
+			hand.Details = actions.Render(hand.Streets, position)
+		}
+	}
+	return errs
+}
+
+// writeValidationErrors 以 400 回傳每個欄位的錯誤
+func writeValidationErrors(w http.ResponseWriter, errs models.ValidationErrors) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  "Validation failed",
+		"fields": errs,
+	})
+}
@@ -0,0 +1,219 @@
+package icm
+
+import (
+	"fmt"
+	"poker_tracker_backend/actions"
+	"poker_tracker_backend/cards"
+	"poker_tracker_backend/equity"
+	"poker_tracker_backend/models"
+)
+
+// Players 計算每位玩家的籌碼佔比與 $EV
+func Players(stacks []models.ICMStack, payouts []float64) ([]models.ICMPlayer, error) {
+	values := make([]float64, len(stacks))
+	total := 0.0
+	for i, s := range stacks {
+		values[i] = float64(s.Stack)
+		total += values[i]
+	}
+	equities, err := Equities(values, payouts)
+	if err != nil {
+		return nil, err
+	}
+	players := make([]models.ICMPlayer, len(stacks))
+	for i, s := range stacks {
+		players[i] = models.ICMPlayer{Player: s.Player, Stack: s.Stack, Equity: equities[i]}
+		if total > 0 {
+			players[i].ChipShare = values[i] / total
+		}
+	}
+	return players, nil
+}
+
+// HandDecision 找出 Hero 面對對手全下的決策點，比較跟注與棄牌的 $EV
+// stacks 是這手牌開始前每個 actor 的籌碼；其他還沒行動的玩家假設會棄牌
+func HandDecision(hand models.Hand, stacks []models.ICMStack, payouts []float64, villainRange string, opts equity.Options) (models.ICMDecision, error) {
+	var decision models.ICMDecision
+	if hand.Position == nil || *hand.Position == "" {
+		return decision, fmt.Errorf("hand has no hero position")
+	}
+	hero := *hand.Position
+	decision.Hero = hero
+
+	// 找到對手全下之後 Hero 的第一個動作，累計在那之前每個人投入的籌碼
+	contributions := map[string]int{}
+	villain := ""
+	found := false
+	for _, street := range hand.Streets {
+		for _, a := range street.Actions {
+			if a.Actor == hero && villain != "" {
+				decision.Actual = a.Type
+				found = true
+				break
+			}
+			contributions[a.Actor] += a.Amount
+			if a.AllIn && a.Actor != hero && villain == "" {
+				villain = a.Actor
+			}
+		}
+		if found {
+			break
+		}
+	}
+	if villain == "" {
+		return decision, fmt.Errorf("hero never faces an all-in in this hand")
+	}
+	decision.Villain = villain
+
+	index := map[string]int{}
+	start := make([]float64, len(stacks))
+	for i, s := range stacks {
+		index[s.Player] = i
+		start[i] = float64(s.Stack)
+	}
+	h, ok := index[hero]
+	if !ok {
+		return decision, fmt.Errorf("no stack given for hero (%s)", hero)
+	}
+	v, ok := index[villain]
+	if !ok {
+		return decision, fmt.Errorf("no stack given for villain (%s)", villain)
+	}
+
+	// 其他玩家投入的籌碼成為死錢
+	base := make([]float64, len(stacks))
+	dead := 0.0
+	for i, s := range stacks {
+		base[i] = start[i] - float64(contributions[s.Player])
+		if base[i] < 0 {
+			return decision, fmt.Errorf("%s puts in more chips than their stack", s.Player)
+		}
+		if i != h && i != v {
+			dead += float64(contributions[s.Player])
+		}
+	}
+	for actor := range contributions {
+		if _, ok := index[actor]; !ok {
+			return decision, fmt.Errorf("no stack given for %s", actor)
+		}
+	}
+	heroIn := float64(contributions[hero])
+	villainIn := float64(contributions[villain])
+
+	// 棄牌：對手拿走底池
+	fold := append([]float64{}, base...)
+	fold[v] += heroIn + villainIn + dead
+
+	// 跟注：兩人都投入較短的籌碼量，多出的部分退回
+	risk := start[h]
+	if start[v] < risk {
+		risk = start[v]
+	}
+	decision.Risk = int(risk)
+	pot := 2*risk + dead
+	afterCall := append([]float64{}, start...)
+	for i := range afterCall {
+		if i != h && i != v {
+			afterCall[i] = base[i]
+		}
+	}
+	afterCall[h] -= risk
+	afterCall[v] -= risk
+	win := append([]float64{}, afterCall...)
+	win[h] += pot
+	lose := append([]float64{}, afterCall...)
+	lose[v] += pot
+	tie := append([]float64{}, afterCall...)
+	tie[h] += pot / 2
+	tie[v] += pot / 2
+
+	foldEV, err := Equities(fold, payouts)
+	if err != nil {
+		return decision, err
+	}
+	winEV, err := Equities(win, payouts)
+	if err != nil {
+		return decision, err
+	}
+	loseEV, err := Equities(lose, payouts)
+	if err != nil {
+		return decision, err
+	}
+	tieEV, err := Equities(tie, payouts)
+	if err != nil {
+		return decision, err
+	}
+
+	winProb, tieProb, err := showdownOdds(hand, villain, villainRange, opts)
+	if err != nil {
+		return decision, err
+	}
+	decision.Win = winProb
+	decision.Tie = tieProb
+	decision.FoldEV = foldEV[h]
+	decision.WinEV = winEV[h]
+	decision.LoseEV = loseEV[h]
+	decision.CallEV = winProb*winEV[h] + tieProb*tieEV[h] + (1-winProb-tieProb)*loseEV[h]
+	if winEV[h] > loseEV[h] {
+		decision.RequiredEquity = (foldEV[h] - loseEV[h]) / (winEV[h] - loseEV[h])
+	}
+	// 以籌碼計算：跟注的金額除以跟注後的底池
+	toCall := risk - heroIn
+	if toCall > 0 {
+		decision.ChipEquity = toCall / pot
+	}
+	decision.Best = "fold"
+	if decision.CallEV > decision.FoldEV {
+		decision.Best = "call"
+	}
+	return decision, nil
+}
+
+// showdownOdds 計算 Hero 對上對手手牌（或範圍）的獨贏與平分機率
+func showdownOdds(hand models.Hand, villain, villainRange string, opts equity.Options) (float64, float64, error) {
+	if hand.HoleCards == nil || *hand.HoleCards == "" {
+		return 0, 0, fmt.Errorf("hand has no hero hole cards")
+	}
+	heroCombos, err := equity.ParseRange(*hand.HoleCards)
+	if err != nil {
+		return 0, 0, fmt.Errorf("hero: %v", err)
+	}
+
+	spec := villainRange
+	if spec == "" {
+		for _, v := range hand.Villains {
+			if v.Position != villain {
+				continue
+			}
+			spec = v.HoleCards
+			if spec == "" {
+				spec = v.Range
+			}
+		}
+	}
+	if spec == "" {
+		return 0, 0, fmt.Errorf("villain (%s) cards are unknown, provide villainRange", villain)
+	}
+	villainCombos, err := equity.ParseRange(spec)
+	if err != nil {
+		return 0, 0, fmt.Errorf("villain: %v", err)
+	}
+
+	// 全下當時的公共牌
+	var board []cards.Card
+	if hand.Board != nil && *hand.Board != "" {
+		board, err = cards.ParseList(*hand.Board)
+		if err != nil {
+			return 0, 0, fmt.Errorf("board: %v", err)
+		}
+	}
+	if street, ok := actions.AllInStreet(hand.Streets); ok && actions.BoardSize(street) < len(board) {
+		board = board[:actions.BoardSize(street)]
+	}
+
+	result, err := equity.Calculate([][]equity.Combo{heroCombos, villainCombos}, board, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+	return result.Players[0].Win, result.Players[0].Tie, nil
+}
@@ -0,0 +1,94 @@
+package icm
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// MaxPlayers 是一次計算的玩家上限，計算量是 2^n
+const MaxPlayers = 20
+
+// Equities 以 Malmuth-Harville 模型計算每位玩家的獎金期望值
+// payouts 依名次排列（第一名在前）；籌碼為 0 的玩家視為已淘汰，平分最後幾個名次的獎金
+func Equities(stacks []float64, payouts []float64) ([]float64, error) {
+	n := len(stacks)
+	if n == 0 {
+		return nil, fmt.Errorf("at least one stack is required")
+	}
+	if n > MaxPlayers {
+		return nil, fmt.Errorf("at most %d players are supported", MaxPlayers)
+	}
+	for i, p := range payouts {
+		if p < 0 {
+			return nil, fmt.Errorf("payout %d must not be negative", i+1)
+		}
+	}
+
+	alive := []int{}
+	for i, s := range stacks {
+		if s < 0 {
+			return nil, fmt.Errorf("stack %d must not be negative", i+1)
+		}
+		if s > 0 {
+			alive = append(alive, i)
+		}
+	}
+	if len(alive) == 0 {
+		return nil, fmt.Errorf("at least one player must have chips")
+	}
+
+	equities := make([]float64, n)
+
+	// 已淘汰的玩家平分最後幾個名次的獎金
+	if busted := n - len(alive); busted > 0 {
+		share := 0.0
+		for place := len(alive); place < n && place < len(payouts); place++ {
+			share += payouts[place]
+		}
+		share /= float64(busted)
+		for i, s := range stacks {
+			if s == 0 {
+				equities[i] = share
+			}
+		}
+	}
+
+	m := len(alive)
+	places := len(payouts)
+	if places > m {
+		places = m
+	}
+
+	// prob[mask] 是 mask 中的玩家依序拿下前幾名的機率
+	// chips[mask] 是 mask 中玩家的籌碼總和
+	size := 1 << uint(m)
+	prob := make([]float64, size)
+	chips := make([]float64, size)
+	for mask := 1; mask < size; mask++ {
+		low := bits.TrailingZeros(uint(mask))
+		chips[mask] = chips[mask&(mask-1)] + stacks[alive[low]]
+	}
+	total := chips[size-1]
+
+	prob[0] = 1
+	for mask := 0; mask < size; mask++ {
+		p := prob[mask]
+		if p == 0 {
+			continue
+		}
+		place := bits.OnesCount(uint(mask))
+		if place >= places {
+			continue
+		}
+		remaining := total - chips[mask]
+		for i := 0; i < m; i++ {
+			if mask&(1<<uint(i)) != 0 {
+				continue
+			}
+			q := p * stacks[alive[i]] / remaining
+			equities[alive[i]] += q * payouts[place]
+			prob[mask|1<<uint(i)] += q
+		}
+	}
+	return equities, nil
+}
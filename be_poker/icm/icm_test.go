@@ -0,0 +1,45 @@
+package icm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEquities(t *testing.T) {
+	tests := []struct {
+		name    string
+		stacks  []float64
+		payouts []float64
+		want    []float64
+	}{
+		// Malmuth-Harville 的教科書例子：50/30/20 的獎金，籌碼 5000/3000/2000
+		{"three players", []float64{5000, 3000, 2000}, []float64{50, 30, 20}, []float64{38.3929, 32.75, 28.8571}},
+		{"equal stacks", []float64{1000, 1000, 1000, 1000}, []float64{50, 30, 20}, []float64{25, 25, 25, 25}},
+		{"winner takes all", []float64{7500, 2500}, []float64{100}, []float64{75, 25}},
+		{"busted player", []float64{6000, 4000, 0}, []float64{50, 30, 20}, []float64{42, 38, 20}},
+	}
+	for _, tt := range tests {
+		got, err := Equities(tt.stacks, tt.payouts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for i := range tt.want {
+			if math.Abs(got[i]-tt.want[i]) > 0.001 {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestEquitiesRejectsInvalidInput(t *testing.T) {
+	if _, err := Equities(nil, []float64{100}); err == nil {
+		t.Error("expected an error for no stacks")
+	}
+	if _, err := Equities([]float64{100, -1}, []float64{100}); err == nil {
+		t.Error("expected an error for a negative stack")
+	}
+	if _, err := Equities(make([]float64, MaxPlayers+1), []float64{100}); err == nil {
+		t.Error("expected an error for too many players")
+	}
+}
@@ -0,0 +1,243 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"poker_tracker_backend/actions"
+	"poker_tracker_backend/cards"
+	"poker_tracker_backend/db"
+	"poker_tracker_backend/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Result 是一次匯入的摘要
+type Result struct {
+	SessionsCreated int      `json:"sessionsCreated"`
+	HandsImported   int      `json:"handsImported"`
+	HandsSkipped    int      `json:"handsSkipped"` // 已經匯入過的手牌
+	Errors          []string `json:"errors"`
+}
+
+// ImportPokerStars 解析 PokerStars 手牌歷史並以 repo 寫入資料庫
+// 同一張桌子、同一級別、同一天的手牌會歸到同一個 session，包括之前匯入時建立的 session
+// 新的 session 與它的第一手牌一起寫入，手牌都存不進去時不會留下空的 session
+func ImportPokerStars(repo db.Repository, r io.Reader) (Result, error) {
+	result := Result{Errors: []string{}}
+
+	hands, errs := ParsePokerStars(r)
+	for _, err := range errs {
+		result.Errors = append(result.Errors, err.Error())
+	}
+
+	sessions := map[string]*models.Session{}
+	for _, parsed := range hands {
+		externalID := "pokerstars:" + parsed.HandNumber
+		exists, err := repo.ExternalHandExists(externalID)
+		if err != nil {
+			return result, err
+		}
+		if exists {
+			result.HandsSkipped++
+			continue
+		}
+
+		scale := amountScale(parsed)
+		key := sessionKey(parsed)
+		session, ok := sessions[key]
+		if !ok {
+			existing, err := repo.SessionByExternalID(key)
+			switch {
+			case err == nil:
+				session = &existing
+				sessions[key] = session
+			case !errors.Is(err, db.ErrNotFound):
+				return result, err
+			}
+		}
+
+		hand := newHand(parsed, scale)
+		hand.ExternalID = externalID
+		if session != nil {
+			hand.SessionID = session.ID
+			err = repo.CreateHand(hand)
+		} else {
+			created := newSession(parsed, key, scale)
+			if err = repo.CreateSessionWithHand(created, hand); err == nil {
+				sessions[key] = &created
+				result.SessionsCreated++
+			}
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("hand #%s: %v", parsed.HandNumber, err))
+			continue
+		}
+		result.HandsImported++
+	}
+	return result, nil
+}
+
+// amountScale 決定金額的單位：盲注有小數時以「分」為單位儲存，因為金額欄位都是整數
+func amountScale(h ParsedHand) float64 {
+	if h.SmallBlind != math.Trunc(h.SmallBlind) || h.BigBlind != math.Trunc(h.BigBlind) {
+		return 100
+	}
+	return 1
+}
+
+func toUnits(amount, scale float64) int {
+	return int(math.Round(amount * scale))
+}
+
+// sessionKey 是 session 的來源編號，以桌名、幣別、級別與 session 日期的那一天區分
+func sessionKey(h ParsedHand) string {
+	return "pokerstars:" + strings.Join([]string{
+		h.TableName,
+		h.Currency,
+		formatAmount(h.SmallBlind),
+		formatAmount(h.BigBlind),
+		models.DateDay(h.Time.Format(models.SessionDateLayout)),
+	}, "|")
+}
+
+// newSession 的日期與前端建立的 session 使用相同格式
+func newSession(h ParsedHand, key string, scale float64) models.Session {
+	return models.Session{
+		ID:             uuid.New().String(),
+		Location:       "PokerStars",
+		Date:           h.Time.Format(models.SessionDateLayout),
+		SmallBlind:     toUnits(h.SmallBlind, scale),
+		BigBlind:       toUnits(h.BigBlind, scale),
+		Currency:       unitCurrency(h.Currency, scale),
+		EffectiveStack: toUnits(h.StackOf(h.Hero), scale),
+		TableSize:      h.MaxSeats,
+		Tag:            "",
+		ExternalID:     key,
+	}
+}
+
+// unitCurrency 標示以「分」儲存的金額，例如 "USD (¢)"
+func unitCurrency(currency string, scale float64) string {
+	if scale != 1 && currency != "" {
+		return currency + " (¢)"
+	}
+	return currency
+}
+
+func newHand(h ParsedHand, scale float64) models.Hand {
+	holeCards := formatCards(h.HeroCards)
+	board := formatCards(h.Board)
+	position := h.PositionOf(h.Hero)
+
+	// Validate 會填入每個動作後的底池；手牌歷史由站點產生，不合法的細節不影響匯入
+	streets := buildStreets(h, scale)
+	actions.Validate(streets)
+
+	villains := []models.Villain{}
+	for _, seat := range h.Seats {
+		cards, ok := h.Shown[seat.Player]
+		if !ok || seat.Player == h.Hero {
+			continue
+		}
+		// 以網名連結到玩家檔案，沒有的話會自動建立
+		villains = append(villains, models.Villain{
+			ID:        seat.Player,
+			HoleCards: formatCards(cards),
+			Position:  seat.Position,
+			Name:      seat.Player,
+			Site:      "PokerStars",
+		})
+	}
+
+	return models.Hand{
+		ID:        uuid.New().String(),
+		HoleCards: &holeCards,
+		Board:     &board,
+		Position:  &position,
+		Details:   renderDetails(h, streets, scale),
+		Result:    toUnits(h.Net(h.Hero), scale),
+		Date:      h.Time.UTC().Format(time.RFC3339),
+		Villains:  villains,
+		Streets:   streets,
+	}
+}
+
+// formatCards 將 "Ah Kd" 轉成 App 使用的 "A♥ K♦"
+func formatCards(list []string) string {
+	raw := strings.Join(list, " ")
+	if formatted, err := cards.Normalize(raw); err == nil {
+		return formatted
+	}
+	return raw
+}
+
+func formatAmount(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+// buildStreets 將解析出的動作轉成結構化的 models.Street，玩家以位置表示
+func buildStreets(h ParsedHand, scale float64) []models.Street {
+	streets := []models.Street{}
+	boardCards := map[string][]string{}
+	if len(h.Board) >= 3 {
+		boardCards["flop"] = h.Board[:3]
+	}
+	if len(h.Board) >= 4 {
+		boardCards["turn"] = h.Board[3:4]
+	}
+	if len(h.Board) >= 5 {
+		boardCards["river"] = h.Board[4:5]
+	}
+
+	for _, name := range []string{"preflop", "flop", "turn", "river"} {
+		street := models.Street{Name: name, Cards: formatCards(boardCards[name]), Actions: []models.Action{}}
+		for _, a := range h.Actions {
+			if a.Street != name {
+				continue
+			}
+			actor := h.PositionOf(a.Player)
+			if actor == "" {
+				actor = a.Player
+			}
+			street.Actions = append(street.Actions, models.Action{
+				Actor:  actor,
+				Type:   a.Type,
+				Amount: toUnits(a.Amount, scale),
+				AllIn:  a.AllIn,
+			})
+		}
+		if len(street.Actions) == 0 && street.Cards == "" {
+			continue
+		}
+		streets = append(streets, street)
+	}
+	return streets
+}
+
+// renderDetails 產生人類可讀的手牌描述，動作部分與手動輸入的手牌使用相同格式
+func renderDetails(h ParsedHand, streets []models.Street, scale float64) string {
+	units := func(v float64) int { return toUnits(v, scale) }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "PokerStars Hand #%s: %d/%d %s, %d-max\n",
+		h.HandNumber, units(h.SmallBlind), units(h.BigBlind), unitCurrency(h.Currency, scale), h.MaxSeats)
+	fmt.Fprintf(&b, "Hero (%s) [%s], stack %d\n", h.PositionOf(h.Hero), formatCards(h.HeroCards), units(h.StackOf(h.Hero)))
+	if rendered := actions.Render(streets, h.PositionOf(h.Hero)); rendered != "" {
+		b.WriteString(rendered + "\n")
+	}
+
+	for _, seat := range h.Seats {
+		if cards, ok := h.Shown[seat.Player]; ok && seat.Player != h.Hero {
+			fmt.Fprintf(&b, "Showdown: %s shows [%s]\n", seat.Position, formatCards(cards))
+		}
+	}
+	fmt.Fprintf(&b, "Pot %d (rake %d), Hero net %+d", units(h.TotalPot), units(h.Rake), units(h.Net(h.Hero)))
+	return b.String()
+}
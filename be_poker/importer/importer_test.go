@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"poker_tracker_backend/db"
+	"strings"
+	"testing"
+)
+
+func TestImportPokerStarsReusesImportedSession(t *testing.T) {
+	if err := db.Open(db.Config{Driver: db.DriverSQLite, SQLitePath: filepath.Join(t.TempDir(), "test.db")}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.DB.Close() })
+	repo := db.Repo.ForUser("importer-test")
+
+	data, err := os.ReadFile("testdata/pokerstars.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := string(data)
+	// 同一張桌子、同一天的下一手牌，分開匯入
+	second := strings.Replace(first, "#208839014473", "#208839014474", 1)
+
+	result, err := ImportPokerStars(repo, strings.NewReader(first))
+	if err != nil || result.SessionsCreated != 1 || result.HandsImported != 1 {
+		t.Fatalf("first import: got %+v, %v", result, err)
+	}
+	result, err = ImportPokerStars(repo, strings.NewReader(second+"\n\n"+first))
+	if err != nil || result.SessionsCreated != 0 || result.HandsImported != 1 || result.HandsSkipped != 1 {
+		t.Fatalf("second import: got %+v, %v", result, err)
+	}
+
+	sessions, err := repo.ListSessions()
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("got %d sessions, %v", len(sessions), err)
+	}
+	hands, err := repo.ListHands()
+	if err != nil || len(hands) != 2 {
+		t.Fatalf("got %d hands, %v", len(hands), err)
+	}
+	for _, h := range hands {
+		if h.SessionID != sessions[0].ID {
+			t.Errorf("hand %s is in session %s, want %s", h.ExternalID, h.SessionID, sessions[0].ID)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Action 是手牌歷史中的一個動作
+type Action struct {
+	Street string  // preflop, flop, turn, river
+	Player string  // 玩家名稱
+	Type   string  // ante, post, fold, check, call, bet, raise
+	Amount float64 // 這個動作投入底池的金額
+	To     float64 // raise 時的加注到金額
+	AllIn  bool
+}
+
+// Seat 是座位上的玩家
+type Seat struct {
+	Number     int
+	Player     string
+	Stack      float64
+	Position   string
+	SittingOut bool
+}
+
+// ParsedHand 是解析後的一手牌
+type ParsedHand struct {
+	HandNumber string
+	Game       string
+	SmallBlind float64
+	BigBlind   float64
+	Currency   string
+	Time       time.Time
+	TableName  string
+	MaxSeats   int
+	ButtonSeat int
+	Seats      []Seat
+	Hero       string
+	HeroCards  []string
+	Board      []string
+	Actions    []Action
+	Shown      map[string][]string // 攤牌時亮出的手牌
+	Collected  map[string]float64  // 每位玩家贏得的金額
+	Invested   map[string]float64  // 每位玩家投入的金額（已扣除退回的下注）
+	TotalPot   float64
+	Rake       float64
+}
+
+var (
+	psHeaderRe    = regexp.MustCompile(`^PokerStars (?:Zoom |Home Game )?Hand #(\d+):\s+(.+?) \(([^)]*)\) - (\d{4}/\d{2}/\d{2} \d{1,2}:\d{2}:\d{2})(?: (\w+))?(?: \[(\d{4}/\d{2}/\d{2} \d{1,2}:\d{2}:\d{2}) ET\])?`)
+	psStakesRe    = regexp.MustCompile(`^([^\d\s/]*)([\d.,]+)/([^\d\s/]*)([\d.,]+)(?: (\w{3}))?$`)
+	psTableRe     = regexp.MustCompile(`^Table '([^']+)' (\d+)-max.*? Seat #(\d+) is the button`)
+	psSeatRe      = regexp.MustCompile(`^Seat (\d+): (.+?) \((\S+) in chips(?:, [^)]*)?\)( is sitting out| out of hand.*)?$`)
+	psStreetRe    = regexp.MustCompile(`^\*\*\* (HOLE CARDS|FIRST FLOP|FLOP|TURN|RIVER|SHOW DOWN|SUMMARY) \*\*\*(.*)$`)
+	psDealtRe     = regexp.MustCompile(`^Dealt to (.+?) \[([^\]]+)\]`)
+	psActionRe    = regexp.MustCompile(`^(.+?): (folds|checks|calls|bets|raises|posts)(.*)$`)
+	psRaiseRe     = regexp.MustCompile(`^ (\S+) to (\S+)`)
+	psAmountRe    = regexp.MustCompile(`(\S+?)(?: and is all-in)?$`)
+	psUncalledRe  = regexp.MustCompile(`^Uncalled bet \((\S+)\) returned to (.+)$`)
+	psCollectedRe = regexp.MustCompile(`^(.+?) collected (\S+) from (?:side |main )?pot`)
+	psShowsRe     = regexp.MustCompile(`^(.+?): shows \[([^\]]+)\]`)
+	psTotalPotRe  = regexp.MustCompile(`^Total pot (\S+).*?\| Rake (\S+)`)
+	psBoardRe     = regexp.MustCompile(`^Board \[([^\]]+)\]`)
+	psSummaryShow = regexp.MustCompile(`^Seat (\d+): .*?(?:showed|mucked) \[([^\]]+)\]`)
+	psBracketsRe  = regexp.MustCompile(`\[([^\]]+)\]`)
+)
+
+// ParsePokerStars 解析 PokerStars 現金桌手牌歷史文字
+// 無法解析的手牌（例如錦標賽）會記錄在錯誤清單中並略過
+func ParsePokerStars(r io.Reader) ([]ParsedHand, []error) {
+	var hands []ParsedHand
+	var errs []error
+
+	var block []string
+	flush := func() {
+		if len(block) == 0 {
+			return
+		}
+		hand, err := parsePokerStarsHand(block)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			hands = append(hands, hand)
+		}
+		block = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\uFEFF"))
+		if strings.HasPrefix(line, "PokerStars ") && strings.Contains(line, "Hand #") {
+			flush()
+		}
+		if line == "" && len(block) == 0 {
+			continue
+		}
+		if len(block) > 0 || strings.HasPrefix(line, "PokerStars ") {
+			block = append(block, line)
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return hands, errs
+}
+
+func parsePokerStarsHand(lines []string) (ParsedHand, error) {
+	h := ParsedHand{
+		Shown:     map[string][]string{},
+		Collected: map[string]float64{},
+		Invested:  map[string]float64{},
+	}
+
+	if strings.Contains(lines[0], "Tournament #") {
+		return h, fmt.Errorf("%s: tournament hands are not supported", handLabel(lines[0]))
+	}
+	header := psHeaderRe.FindStringSubmatch(lines[0])
+	if header == nil {
+		return h, fmt.Errorf("%s: unrecognised hand header", handLabel(lines[0]))
+	}
+	h.HandNumber = header[1]
+	h.Game = header[2]
+
+	stakes := psStakesRe.FindStringSubmatch(header[3])
+	if stakes == nil {
+		return h, fmt.Errorf("hand #%s: unrecognised stakes %q", h.HandNumber, header[3])
+	}
+	h.SmallBlind = parseAmount(stakes[2])
+	h.BigBlind = parseAmount(stakes[4])
+	h.Currency = currencyCode(stakes[5], stakes[1])
+
+	h.Time = parseHandTime(header[4], header[5], header[6])
+
+	street := "preflop"
+	committed := map[string]float64{}
+	for _, line := range lines[1:] {
+		if m := psStreetRe.FindStringSubmatch(line); m != nil {
+			switch m[1] {
+			case "HOLE CARDS":
+				street = "preflop"
+			case "FLOP", "FIRST FLOP":
+				street = "flop"
+			case "TURN":
+				street = "turn"
+			case "RIVER":
+				street = "river"
+			case "SHOW DOWN":
+				street = "showdown"
+			case "SUMMARY":
+				street = "summary"
+			}
+			if street == "flop" || street == "turn" || street == "river" {
+				committed = map[string]float64{}
+				groups := psBracketsRe.FindAllStringSubmatch(m[2], -1)
+				if len(groups) > 0 {
+					h.Board = append(h.Board, strings.Fields(groups[len(groups)-1][1])...)
+				}
+			}
+			continue
+		}
+
+		if street == "summary" {
+			if m := psTotalPotRe.FindStringSubmatch(line); m != nil {
+				h.TotalPot = parseAmount(m[1])
+				h.Rake = parseAmount(m[2])
+			} else if m := psBoardRe.FindStringSubmatch(line); m != nil {
+				h.Board = strings.Fields(m[1])
+			} else if m := psSummaryShow.FindStringSubmatch(line); m != nil {
+				seatNumber, _ := strconv.Atoi(m[1])
+				if player := h.playerAtSeat(seatNumber); player != "" {
+					if _, ok := h.Shown[player]; !ok {
+						h.Shown[player] = strings.Fields(m[2])
+					}
+				}
+			}
+			continue
+		}
+
+		if m := psTableRe.FindStringSubmatch(line); m != nil {
+			h.TableName = m[1]
+			h.MaxSeats, _ = strconv.Atoi(m[2])
+			h.ButtonSeat, _ = strconv.Atoi(m[3])
+			continue
+		}
+		if m := psSeatRe.FindStringSubmatch(line); m != nil && len(h.Actions) == 0 && h.Hero == "" {
+			number, _ := strconv.Atoi(m[1])
+			h.Seats = append(h.Seats, Seat{
+				Number:     number,
+				Player:     m[2],
+				Stack:      parseAmount(m[3]),
+				SittingOut: m[4] != "",
+			})
+			continue
+		}
+		if m := psDealtRe.FindStringSubmatch(line); m != nil && h.Hero == "" {
+			h.Hero = m[1]
+			h.HeroCards = strings.Fields(m[2])
+			continue
+		}
+		if m := psUncalledRe.FindStringSubmatch(line); m != nil {
+			h.Invested[m[2]] -= parseAmount(m[1])
+			continue
+		}
+		if m := psCollectedRe.FindStringSubmatch(line); m != nil {
+			h.Collected[m[1]] += parseAmount(m[2])
+			continue
+		}
+		if m := psShowsRe.FindStringSubmatch(line); m != nil {
+			h.Shown[m[1]] = strings.Fields(m[2])
+			continue
+		}
+		if m := psActionRe.FindStringSubmatch(line); m != nil {
+			action := parseAction(street, m[1], m[2], m[3], committed)
+			if action.Type == "" {
+				continue
+			}
+			h.Invested[action.Player] += action.Amount
+			h.Actions = append(h.Actions, action)
+		}
+	}
+
+	if h.Hero == "" {
+		return h, fmt.Errorf("hand #%s: no hero hole cards found", h.HandNumber)
+	}
+	if h.MaxSeats == 0 {
+		h.MaxSeats = len(h.Seats)
+	}
+	h.assignPositions()
+	return h, nil
+}
+
+// parseAction 解析動作並維護每位玩家在這條街已投入的金額
+func parseAction(street, player, verb, rest string, committed map[string]float64) Action {
+	a := Action{Street: street, Player: player, AllIn: strings.HasSuffix(rest, "and is all-in")}
+	switch verb {
+	case "folds":
+		a.Type = "fold"
+	case "checks":
+		a.Type = "check"
+	case "calls", "bets":
+		a.Type = strings.TrimSuffix(verb, "s")
+		if m := psAmountRe.FindStringSubmatch(strings.TrimSpace(rest)); m != nil {
+			a.Amount = parseAmount(m[1])
+		}
+		committed[player] += a.Amount
+		a.To = committed[player]
+	case "raises":
+		a.Type = "raise"
+		if m := psRaiseRe.FindStringSubmatch(rest); m != nil {
+			a.To = parseAmount(m[2])
+			a.Amount = a.To - committed[player]
+			committed[player] = a.To
+		}
+	case "posts":
+		a.Type = "post"
+		fields := strings.Fields(strings.TrimSuffix(rest, " and is all-in"))
+		if len(fields) == 0 {
+			return Action{}
+		}
+		a.Amount = parseAmount(fields[len(fields)-1])
+		// ante 是死錢，不計入這條街的跟注金額
+		if strings.Contains(rest, "ante") {
+			a.Type = "ante"
+		} else {
+			committed[player] += a.Amount
+		}
+		a.To = committed[player]
+	}
+	return a
+}
+
+func (h *ParsedHand) playerAtSeat(number int) string {
+	for _, seat := range h.Seats {
+		if seat.Number == number {
+			return seat.Player
+		}
+	}
+	return ""
+}
+
+// PositionOf 回傳玩家的位置，找不到時回傳空字串
+func (h *ParsedHand) PositionOf(player string) string {
+	for _, seat := range h.Seats {
+		if seat.Player == player {
+			return seat.Position
+		}
+	}
+	return ""
+}
+
+// StackOf 回傳玩家的起始籌碼
+func (h *ParsedHand) StackOf(player string) float64 {
+	for _, seat := range h.Seats {
+		if seat.Player == player {
+			return seat.Stack
+		}
+	}
+	return 0
+}
+
+// Net 回傳玩家這手牌的淨輸贏
+func (h *ParsedHand) Net(player string) float64 {
+	return h.Collected[player] - h.Invested[player]
+}
+
+// assignPositions 從按鈕位開始順時針分配位置
+func (h *ParsedHand) assignPositions() {
+	active := []int{}
+	for i, seat := range h.Seats {
+		if !seat.SittingOut {
+			active = append(active, i)
+		}
+	}
+	sort.Slice(active, func(a, b int) bool { return h.Seats[active[a]].Number < h.Seats[active[b]].Number })
+	if len(active) == 0 {
+		return
+	}
+
+	// 按鈕位可能是空位（dead button），從按鈕之後的第一個座位開始
+	start := 0
+	for i, idx := range active {
+		if h.Seats[idx].Number >= h.ButtonSeat {
+			start = i
+			break
+		}
+	}
+	ordered := append(active[start:], active[:start]...)
+
+	names := positionNames(len(ordered))
+	for i, idx := range ordered {
+		h.Seats[idx].Position = names[i]
+	}
+}
+
+// positionNames 回傳從按鈕位開始的位置名稱
+func positionNames(players int) []string {
+	switch players {
+	case 1:
+		return []string{"BTN"}
+	case 2:
+		return []string{"BTN", "BB"}
+	}
+	names := []string{"BTN", "SB", "BB"}
+	switch others := players - 3; others {
+	case 0:
+	case 1:
+		names = append(names, "CO")
+	case 2:
+		names = append(names, "HJ", "CO")
+	case 3:
+		names = append(names, "UTG", "HJ", "CO")
+	default:
+		names = append(names, "UTG")
+		for i := 1; i <= others-4; i++ {
+			names = append(names, fmt.Sprintf("UTG+%d", i))
+		}
+		names = append(names, "MP", "HJ", "CO")
+	}
+	return names
+}
+
+func parseAmount(s string) float64 {
+	s = strings.TrimFunc(s, func(r rune) bool {
+		return !(r >= '0' && r <= '9') && r != '.'
+	})
+	s = strings.ReplaceAll(s, ",", "")
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+func currencyCode(code, symbol string) string {
+	if code != "" {
+		return code
+	}
+	switch symbol {
+	case "$":
+		return "USD"
+	case "€":
+		return "EUR"
+	case "£":
+		return "GBP"
+	}
+	return ""
+}
+
+// parseHandTime 優先使用括號內的 ET 時間，因為它的時區一定可以辨識
+func parseHandTime(local, zone, eastern string) time.Time {
+	const layout = "2006/01/02 15:04:05"
+	if eastern != "" {
+		if t, err := time.ParseInLocation(layout, eastern, easternTime()); err == nil {
+			return t
+		}
+	}
+	if zone == "ET" {
+		if t, err := time.ParseInLocation(layout, local, easternTime()); err == nil {
+			return t
+		}
+	}
+	t, _ := time.ParseInLocation(layout, local, time.UTC)
+	return t
+}
+
+func easternTime() *time.Location {
+	if loc, err := time.LoadLocation("America/New_York"); err == nil {
+		return loc
+	}
+	return time.FixedZone("ET", -5*60*60)
+}
+
+func handLabel(header string) string {
+	if i := strings.Index(header, "Hand #"); i >= 0 {
+		number := header[i+len("Hand #"):]
+		if j := strings.IndexAny(number, ": "); j >= 0 {
+			number = number[:j]
+		}
+		return "hand #" + number
+	}
+	return "hand"
+}
@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePokerStars(t *testing.T) {
+	f, err := os.Open("testdata/pokerstars.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	hands, errs := ParsePokerStars(f)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(hands) != 1 {
+		t.Fatalf("got %d hands, want 1", len(hands))
+	}
+	h := hands[0]
+
+	if h.HandNumber != "208839014473" || h.TableName != "Acamar IV" || h.MaxSeats != 6 || h.ButtonSeat != 3 {
+		t.Errorf("header: got #%s %q %d-max button %d", h.HandNumber, h.TableName, h.MaxSeats, h.ButtonSeat)
+	}
+	if h.SmallBlind != 0.05 || h.BigBlind != 0.10 || h.Currency != "USD" {
+		t.Errorf("stakes: got %v/%v %s", h.SmallBlind, h.BigBlind, h.Currency)
+	}
+	if got := h.Time.UTC().Format("2006-01-02 15:04:05"); got != "2020-01-14 17:43:35" {
+		t.Errorf("time: got %s", got)
+	}
+	if h.Hero != "Hero" || !reflect.DeepEqual(h.HeroCards, []string{"Ah", "Kd"}) {
+		t.Errorf("hero: got %s %v", h.Hero, h.HeroCards)
+	}
+	if !reflect.DeepEqual(h.Board, []string{"Kc", "7d", "2s", "9h", "3c"}) {
+		t.Errorf("board: got %v", h.Board)
+	}
+	if !reflect.DeepEqual(h.Shown["bbguy"], []string{"Qs", "Qd"}) {
+		t.Errorf("shown: got %v", h.Shown)
+	}
+
+	positions := map[string]string{}
+	for _, s := range h.Seats {
+		positions[s.Player] = s.Position
+	}
+	if positions["btnguy"] != "BTN" || positions["sbguy"] != "SB" || positions["bbguy"] != "BB" {
+		t.Errorf("positions: got %v", positions)
+	}
+
+	// 沒被跟注的 $0.35 退回 Hero
+	money := []struct {
+		name      string
+		got, want float64
+	}{
+		{"hero invested", h.Invested["Hero"], 10.00},
+		{"villain invested", h.Invested["bbguy"], 10.00},
+		{"hero collected", h.Collected["Hero"], 19.15},
+		{"total pot", h.TotalPot, 20.05},
+		{"rake", h.Rake, 0.90},
+	}
+	for _, m := range money {
+		if math.Abs(m.got-m.want) > 1e-9 {
+			t.Errorf("%s: got %v, want %v", m.name, m.got, m.want)
+		}
+	}
+
+	last := h.Actions[len(h.Actions)-1]
+	if last.Street != "turn" || last.Player != "bbguy" || last.Type != "call" || !last.AllIn {
+		t.Errorf("last action: got %+v", last)
+	}
+}
+
+func TestParsePokerStarsSkipsTournaments(t *testing.T) {
+	text := `PokerStars Hand #208839099999: Tournament #2800000000, $1.00+$0.10 USD Hold'em No Limit - Level I (10/20) - 2020/01/14 18:43:35 CET [2020/01/14 12:43:35 ET]
+Table '2800000000 1' 9-max Seat #1 is the button
+Seat 1: Hero (1500 in chips)
+`
+	hands, errs := ParsePokerStars(strings.NewReader(text))
+	if len(hands) != 0 || len(errs) != 1 {
+		t.Errorf("got %d hands and %d errors, want 0 and 1", len(hands), len(errs))
+	}
+}
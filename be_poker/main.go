@@ -79,8 +79,13 @@ func printStartupInfo() {
 	
 	fmt.Println("🚀 Server Configuration:")
 	fmt.Printf("   📍 Port: %s\n", port)
-	fmt.Println("   🗄️  Database: SQLite (Local)")
-	fmt.Println("   📁 Database File: poker_tracker.db")
+	cfg := db.ConfigFromEnv()
+	if cfg.Driver == db.DriverSQLite {
+		fmt.Println("   🗄️  Database: SQLite (Local)")
+		fmt.Printf("   📁 Database File: %s\n", cfg.SQLitePath)
+	} else {
+		fmt.Println("   🗄️  Database: PostgreSQL")
+	}
 	
 	// 只在本地開發時顯示本地地址
 	if port == "8080" {
@@ -90,6 +95,8 @@ func printStartupInfo() {
 	fmt.Println()
 	
 	fmt.Println("📱 API Endpoints:")
+	fmt.Println("   POST /auth/register - Create account")
+	fmt.Println("   POST /auth/login    - Log in (Bearer token)")
 	fmt.Println("   GET  /hands     - List hands")
 	fmt.Println("   POST /hands     - Create hand")
 	fmt.Println("   POST /analyze   - AI analysis")
@@ -112,6 +119,12 @@ func printStartupInfo() {
 }
 
 func main() {
+	// 子指令（例如 migrate）執行完就結束，不啟動服務器
+	if len(os.Args) > 1 {
+		exitOnError(runCommand(os.Args[1:]))
+		return
+	}
+
 	// 環境檢查
 	checkEnvironment()
 	
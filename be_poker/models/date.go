@@ -0,0 +1,15 @@
+package models
+
+import "strings"
+
+// SessionDateLayout 是前端寫入 session 日期的格式；手牌的日期則為 RFC3339
+const SessionDateLayout = "2006/01/02 15:04"
+
+// DateDay 回傳日期字串的日期部分（YYYY-MM-DD），
+// 讓 2024/05/01 20:00 這類 session 日期可以與 2024-05-01T20:00:00Z 這類手牌日期比較
+func DateDay(date string) string {
+	if len(date) > 10 {
+		date = date[:10]
+	}
+	return strings.ReplaceAll(date, "/", "-")
+}
@@ -9,13 +9,140 @@ type Session struct {
 	Currency      string `json:"currency"`
 	EffectiveStack int   `json:"effectiveStack"`
 	TableSize     int    `json:"tableSize"`
-	Tag           string `json:"tag"`
+	Tag           string `json:"tag"`                     // 舊版欄位，等於第一個標籤的名稱
+	Tags          []Tag  `json:"tags,omitempty"`          // 多個標籤，更新時為 null 表示不變
+	ExternalID    string `json:"externalId,omitempty"`    // 匯入的 session 的來源，例如 pokerstars:<桌名>|USD|1|2|2024-05-01
+	StartTime     string `json:"startTime,omitempty"` // RFC3339
+	EndTime       string `json:"endTime,omitempty"`   // RFC3339
+	Transactions  []Transaction `json:"transactions,omitempty"` // 買入、補碼、兌現
+	CashResult    *int    `json:"cashResult,omitempty"`    // 由買入與兌現算出的實際輸贏，尚未結算時為 null
+	DurationMinutes int   `json:"durationMinutes,omitempty"`
+	State         string `json:"state,omitempty"`         // live, paused, ended；事後補記的 session 為空
+	PausedAt      string `json:"pausedAt,omitempty"`      // 暫停開始的時間
+	PausedSeconds int    `json:"pausedSeconds,omitempty"` // 累計暫停秒數，不計入時長
+	Type          string `json:"type,omitempty"`          // cash（預設）、tournament、sng
+	Tournament    *Tournament `json:"tournament,omitempty"` // 錦標賽資料，現金局為 null
+}
+
+// Tournament 是錦標賽或 SNG 的報名與名次資料，金額以 session 幣別記錄
+type Tournament struct {
+	Name      string `json:"name,omitempty"`
+	BuyIn     int    `json:"buyIn"`     // 不含手續費
+	Fee       int    `json:"fee"`
+	ReEntries int    `json:"reEntries"` // 重新報名次數，每次都再付一次買入與手續費
+	FieldSize int    `json:"fieldSize,omitempty"`
+	Place     int    `json:"place,omitempty"` // 最終名次，0 表示尚未結束
+	Prize     int    `json:"prize"`
+	Bounties  int    `json:"bounties"`
+}
+
+// Transaction 是 session 中的一筆現金異動
+type Transaction struct {
+	ID        string `json:"id"`
+	SessionID string `json:"sessionId"`
+	Type      string `json:"type"`           // buyin, rebuy, cashout
+	Amount    int    `json:"amount"`
+	Time      string `json:"time,omitempty"` // RFC3339
+}
+
+// Tag 是手牌與 session 共用的標籤；寫入時可以只給名稱，不存在的標籤會自動建立
+type Tag struct {
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name"`
+	Color    string `json:"color,omitempty"`    // 例如 #ff8800
+	Category string `json:"category,omitempty"` // 例如 spot、mistake、opponent
+	Hands    int    `json:"hands,omitempty"`    // 使用次數，只在列出標籤時填寫
+	Sessions int    `json:"sessions,omitempty"`
 }
 
 type Villain struct {
 	ID        string `json:"id"`
 	HoleCards string `json:"holeCards"`
 	Position  string `json:"position"`
+	Range     string `json:"range,omitempty"` // 不知道手牌時可用範圍，例如 "TT+, AQs+"
+	PlayerID  string `json:"playerId,omitempty"` // 對應的玩家檔案
+	Name      string `json:"name,omitempty"`     // 玩家名稱；沒有 playerId 時以名稱找到或建立玩家
+	Site      string `json:"site,omitempty"`     // 以名稱建立玩家時使用的站點或場館
+}
+
+// User 是登入帳號，每位使用者只看得到自己的 session、手牌、對手與標籤
+type User struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"createdAt,omitempty"`
+	Admin     bool   `json:"admin"` // 以 users admin 指令指定，可以修改所有人共用的匯率
+}
+
+// AuthResponse 是註冊與登入的回應，之後的請求以 Authorization: Bearer <token> 帶上
+type AuthResponse struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expiresAt"`
+	User      User   `json:"user"`
+}
+
+// Player 是對手的長期檔案，手牌中的 villain 以 playerId 連結
+type Player struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`              // 網名或外觀描述，例如「戴帽子的老先生」
+	Site     string `json:"site,omitempty"`    // 站點或場館
+	Notes    string `json:"notes,omitempty"`
+	Color    string `json:"color,omitempty"`   // 顏色標記，例如 #ff0000 代表魚
+	Hands    int    `json:"hands,omitempty"`   // 一起玩過的手數，只在列出玩家時填寫
+	LastSeen string `json:"lastSeen,omitempty"`
+}
+
+// PlayerSummary 是對上某位玩家的彙總，金額為 hero 在這些手牌的輸贏（多人底池計入整手結果）
+type PlayerSummary struct {
+	Player       Player   `json:"player"`
+	Currency     string   `json:"currency"`
+	Hands        int      `json:"hands"`
+	Sessions     int      `json:"sessions"`
+	Won          int      `json:"won"`  // hero 贏的手數
+	Lost         int      `json:"lost"` // hero 輸的手數
+	NetResult    float64  `json:"netResult"`
+	BBHands      int      `json:"bbHands"`
+	TotalBB      float64  `json:"totalBb"`
+	BBPer100     float64  `json:"bbPer100"`
+	KnownHands   int      `json:"knownHands"` // 看到對手手牌的手數
+	FirstSeen    string   `json:"firstSeen,omitempty"`
+	LastSeen     string   `json:"lastSeen,omitempty"`
+	MissingRates []string `json:"missingRates,omitempty"`
+}
+
+// Aggression 是翻牌後的攻擊性：下注與加注次數比上跟注次數
+type Aggression struct {
+	Bets   int      `json:"bets"`   // 下注與加注次數
+	Calls  int      `json:"calls"`  // 跟注次數
+	Factor *float64 `json:"factor"` // 沒有跟注時為 null
+}
+
+// PlayerHUD 是某位玩家在記錄的手牌中的 HUD 統計，只計算有結構化動作且知道玩家位置的手牌
+type PlayerHUD struct {
+	Player     Player     `json:"player"`
+	Hands      int        `json:"hands"`
+	VPIP       StatValue  `json:"vpip"`
+	PFR        StatValue  `json:"pfr"`
+	Aggression Aggression `json:"aggression"`
+	WTSD       StatValue  `json:"wtsd"`       // 看到翻牌後打到攤牌
+	WSD        StatValue  `json:"wsd"`        // 攤牌時贏得底池；無法判斷贏家的攤牌不計入
+	FoldToCBet StatValue  `json:"foldToCbet"` // 面對翻牌前加注者的翻牌圈持續下注時棄牌
+	Skipped    int        `json:"skipped"`    // 沒有位置或動作而無法計算的手牌
+}
+
+// Action 是某條街上的一個動作
+type Action struct {
+	Actor  string `json:"actor"`           // 座位或位置，例如 BTN、Seat 3
+	Type   string `json:"type"`            // ante, post, fold, check, call, bet, raise
+	Amount int    `json:"amount"`          // 這個動作新投入的金額（raise 為補上的差額，不是加注到的總額）
+	Pot    int    `json:"pot"`             // 動作完成後的底池
+	AllIn  bool   `json:"allIn,omitempty"` // 是否全下
+}
+
+// Street 是一條街以及依序發生的動作
+type Street struct {
+	Name    string   `json:"name"`            // preflop, flop, turn, river
+	Cards   string   `json:"cards,omitempty"` // 這條街發出的公共牌
+	Actions []Action `json:"actions"`
 }
 
 type Hand struct {
@@ -28,18 +155,324 @@ type Hand struct {
 	Note         *string   `json:"note"`         // 筆記
 	Result       int       `json:"result"`
 	Date         string    `json:"date"`
-	Tag          string    `json:"tag"`          // 標籤
+	Tag          string    `json:"tag"`          // 舊版欄位，等於第一個標籤的名稱
+	Tags         []Tag     `json:"tags,omitempty"`         // 多個標籤，更新時為 null 表示不變
 	Villains     []Villain `json:"villains"`     // Villains 陣列
 	Analysis     string    `json:"analysis,omitempty"`     // OpenAI 分析結果
 	AnalysisDate string    `json:"analysisDate,omitempty"` // 分析時間
 	Favorite     bool      `json:"favorite"`     // 是否為最愛
+	ExternalID   string    `json:"externalId,omitempty"`   // 匯入來源的手牌編號，例如 pokerstars:123
+	Streets      []Street  `json:"streets,omitempty"`      // 結構化的行動紀錄
+	Level        int       `json:"level,omitempty"`        // 錦標賽的盲注級別
+	Ante         int       `json:"ante,omitempty"`         // 錦標賽的前注
+}
+
+// HandShare 是手牌的公開分享連結，過期或撤銷後就無法開啟
+type HandShare struct {
+	ID            string `json:"id"`
+	HandID        string `json:"handId"`
+	Token         string `json:"token,omitempty"` // 只在建立時回傳，資料庫只保存雜湊
+	URL           string `json:"url,omitempty"`
+	HideHoleCards bool   `json:"hideHoleCards"`
+	HideResults   bool   `json:"hideResults"`
+	CreatedAt     string `json:"createdAt"`
+	ExpiresAt     string `json:"expiresAt"`
+	RevokedAt     string `json:"revokedAt,omitempty"`
+	Active        bool   `json:"active"`
+	OwnerID       string `json:"-"`
+}
+
+// SharedHand 是分享連結公開的唯讀手牌，不含筆記、標籤與對手檔案
+type SharedHand struct {
+	Position      *string   `json:"position"`
+	HoleCards     *string   `json:"holeCards,omitempty"`
+	Board         *string   `json:"board"`
+	Details       string    `json:"details,omitempty"`
+	Villains      []Villain `json:"villains"`
+	Streets       []Street  `json:"streets,omitempty"`
+	Analysis      string    `json:"analysis,omitempty"`
+	Result        *int      `json:"result,omitempty"`
+	Date          string    `json:"date"`
+	SmallBlind    int       `json:"smallBlind,omitempty"`
+	BigBlind      int       `json:"bigBlind,omitempty"`
+	Currency      string    `json:"currency,omitempty"`
+	Level         int       `json:"level,omitempty"`
+	Ante          int       `json:"ante,omitempty"`
+	HideHoleCards bool      `json:"hideHoleCards"`
+	HideResults   bool      `json:"hideResults"`
+	ExpiresAt     string    `json:"expiresAt"`
 }
 
 type Stats struct {
-	TotalProfit    int               `json:"totalProfit"`
+	Currency       string            `json:"currency"`    // 金額換算後的報表幣別
+	TotalProfit    float64           `json:"totalProfit"`
 	TotalSessions  int               `json:"totalSessions"`
 	WinRate        int               `json:"winRate"`
 	AvgSession     float64           `json:"avgSession"`
-	ByStakes       map[string]int    `json:"byStakes"`
-	ByLocation     map[string]int    `json:"byLocation"`
-} 
\ No newline at end of file
+	ByStakes       map[string]float64 `json:"byStakes"`
+	ByLocation     map[string]float64 `json:"byLocation"`
+	ByCurrency     map[string]CurrencyBreakdown `json:"byCurrency"`
+	MissingRates   []string          `json:"missingRates,omitempty"` // 缺少匯率而未計入總計的幣別
+
+	// 全下期望值（河牌前全下且對手手牌已知的手牌以期望值計算）
+	AllInEVProfit     float64            `json:"allInEvProfit"`
+	AllInHands        int                `json:"allInHands"`
+	Luck              float64            `json:"luck"` // 實際盈虧減去全下期望值
+	ByStakesAllInEV   map[string]float64 `json:"byStakesAllInEv"`
+	ByLocationAllInEV map[string]float64 `json:"byLocationAllInEv"`
+
+	// 以大盲為單位的統計，每手牌以所屬 session 的大盲換算，不同級別與幣別才能相加
+	TotalHands   int                `json:"totalHands"`
+	BBHands      int                `json:"bbHands"` // 有 session 大盲可換算的手數
+	TotalBB      float64            `json:"totalBb"`
+	BBPer100     float64            `json:"bbPer100"`
+	AvgSessionBB float64            `json:"avgSessionBb"`
+	AllInEVBB    float64            `json:"allInEvBb"`
+	ByStakesBB   map[string]float64 `json:"byStakesBb"`
+	ByLocationBB map[string]float64 `json:"byLocationBb"`
+
+	// 以買入與兌現計算的實際結果與時薪
+	CashProfit   float64      `json:"cashProfit"`
+	CashSessions int          `json:"cashSessions"` // 有結算結果的 session 數
+	HoursPlayed  float64      `json:"hoursPlayed"`
+	HourlyRate   float64      `json:"hourlyRate"`
+	BBPerHour    float64      `json:"bbPerHour"`
+	ResultGaps   []SessionGap `json:"resultGaps"` // 記錄的手牌輸贏與實際結果不符的 session
+
+	// 依標籤的統計：手牌標籤以手牌輸贏計算，session 標籤以 session 輸贏計算
+	ByTag        map[string]TagStats `json:"byTag"`
+	BySessionTag map[string]float64  `json:"bySessionTag"`
+
+	// 錦標賽另外統計，上面的欄位只包含現金局
+	Tournaments TournamentStats `json:"tournaments"`
+}
+
+// TournamentStats 是錦標賽與 SNG 的統計，金額已換算成報表幣別
+type TournamentStats struct {
+	Count     int     `json:"count"`
+	Finished  int     `json:"finished"` // 已有結果的場數，以下金額與比例只計算這些
+	Entries   int     `json:"entries"` // 含重新報名
+	Cost      float64 `json:"cost"`    // 買入加手續費
+	Winnings  float64 `json:"winnings"` // 獎金加賞金
+	Bounties  float64 `json:"bounties"`
+	Profit    float64 `json:"profit"`
+	ROI       float64 `json:"roi"`       // 百分比
+	ITM       int     `json:"itm"`       // 拿到獎金的場數
+	ITMRate   float64 `json:"itmRate"`   // 百分比
+	AvgFinish float64 `json:"avgFinish"`
+	ByType    map[string]float64 `json:"byType"` // 依 tournament / sng 的盈虧
+}
+
+// TagStats 是某個標籤的手牌統計
+type TagStats struct {
+	Hands    int     `json:"hands"`
+	Profit   float64 `json:"profit"` // 報表幣別
+	TotalBB  float64 `json:"totalBb"`
+	BBPer100 float64 `json:"bbPer100"`
+}
+
+// SessionGap 是手牌輸贏加總與實際現金結果的差距
+type SessionGap struct {
+	SessionID   string `json:"sessionId"`
+	Date        string `json:"date"`
+	Location    string `json:"location"`
+	HandsResult int    `json:"handsResult"`
+	CashResult  int    `json:"cashResult"`
+	Gap         int    `json:"gap"` // CashResult - HandsResult
+} 
+
+// PlayerHand 是一位玩家在攤牌時的牌型
+type PlayerHand struct {
+	Player    string `json:"player"`             // hero 或對手 ID
+	Position  string `json:"position"`
+	HoleCards string `json:"holeCards"`
+	MadeHand  string `json:"madeHand,omitempty"` // 例如 "Full House, Kings full of Sevens"
+	Category  string `json:"category,omitempty"`
+	Value     uint32 `json:"value,omitempty"`    // 可比較大小的牌力
+	Winner    bool   `json:"winner"`
+}
+
+// Showdown 是一手牌的攤牌結果
+type Showdown struct {
+	HandID         string       `json:"handId"`
+	Board          string       `json:"board"`
+	Players        []PlayerHand `json:"players"`
+	Winners        []string     `json:"winners"`
+	Split          bool         `json:"split"`
+	Complete       bool         `json:"complete"`          // 公共牌發完且至少兩位玩家有手牌
+	ResultMismatch bool         `json:"resultMismatch"`    // 記錄的輸贏與攤牌結果矛盾
+	Warning        string       `json:"warning,omitempty"`
+}
+
+// EquityRequest 是 POST /equity 的請求
+// 提供 handId 時使用已儲存手牌的 Hero 手牌、對手與全下當時的公共牌
+type EquityRequest struct {
+	HandID     string    `json:"handId,omitempty"`
+	HoleCards  string    `json:"holeCards,omitempty"`
+	HeroRange  string    `json:"heroRange,omitempty"`
+	Board      string    `json:"board,omitempty"`
+	Villains   []Villain `json:"villains,omitempty"`
+	Iterations int       `json:"iterations,omitempty"` // Monte Carlo 模擬次數
+	Seed       int64     `json:"seed,omitempty"`
+}
+
+// PlayerEquity 是一位玩家的勝率
+type PlayerEquity struct {
+	Player   string  `json:"player"`
+	Position string  `json:"position,omitempty"`
+	Hand     string  `json:"hand"` // 手牌或範圍
+	Win      float64 `json:"win"`
+	Tie      float64 `json:"tie"`
+	Lose     float64 `json:"lose"`
+	Equity   float64 `json:"equity"`
+}
+
+// EquityResult 是 POST /equity 的回應
+type EquityResult struct {
+	HandID      string         `json:"handId,omitempty"`
+	Board       string         `json:"board"`
+	AllInStreet string         `json:"allInStreet,omitempty"`
+	Players     []PlayerEquity `json:"players"`
+	Method      string         `json:"method"` // exact 或 monte_carlo
+	Samples     int            `json:"samples"`
+	Seed        int64          `json:"seed,omitempty"`
+}
+
+// StatValue 是一個比例型的統計，附上樣本數
+type StatValue struct {
+	Count         int     `json:"count"`         // 發生次數
+	Opportunities int     `json:"opportunities"` // 有機會發生的手數
+	Percent       float64 `json:"percent"`
+}
+
+// StatLine 是一組翻牌前與 c-bet 統計
+type StatLine struct {
+	Hands          int       `json:"hands"`
+	VPIP           StatValue `json:"vpip"`
+	PFR            StatValue `json:"pfr"`
+	ThreeBet       StatValue `json:"threeBet"`
+	FoldToThreeBet StatValue `json:"foldToThreeBet"`
+	Steal          StatValue `json:"steal"`
+	CBet           StatValue `json:"cbet"`
+}
+
+// PlayerStats 是 GET /stats/player 的回應，只計算有結構化動作的手牌
+type PlayerStats struct {
+	Overall    StatLine            `json:"overall"`
+	ByPosition map[string]StatLine `json:"byPosition"`
+	ByStakes   map[string]StatLine `json:"byStakes"`
+	Skipped    int                 `json:"skipped"` // 沒有位置或動作而無法計算的手牌
+}
+
+// ExchangeRate 是某一天的匯率：1 美元可以換多少單位的該幣別
+type ExchangeRate struct {
+	Currency string  `json:"currency"` // ISO 代碼，例如 TWD
+	Date     string  `json:"date"`     // YYYY-MM-DD
+	Rate     float64 `json:"rate"`
+}
+
+// Settings 是使用者設定
+type Settings struct {
+	ReportingCurrency string `json:"reportingCurrency"` // 統計使用的幣別
+}
+
+// CurrencyBreakdown 是單一幣別的盈虧
+type CurrencyBreakdown struct {
+	Sessions  int     `json:"sessions"`
+	Hands     int     `json:"hands"`
+	Profit    float64 `json:"profit"`    // 原幣別金額
+	Converted float64 `json:"converted"` // 換算成報表幣別的金額
+}
+
+// LiveStatus 是進行中 session 的即時狀態
+type LiveStatus struct {
+	SessionID      string `json:"sessionId"`
+	State          string `json:"state"`
+	StartTime      string `json:"startTime"`
+	ElapsedSeconds int    `json:"elapsedSeconds"` // 不含暫停時間
+	PausedSeconds  int    `json:"pausedSeconds"`
+	Hands          int    `json:"hands"`
+	Invested       int    `json:"invested"`      // 買入加補碼
+	CurrentStack   int    `json:"currentStack"`  // 買入加補碼加上手牌輸贏，扣掉已兌現的金額
+	RunningProfit  int    `json:"runningProfit"` // 結束並兌現後為實際結果
+}
+
+// ICMStack 是一位玩家的籌碼；分析手牌時 Player 要對應動作中的 actor（例如 BTN）
+type ICMStack struct {
+	Player string `json:"player"`
+	Stack  int    `json:"stack"`
+}
+
+// ICMRequest 是 POST /icm 的請求
+// 提供 handId 時，stacks 是這手牌開始前的籌碼，並比較 Hero 面對全下時跟注與棄牌的 $EV
+type ICMRequest struct {
+	Stacks       []ICMStack `json:"stacks"`
+	Payouts      []float64  `json:"payouts"` // 依名次排列，第一名在前
+	HandID       string     `json:"handId,omitempty"`
+	VillainRange string     `json:"villainRange,omitempty"` // 不知道對手手牌時使用的範圍
+	Iterations   int        `json:"iterations,omitempty"`
+	Seed         int64      `json:"seed,omitempty"`
+}
+
+// ICMPlayer 是一位玩家的 $EV
+type ICMPlayer struct {
+	Player    string  `json:"player"`
+	Stack     int     `json:"stack"`
+	ChipShare float64 `json:"chipShare"` // 籌碼佔比
+	Equity    float64 `json:"equity"`    // 獎金期望值
+}
+
+// ICMDecision 比較 Hero 面對全下時跟注與棄牌的 $EV
+type ICMDecision struct {
+	Hero           string  `json:"hero"`
+	Villain        string  `json:"villain"`
+	Risk           int     `json:"risk"` // 跟注後 Hero 需要投入的總籌碼
+	Win            float64 `json:"win"`  // 攤牌獨贏的機率
+	Tie            float64 `json:"tie"`
+	FoldEV         float64 `json:"foldEv"`
+	CallEV         float64 `json:"callEv"`
+	WinEV          float64 `json:"winEv"`
+	LoseEV         float64 `json:"loseEv"`
+	ChipEquity     float64 `json:"chipEquity"`     // 以籌碼計算時跟注需要的勝率
+	RequiredEquity float64 `json:"requiredEquity"` // 以 ICM 計算時跟注需要的勝率
+	Best           string  `json:"best"`           // call 或 fold
+	Actual         string  `json:"actual,omitempty"` // 手牌中實際的動作
+}
+
+// ICMResult 是 POST /icm 的回應
+type ICMResult struct {
+	Players  []ICMPlayer  `json:"players"`
+	Decision *ICMDecision `json:"decision,omitempty"`
+}
+
+// HandPage 是分頁查詢手牌的結果
+type HandPage struct {
+	Items      []Hand `json:"items"`
+	Total      int    `json:"total"`                // 符合條件的總筆數（不受分頁影響）
+	NextCursor string `json:"nextCursor,omitempty"` // 沒有下一頁時為空
+}
+
+// SessionPage 是分頁查詢 session 的結果
+type SessionPage struct {
+	Items      []Session `json:"items"`
+	Total      int       `json:"total"`
+	NextCursor string    `json:"nextCursor,omitempty"`
+}
+
+// SearchHit 是一筆搜尋結果，Hand 與 Session 只會有一個
+// Highlights 是有符合的欄位片段，符合處以 <mark></mark> 標記，其餘文字已做 HTML 跳脫
+type SearchHit struct {
+	Type       string            `json:"type"` // hand 或 session
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+	Hand       *Hand             `json:"hand,omitempty"`
+	Session    *Session          `json:"session,omitempty"`
+}
+
+// SearchResult 是 GET /search 的回應
+type SearchResult struct {
+	Query string      `json:"query"`
+	Terms []string    `json:"terms"` // 扣掉篩選條件後的搜尋詞
+	Total int         `json:"total"`
+	Hits  []SearchHit `json:"hits"`
+}
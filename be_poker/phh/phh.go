@@ -0,0 +1,339 @@
+package phh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"poker_tracker_backend/actions"
+	"poker_tracker_backend/cards"
+	"poker_tracker_backend/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotEnoughPlayers 表示手牌中認得出來的玩家少於兩位，無法寫成 PHH
+var ErrNotEnoughPlayers = errors.New("hand needs at least two players with a position")
+
+// DefaultStackBB 是 session 沒有記錄有效籌碼時假設的起始籌碼（大盲數）
+const DefaultStackBB = 100
+
+// 位置由小盲開始排序，PHH 的 p1 是按鈕左邊第一位
+var positionOrder = []string{"SB", "BB", "UTG", "UTG+1", "UTG+2", "UTG+3", "UTG+4", "UTG+5", "MP", "LJ", "HJ", "CO", "BTN"}
+
+// seat 是 PHH 中的一位玩家
+type seat struct {
+	actor       string // 動作中的 actor，也就是位置或 Seat N
+	name        string
+	cards       []cards.Card // 不知道時為 nil
+	blind       int
+	ante        int
+	contributed int
+	allIn       bool
+	folded      bool
+}
+
+// Encode 把手牌寫成 PHH（Poker Hand History）格式的 TOML
+// 級別、幣別與有效籌碼來自所屬的 session；hero 的位置沒有填時以 "Hero" 當作 actor
+func Encode(hand models.Hand, session models.Session) ([]byte, error) {
+	heroActor := "Hero"
+	if hand.Position != nil && strings.TrimSpace(*hand.Position) != "" {
+		heroActor = *hand.Position
+	}
+
+	seats := []*seat{}
+	byActor := map[string]*seat{}
+	addSeat := func(actor string) *seat {
+		if s, ok := byActor[actor]; ok {
+			return s
+		}
+		s := &seat{actor: actor, name: actor}
+		byActor[actor] = s
+		seats = append(seats, s)
+		return s
+	}
+
+	hero := addSeat(heroActor)
+	hero.name = "Hero"
+	if hand.HoleCards != nil {
+		hero.cards = parseCards(*hand.HoleCards)
+	}
+	for _, v := range hand.Villains {
+		if strings.TrimSpace(v.Position) == "" {
+			continue
+		}
+		s := addSeat(v.Position)
+		if v.Name != "" {
+			s.name = v.Name
+		}
+		s.cards = parseCards(v.HoleCards)
+	}
+	for _, street := range hand.Streets {
+		for _, a := range street.Actions {
+			addSeat(a.Actor)
+		}
+	}
+	if len(seats) < 2 {
+		return nil, ErrNotEnoughPlayers
+	}
+	sort.SliceStable(seats, func(i, j int) bool { return seatRank(seats[i].actor) < seatRank(seats[j].actor) })
+	index := map[string]int{}
+	for i, s := range seats {
+		index[s.actor] = i + 1
+	}
+
+	// 盲注與前注：有結構化動作時以實際投入為準，否則以 session 的級別推算
+	posted := false
+	for _, street := range hand.Streets {
+		for _, a := range street.Actions {
+			switch a.Type {
+			case "ante":
+				byActor[a.Actor].ante += a.Amount
+				posted = true
+			case "post":
+				byActor[a.Actor].blind += a.Amount
+				posted = true
+			}
+		}
+	}
+	if !posted {
+		if s, ok := byActor["SB"]; ok {
+			s.blind = session.SmallBlind
+		} else if s, ok := byActor["BTN"]; ok && len(seats) == 2 {
+			s.blind = session.SmallBlind
+		}
+		if s, ok := byActor["BB"]; ok {
+			s.blind = session.BigBlind
+		}
+		for _, s := range seats {
+			s.ante = hand.Ante
+		}
+	}
+
+	bigBlind := session.BigBlind
+	for _, s := range seats {
+		if s.blind > bigBlind {
+			bigBlind = s.blind
+		}
+	}
+
+	// 動作
+	lines := []string{}
+	for _, s := range seats {
+		lines = append(lines, fmt.Sprintf("d dh p%d %s", index[s.actor], formatCards(s.cards, 2)))
+	}
+
+	board := []cards.Card{}
+	if hand.Board != nil {
+		board = parseCards(*hand.Board)
+	}
+	streets := map[string]models.Street{}
+	for _, street := range hand.Streets {
+		streets[street.Name] = street
+	}
+
+	for _, s := range seats {
+		s.contributed = s.ante + s.blind
+	}
+	lines = append(lines, streetActions(streets["preflop"], seats, byActor, index, true)...)
+	reachedRiver := false
+	for _, name := range []string{"flop", "turn", "river"} {
+		street, played := streets[name]
+		dealt := parseCards(street.Cards)
+		from, to := actions.BoardSize(previousStreet(name)), actions.BoardSize(name)
+		if len(dealt) == 0 && len(board) >= to {
+			dealt = board[from:to]
+		}
+		if !played && len(dealt) == 0 {
+			break
+		}
+		lines = append(lines, "d db "+formatCards(dealt, to-from))
+		lines = append(lines, streetActions(street, seats, byActor, index, false)...)
+		reachedRiver = name == "river"
+	}
+
+	// 攤牌：還沒棄牌的玩家亮出已知的手牌
+	remaining := []*seat{}
+	for _, s := range seats {
+		if !s.folded {
+			remaining = append(remaining, s)
+		}
+	}
+	if len(remaining) >= 2 && (reachedRiver || allInShowdown(remaining)) {
+		for _, s := range remaining {
+			if s.cards != nil {
+				lines = append(lines, fmt.Sprintf("p%d sm %s", index[s.actor], formatCards(s.cards, 2)))
+			}
+		}
+	}
+
+	stack := session.EffectiveStack
+	if stack <= 0 {
+		stack = DefaultStackBB * bigBlind
+	}
+	antes, blinds, stacks, players := []int{}, []int{}, []int{}, []string{}
+	for _, s := range seats {
+		start := stack
+		if s.allIn || s.contributed > start {
+			start = s.contributed
+		}
+		antes = append(antes, s.ante)
+		blinds = append(blinds, s.blind)
+		stacks = append(stacks, start)
+		players = append(players, s.name)
+	}
+	// 單挑時按鈕下小盲，PHH 仍依「小盲、大盲」的順序寫 blinds_or_straddles
+	if len(blinds) == 2 && blinds[1] < blinds[0] {
+		blinds[0], blinds[1] = blinds[1], blinds[0]
+	}
+
+	var b strings.Builder
+	b.WriteString("variant = \"NT\"\n")
+	b.WriteString("ante_trimming_status = true\n")
+	fmt.Fprintf(&b, "antes = %s\n", intArray(antes))
+	fmt.Fprintf(&b, "blinds_or_straddles = %s\n", intArray(blinds))
+	fmt.Fprintf(&b, "min_bet = %d\n", bigBlind)
+	fmt.Fprintf(&b, "starting_stacks = %s\n", intArray(stacks))
+	b.WriteString("actions = [\n")
+	for _, line := range lines {
+		fmt.Fprintf(&b, "  %s,\n", tomlString(line))
+	}
+	b.WriteString("]\n")
+	fmt.Fprintf(&b, "players = %s\n", stringArray(players))
+	if session.TableSize >= len(seats) {
+		fmt.Fprintf(&b, "seat_count = %d\n", session.TableSize)
+	}
+	if hand.Level > 0 {
+		fmt.Fprintf(&b, "level = %d\n", hand.Level)
+	}
+	if session.Currency != "" {
+		fmt.Fprintf(&b, "currency = %s\n", tomlString(session.Currency))
+	}
+	date := hand.Date
+	if date == "" {
+		date = session.Date
+	}
+	if t, err := time.Parse("2006-01-02", models.DateDay(date)); err == nil {
+		fmt.Fprintf(&b, "day = %d\nmonth = %d\nyear = %d\n", t.Day(), int(t.Month()), t.Year())
+	}
+
+	// App 自己的欄位，PHH 規定以底線開頭
+	fmt.Fprintf(&b, "_hand_id = %s\n", tomlString(hand.ID))
+	if hand.SessionID != "" {
+		fmt.Fprintf(&b, "_session_id = %s\n", tomlString(hand.SessionID))
+	}
+	if hand.ExternalID != "" {
+		fmt.Fprintf(&b, "_external_id = %s\n", tomlString(hand.ExternalID))
+	}
+	if session.Location != "" {
+		fmt.Fprintf(&b, "_location = %s\n", tomlString(session.Location))
+	}
+	fmt.Fprintf(&b, "_hero = %s\n", tomlString(fmt.Sprintf("p%d", index[heroActor])))
+	fmt.Fprintf(&b, "_hero_result = %d\n", hand.Result)
+	return []byte(b.String()), nil
+}
+
+// streetActions 把一條街的動作轉成 PHH 的 f、cc、cbr；下注與加注寫加注到的總額
+func streetActions(street models.Street, seats []*seat, byActor map[string]*seat, index map[string]int, preflop bool) []string {
+	lines := []string{}
+	committed := map[string]int{}
+	if preflop {
+		for _, s := range seats {
+			committed[s.actor] = s.blind
+		}
+	}
+	for _, a := range street.Actions {
+		s := byActor[a.Actor]
+		if a.AllIn {
+			s.allIn = true
+		}
+		switch a.Type {
+		case "ante", "post":
+			continue
+		case "fold":
+			s.folded = true
+			lines = append(lines, fmt.Sprintf("p%d f", index[a.Actor]))
+		case "check", "call":
+			lines = append(lines, fmt.Sprintf("p%d cc", index[a.Actor]))
+		case "bet", "raise":
+			lines = append(lines, fmt.Sprintf("p%d cbr %d", index[a.Actor], committed[a.Actor]+a.Amount))
+		}
+		committed[a.Actor] += a.Amount
+		s.contributed += a.Amount
+	}
+	return lines
+}
+
+// allInShowdown 表示剩下的玩家最多只有一位還有籌碼，公共牌會直接發完
+func allInShowdown(remaining []*seat) bool {
+	withChips := 0
+	for _, s := range remaining {
+		if !s.allIn {
+			withChips++
+		}
+	}
+	return withChips <= 1
+}
+
+// seatRank 依位置排序；Seat N 排在已知位置之後，其他名稱排在最後
+func seatRank(actor string) int {
+	for i, p := range positionOrder {
+		if strings.EqualFold(actor, p) {
+			return i
+		}
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(actor, "Seat"))); err == nil {
+		return len(positionOrder) + n
+	}
+	return 1000
+}
+
+func previousStreet(name string) string {
+	switch name {
+	case "turn":
+		return "flop"
+	case "river":
+		return "turn"
+	}
+	return "preflop"
+}
+
+// parseCards 解析手牌或公共牌，無法解析時視為不知道
+func parseCards(s string) []cards.Card {
+	list, err := cards.ParseList(s)
+	if err != nil || len(list) == 0 {
+		return nil
+	}
+	return list
+}
+
+// formatCards 以 PHH 的寫法輸出牌，例如 AsKd；不知道的牌寫成 ??
+func formatCards(list []cards.Card, count int) string {
+	if len(list) == 0 {
+		return strings.Repeat("??", count)
+	}
+	return strings.ReplaceAll(cards.FormatASCII(list), " ", "")
+}
+
+// tomlString 輸出 TOML 的基本字串；JSON 的跳脫寫法在 TOML 中同樣有效
+func tomlString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func intArray(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func stringArray(values []string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = tomlString(v)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
@@ -1,7 +1,10 @@
 package routes
 
 import (
+	"errors"
 	"net/http"
+	"poker_tracker_backend/auth"
+	"poker_tracker_backend/db"
 	"poker_tracker_backend/handlers"
 )
 
@@ -17,12 +20,57 @@ func enableCORS(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// requireAuth 驗證 Authorization: Bearer <token>，通過後把使用者放進 request context
+// OPTIONS 預檢請求不需要 token
+func requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		enableCORS(w, r)
+		if r.Method == "OPTIONS" {
+			return
+		}
+		token := auth.BearerToken(r)
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		user, err := db.Repo.UserByToken(auth.HashToken(token))
+		if errors.Is(err, db.ErrNotFound) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Auth error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		next(w, auth.WithUser(r, user))
+	}
+}
+
 func RegisterRoutes() {
-	http.HandleFunc("/sessions", func(w http.ResponseWriter, r *http.Request) {
+	// 帳號：註冊與登入不需要 token
+	http.HandleFunc("/auth/register", func(w http.ResponseWriter, r *http.Request) {
 		enableCORS(w, r)
 		if r.Method == "OPTIONS" {
 			return
 		}
+		handlers.Register(w, r)
+	})
+
+	http.HandleFunc("/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		enableCORS(w, r)
+		if r.Method == "OPTIONS" {
+			return
+		}
+		handlers.Login(w, r)
+	})
+
+	http.HandleFunc("/auth/logout", requireAuth(handlers.Logout))
+
+	http.HandleFunc("/auth/me", requireAuth(handlers.GetCurrentUser))
+
+	http.HandleFunc("/sessions", requireAuth(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			handlers.GetSessions(w, r)
@@ -31,26 +79,33 @@ func RegisterRoutes() {
 		case http.MethodDelete:
 			handlers.DeleteSession(w, r)
 		}
-	})
+	}))
 
-	http.HandleFunc("/session", func(w http.ResponseWriter, r *http.Request) {
-		enableCORS(w, r)
-		if r.Method == "OPTIONS" {
-			return
-		}
+	http.HandleFunc("/session", requireAuth(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			handlers.GetSession(w, r)
 		case http.MethodPut:
 			handlers.UpdateSession(w, r)
 		}
-	})
+	}))
 
-	http.HandleFunc("/hands", func(w http.ResponseWriter, r *http.Request) {
-		enableCORS(w, r)
-		if r.Method == "OPTIONS" {
-			return
+	// 進行中的 session：/sessions/{id}/start|pause|resume|end|status
+	http.HandleFunc("/sessions/", requireAuth(handlers.SessionLive))
+
+	// session 的買入、補碼、兌現
+	http.HandleFunc("/session/transactions", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handlers.AddSessionTransaction(w, r)
+		case http.MethodDelete:
+			handlers.DeleteSessionTransaction(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
+	}))
+
+	http.HandleFunc("/hands", requireAuth(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			handlers.GetHands(w, r)
@@ -59,20 +114,96 @@ func RegisterRoutes() {
 		case http.MethodDelete:
 			handlers.DeleteHand(w, r)
 		}
-	})
+	}))
 
-	http.HandleFunc("/hand", func(w http.ResponseWriter, r *http.Request) {
+	// 手牌分享連結：/hands/{id}/share、/hands/{id}/shares[/{shareId}]
+	http.HandleFunc("/hands/", requireAuth(handlers.HandDetail))
+
+	// 公開的分享手牌，不需要登入
+	http.HandleFunc("/shared/", func(w http.ResponseWriter, r *http.Request) {
 		enableCORS(w, r)
 		if r.Method == "OPTIONS" {
 			return
 		}
+		handlers.GetSharedHand(w, r)
+	})
+
+	http.HandleFunc("/hand", requireAuth(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			handlers.GetHand(w, r)
 		case http.MethodPut:
 			handlers.UpdateHand(w, r)
 		}
-	})
+	}))
+
+	// 標籤
+	http.HandleFunc("/tags", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetTags(w, r)
+		case http.MethodPost:
+			handlers.CreateTag(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/tag", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetTag(w, r)
+		case http.MethodPut:
+			handlers.UpdateTag(w, r)
+		case http.MethodDelete:
+			handlers.DeleteTag(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// 合併標籤：來源標籤的手牌與 session 移到目標標籤
+	http.HandleFunc("/tags/merge", requireAuth(handlers.MergeTags))
+
+	// 對手檔案
+	http.HandleFunc("/players", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetPlayers(w, r)
+		case http.MethodPost:
+			handlers.CreatePlayer(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/player", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetPlayer(w, r)
+		case http.MethodPut:
+			handlers.UpdatePlayer(w, r)
+		case http.MethodDelete:
+			handlers.DeletePlayer(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// 某位玩家的手牌與對戰結果：/players/{id}/hands|stats|hud
+	http.HandleFunc("/players/", requireAuth(handlers.PlayerDetail))
+
+	// 手牌與攤牌結果
+	http.HandleFunc("/hand/showdown", requireAuth(handlers.GetHandShowdown))
+
+	// 全下勝率
+	http.HandleFunc("/equity", requireAuth(handlers.CalculateEquity))
+
+	// 全文搜尋手牌與 session
+	http.HandleFunc("/search", requireAuth(handlers.Search))
+
+	// 錦標賽 ICM 計算
+	http.HandleFunc("/icm", requireAuth(handlers.CalculateICM))
 
 	// 測試路由
 	http.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
@@ -84,28 +215,55 @@ func RegisterRoutes() {
 	})
 
 	// 暫時註釋掉analyze路由
-	http.HandleFunc("/analyze", func(w http.ResponseWriter, r *http.Request) {
-		enableCORS(w, r)
-		if r.Method == "OPTIONS" {
-			return
-		}
-		handlers.AnalyzeHand(w, r)
-	})
+	http.HandleFunc("/analyze", requireAuth(handlers.AnalyzeHand))
 
 	// 切換最愛狀態
-	http.HandleFunc("/toggle-favorite", func(w http.ResponseWriter, r *http.Request) {
-		enableCORS(w, r)
-		if r.Method == "OPTIONS" {
-			return
+	http.HandleFunc("/toggle-favorite", requireAuth(handlers.ToggleFavorite))
+
+	http.HandleFunc("/stats", requireAuth(handlers.GetStats))
+
+	// Hero 的翻牌前統計
+	http.HandleFunc("/stats/player", requireAuth(handlers.GetPlayerStats))
+
+	// 報表幣別等設定
+	http.HandleFunc("/settings", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetSettings(w, r)
+		case http.MethodPut:
+			handlers.UpdateSettings(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-		handlers.ToggleFavorite(w, r)
-	})
+	}))
 
-	http.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
-		enableCORS(w, r)
-		if r.Method == "OPTIONS" {
-			return
+	// 匯率表，POST 上傳 CSV
+	http.HandleFunc("/exchange-rates", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetExchangeRates(w, r)
+		case http.MethodPost:
+			handlers.ImportExchangeRates(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-		handlers.GetStats(w, r)
-	})
-} 
\ No newline at end of file
+	}))
+
+	// 匯入 PokerStars 手牌歷史
+	http.HandleFunc("/import", requireAuth(handlers.ImportHands))
+
+	// 匯出 PHH（Poker Hand History）手牌，以 zip 下載
+	http.HandleFunc("/export/phh", requireAuth(handlers.ExportPHH))
+
+	// 匯出 session 與手牌給試算表使用，篩選參數與列表相同
+	http.HandleFunc("/export/sessions.csv", requireAuth(handlers.ExportSessionsCSV))
+
+	http.HandleFunc("/export/hands.csv", requireAuth(handlers.ExportHandsCSV))
+
+	http.HandleFunc("/export/workbook.xlsx", requireAuth(handlers.ExportWorkbook))
+
+	// 帳號的完整備份與還原
+	http.HandleFunc("/backup", requireAuth(handlers.GetBackup))
+
+	http.HandleFunc("/restore", requireAuth(handlers.RestoreBackup))
+}
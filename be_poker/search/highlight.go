@@ -0,0 +1,105 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// SnippetRadius 是片段在第一個符合處前後保留的字數
+const SnippetRadius = 60
+
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+)
+
+// Highlight 回傳 text 中第一個符合處附近的片段，所有符合的搜尋詞以 <mark></mark> 標記
+// 比對不分大小寫；片段其餘文字做 HTML 跳脫。沒有任何符合時回傳空字串
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// marked[i] 表示第 i 個字落在符合的範圍內
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, needle := range needles(terms) {
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if !hasPrefix(lower[i:], needle) {
+				continue
+			}
+			for j := i; j < i+len(needle); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	start, end := first-SnippetRadius, first+SnippetRadius
+	if start < 0 {
+		start = 0
+	}
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			segment = markOpen + segment + markClose
+		}
+		b.WriteString(segment)
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// needles 是要標記的字串：整個搜尋詞，以及片語或 check-raise 這類詞拆開後的字詞
+// Postgres 全文搜尋以字詞比對，文字中不一定有完整的搜尋詞
+func needles(terms []string) [][]rune {
+	list := [][]rune{}
+	for _, term := range terms {
+		words := strings.FieldsFunc(term, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(words) > 1 || (len(words) == 1 && words[0] != term) {
+			words = append(words, term)
+		}
+		for _, w := range words {
+			needle := []rune(w)
+			for i, r := range needle {
+				needle[i] = unicode.ToLower(r)
+			}
+			list = append(list, needle)
+		}
+	}
+	return list
+}
+
+func hasPrefix(s, prefix []rune) bool {
+	for i, r := range prefix {
+		if s[i] != r {
+			return false
+		}
+	}
+	return true
+}
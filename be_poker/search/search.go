@@ -0,0 +1,125 @@
+package search
+
+import (
+	"fmt"
+	"poker_tracker_backend/db"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Query 是解析後的搜尋字串
+// 一般文字成為搜尋詞，key:value 形式的已知欄位成為篩選條件
+type Query struct {
+	Terms    []string
+	Hands    db.HandFilter
+	Sessions db.SessionFilter
+	// 只適用於手牌的條件（例如 pos:）會排除 session，反之亦然
+	SearchHands    bool
+	SearchSessions bool
+}
+
+// Parse 解析搜尋字串，例如 `"check raise" flush pos:BTN tag:bluff`
+// 支援的篩選：pos、tag、session、stakes、loc、from、to、is:fav、is:analyzed、in:hands|sessions
+// 雙引號內的文字視為一個片語；不認得的 key:value 當成一般文字
+func Parse(q string) (Query, error) {
+	query := Query{Terms: []string{}, SearchHands: true, SearchSessions: true}
+	for _, token := range tokenize(q) {
+		key, value, ok := strings.Cut(token, ":")
+		if !ok || value == "" {
+			query.Terms = append(query.Terms, token)
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "pos", "position":
+			query.Hands.Position = strings.ToUpper(value)
+			query.SearchSessions = false
+		case "tag":
+			query.Hands.Tags = append(query.Hands.Tags, value)
+			query.Sessions.Tags = append(query.Sessions.Tags, value)
+		case "session":
+			query.Hands.SessionID = value
+			query.SearchSessions = false
+		case "loc", "location":
+			query.Hands.Location = value
+			query.Sessions.Location = value
+		case "stakes":
+			sb, bb, err := parseStakes(value)
+			if err != nil {
+				return query, err
+			}
+			query.Hands.SmallBlind, query.Hands.BigBlind = sb, bb
+			query.Sessions.SmallBlind, query.Sessions.BigBlind = sb, bb
+		case "from", "to":
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				return query, fmt.Errorf("%s: must be a date in YYYY-MM-DD format", key)
+			}
+			if strings.ToLower(key) == "from" {
+				query.Hands.DateFrom, query.Sessions.DateFrom = value, value
+			} else {
+				query.Hands.DateTo, query.Sessions.DateTo = value, value
+			}
+		case "is":
+			yes := true
+			switch strings.ToLower(value) {
+			case "fav", "favorite":
+				query.Hands.Favorite = &yes
+			case "analyzed":
+				query.Hands.HasAnalysis = &yes
+			default:
+				return query, fmt.Errorf("is: must be fav or analyzed")
+			}
+			query.SearchSessions = false
+		case "in":
+			switch strings.ToLower(value) {
+			case "hand", "hands":
+				query.SearchSessions = false
+			case "session", "sessions":
+				query.SearchHands = false
+			default:
+				return query, fmt.Errorf("in: must be hands or sessions")
+			}
+		default:
+			query.Terms = append(query.Terms, token)
+		}
+	}
+	return query, nil
+}
+
+// tokenize 以空白切開，雙引號內的空白保留
+func tokenize(q string) []string {
+	tokens := []string{}
+	var b strings.Builder
+	quoted := false
+	flush := func() {
+		if t := strings.TrimSpace(b.String()); t != "" {
+			tokens = append(tokens, t)
+		}
+		b.Reset()
+	}
+	for _, r := range q {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			flush()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+func parseStakes(v string) (int, int, error) {
+	parts := strings.Split(strings.TrimPrefix(v, "$"), "/")
+	if len(parts) == 2 {
+		sb, err1 := strconv.Atoi(parts[0])
+		bb, err2 := strconv.Atoi(parts[1])
+		if err1 == nil && err2 == nil && sb >= 0 && bb > 0 {
+			return sb, bb, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("stakes: must look like 1/2")
+}
@@ -0,0 +1,127 @@
+package sessions
+
+import (
+	"fmt"
+	"poker_tracker_backend/models"
+	"time"
+)
+
+// 進行中 session 的狀態
+const (
+	StateLive   = "live"
+	StatePaused = "paused"
+	StateEnded  = "ended"
+)
+
+// Start 開始一個 session；事後補記但還沒結束的 session 也可以開始
+func Start(session *models.Session, now time.Time) error {
+	switch session.State {
+	case StateLive, StatePaused:
+		return fmt.Errorf("session is already %s", session.State)
+	case StateEnded:
+		return fmt.Errorf("session has already ended")
+	}
+	if session.EndTime != "" {
+		return fmt.Errorf("session has already ended")
+	}
+	session.State = StateLive
+	if session.StartTime == "" {
+		session.StartTime = now.UTC().Format(time.RFC3339)
+	}
+	return nil
+}
+
+// Pause 暫停計時，例如休息或換桌
+func Pause(session *models.Session, now time.Time) error {
+	if session.State != StateLive {
+		return fmt.Errorf("only a live session can be paused (state %q)", session.State)
+	}
+	session.State = StatePaused
+	session.PausedAt = now.UTC().Format(time.RFC3339)
+	return nil
+}
+
+// Resume 繼續計時，暫停的時間累計到 PausedSeconds
+func Resume(session *models.Session, now time.Time) error {
+	if session.State != StatePaused {
+		return fmt.Errorf("only a paused session can be resumed (state %q)", session.State)
+	}
+	closePause(session, now)
+	session.State = StateLive
+	return nil
+}
+
+// End 結束 session，暫停中結束時暫停的時間不計入時長
+func End(session *models.Session, now time.Time) error {
+	if session.State != StateLive && session.State != StatePaused {
+		return fmt.Errorf("only a live or paused session can be ended (state %q)", session.State)
+	}
+	closePause(session, now)
+	session.State = StateEnded
+	session.EndTime = now.UTC().Format(time.RFC3339)
+	return nil
+}
+
+func closePause(session *models.Session, now time.Time) {
+	if pausedAt, err := parseTime(session.PausedAt); err == nil && !pausedAt.IsZero() && now.After(pausedAt) {
+		session.PausedSeconds += int(now.Sub(pausedAt).Seconds())
+	}
+	session.PausedAt = ""
+}
+
+// Elapsed 回傳到 now 為止實際打牌的時間，不含暫停
+func Elapsed(session models.Session, now time.Time) time.Duration {
+	start, err := parseTime(session.StartTime)
+	if err != nil || start.IsZero() {
+		return 0
+	}
+	end := now
+	if t, err := parseTime(session.EndTime); err == nil && !t.IsZero() {
+		end = t
+	} else if t, err := parseTime(session.PausedAt); err == nil && !t.IsZero() {
+		end = t
+	}
+	elapsed := end.Sub(start) - time.Duration(session.PausedSeconds)*time.Second
+	if elapsed < 0 {
+		return 0
+	}
+	return elapsed
+}
+
+// Status 計算進行中 session 的即時狀態
+func Status(session models.Session, hands []models.Hand, now time.Time) models.LiveStatus {
+	Summarize(&session)
+	status := models.LiveStatus{
+		SessionID:      session.ID,
+		State:          session.State,
+		StartTime:      session.StartTime,
+		ElapsedSeconds: int(Elapsed(session, now).Seconds()),
+		PausedSeconds:  session.PausedSeconds,
+		Hands:          len(hands),
+	}
+	// 暫停中的時間也顯示出來
+	if pausedAt, err := parseTime(session.PausedAt); err == nil && !pausedAt.IsZero() && now.After(pausedAt) {
+		status.PausedSeconds += int(now.Sub(pausedAt).Seconds())
+	}
+
+	handsResult := 0
+	for _, h := range hands {
+		handsResult += h.Result
+	}
+	cashedOut := 0
+	for _, t := range session.Transactions {
+		switch t.Type {
+		case BuyIn, Rebuy:
+			status.Invested += t.Amount
+		case CashOut:
+			cashedOut += t.Amount
+		}
+	}
+	status.CurrentStack = status.Invested + handsResult - cashedOut
+	status.RunningProfit = handsResult
+	if session.State == StateEnded && session.CashResult != nil {
+		status.RunningProfit = *session.CashResult
+		status.CurrentStack = 0
+	}
+	return status
+}
@@ -0,0 +1,119 @@
+package sessions
+
+import (
+	"fmt"
+	"poker_tracker_backend/models"
+	"time"
+)
+
+// 現金異動的種類
+const (
+	BuyIn   = "buyin"
+	Rebuy   = "rebuy"
+	CashOut = "cashout"
+)
+
+// Validate 檢查 session 的時間與現金異動
+func Validate(session *models.Session) models.ValidationErrors {
+	var errs models.ValidationErrors
+
+	start, startErr := parseTime(session.StartTime)
+	if startErr != nil {
+		errs.Add("startTime", "must be an RFC3339 timestamp")
+	}
+	end, endErr := parseTime(session.EndTime)
+	if endErr != nil {
+		errs.Add("endTime", "must be an RFC3339 timestamp")
+	}
+	if startErr == nil && endErr == nil && !start.IsZero() && !end.IsZero() && end.Before(start) {
+		errs.Add("endTime", "must not be before startTime")
+	}
+	if session.EndTime != "" && session.StartTime == "" {
+		errs.Add("startTime", "is required when endTime is set")
+	}
+
+	errs = append(errs, validateTournament(session)...)
+
+	for i, t := range session.Transactions {
+		field := fmt.Sprintf("transactions[%d]", i)
+		errs = append(errs, ValidateTransaction(field, t)...)
+	}
+	return errs
+}
+
+// ValidateTransaction 檢查單筆現金異動
+func ValidateTransaction(field string, t models.Transaction) models.ValidationErrors {
+	var errs models.ValidationErrors
+	switch t.Type {
+	case BuyIn, Rebuy, CashOut:
+	default:
+		errs.Add(field+".type", "must be buyin, rebuy or cashout")
+	}
+	if t.Amount < 0 || (t.Amount == 0 && t.Type != CashOut) {
+		errs.Add(field+".amount", "must be positive")
+	}
+	if _, err := parseTime(t.Time); err != nil {
+		errs.Add(field+".time", "must be an RFC3339 timestamp")
+	}
+	return errs
+}
+
+// Summarize 依現金異動與時間算出 CashResult 與 DurationMinutes
+// 有兌現紀錄，或 session 已結束且有買入時才有結果；結束時沒有兌現視為輸光
+// 錦標賽以買入、獎金與賞金計算，有名次或已結束時才有結果
+func Summarize(session *models.Session) {
+	session.DurationMinutes = 0
+	if d, ok := Duration(*session); ok {
+		session.DurationMinutes = int(d.Minutes())
+	}
+
+	session.CashResult = nil
+	if IsTournament(*session) && session.Tournament != nil {
+		if t := session.Tournament; t.Place > 0 || session.EndTime != "" {
+			result := TournamentResult(*t)
+			session.CashResult = &result
+		}
+		return
+	}
+
+	invested, cashedOut := 0, 0
+	hasBuyIn, hasCashOut := false, false
+	for _, t := range session.Transactions {
+		switch t.Type {
+		case BuyIn, Rebuy:
+			invested += t.Amount
+			hasBuyIn = true
+		case CashOut:
+			cashedOut += t.Amount
+			hasCashOut = true
+		}
+	}
+	if hasCashOut || (hasBuyIn && session.EndTime != "") {
+		result := cashedOut - invested
+		session.CashResult = &result
+	}
+}
+
+// Duration 回傳 session 的時長（扣掉暫停時間），沒有開始或結束時間時回傳 false
+func Duration(session models.Session) (time.Duration, bool) {
+	start, err := parseTime(session.StartTime)
+	if err != nil || start.IsZero() {
+		return 0, false
+	}
+	end, err := parseTime(session.EndTime)
+	if err != nil || end.IsZero() || end.Before(start) {
+		return 0, false
+	}
+	d := end.Sub(start) - time.Duration(session.PausedSeconds)*time.Second
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
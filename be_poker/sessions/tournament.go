@@ -0,0 +1,73 @@
+package sessions
+
+import "poker_tracker_backend/models"
+
+// Session 類型
+const (
+	TypeCash       = "cash"
+	TypeTournament = "tournament"
+	TypeSNG        = "sng"
+)
+
+// IsTournament 判斷 session 是否為錦標賽或 SNG
+func IsTournament(session models.Session) bool {
+	return session.Type == TypeTournament || session.Type == TypeSNG
+}
+
+// Entries 回傳報名次數（第一次加上重新報名）
+func Entries(t models.Tournament) int {
+	return 1 + t.ReEntries
+}
+
+// Cost 回傳所有報名的買入加手續費
+func Cost(t models.Tournament) int {
+	return (t.BuyIn + t.Fee) * Entries(t)
+}
+
+// TournamentResult 回傳獎金加賞金減去總花費
+func TournamentResult(t models.Tournament) int {
+	return t.Prize + t.Bounties - Cost(t)
+}
+
+// validateTournament 檢查 session 類型與錦標賽資料
+func validateTournament(session *models.Session) models.ValidationErrors {
+	var errs models.ValidationErrors
+	switch session.Type {
+	case "", TypeCash:
+		if session.Tournament != nil {
+			errs.Add("tournament", "only tournament and sng sessions can have tournament details")
+		}
+		return errs
+	case TypeTournament, TypeSNG:
+	default:
+		errs.Add("type", "must be cash, tournament or sng")
+		return errs
+	}
+
+	t := session.Tournament
+	if t == nil {
+		errs.Add("tournament", "is required for tournament and sng sessions")
+		return errs
+	}
+	// 依固定順序檢查，錯誤訊息的順序才不會每次不同
+	for _, f := range []struct {
+		field string
+		value int
+	}{
+		{"tournament.buyIn", t.BuyIn},
+		{"tournament.fee", t.Fee},
+		{"tournament.reEntries", t.ReEntries},
+		{"tournament.fieldSize", t.FieldSize},
+		{"tournament.place", t.Place},
+		{"tournament.prize", t.Prize},
+		{"tournament.bounties", t.Bounties},
+	} {
+		if f.value < 0 {
+			errs.Add(f.field, "must not be negative")
+		}
+	}
+	if t.FieldSize > 0 && t.Place > t.FieldSize {
+		errs.Add("tournament.place", "must not be larger than fieldSize")
+	}
+	return errs
+}
@@ -0,0 +1,169 @@
+package stats
+
+import (
+	"fmt"
+	"hash/fnv"
+	"poker_tracker_backend/actions"
+	"poker_tracker_backend/cards"
+	"poker_tracker_backend/equity"
+	"poker_tracker_backend/models"
+	"sort"
+)
+
+// 統計時每手牌的計算上限：翻牌前全下改用 Monte Carlo，避免統計太慢
+const (
+	statsMaxExact   = 200000
+	statsIterations = 20000
+)
+
+// AllInEV 計算 Hero 在河牌前全下的手牌的期望輸贏
+// 只有 Hero 與至少一位對手的手牌已知、Hero 沒有棄牌，且有結構化動作時才計算
+// 每位玩家的投入以 Hero 的投入為上限（超過的部分 Hero 贏不到，沒被跟注的部分會退回），
+// 再依投入分成主池與邊池，每個池以 Hero 對有資格爭奪該池的對手的勝率計算
+// 期望值 = Σ 各池的勝率 × 池的大小 − Hero 投入的金額（未計入抽水）
+func AllInEV(hand models.Hand) (float64, bool) {
+	if hand.Position == nil || *hand.Position == "" || hand.HoleCards == nil {
+		return 0, false
+	}
+	hero := *hand.Position
+
+	street, ok := actions.AllInStreet(hand.Streets)
+	if !ok || street == "river" {
+		return 0, false
+	}
+
+	contributions := map[string]int{}
+	folded := map[string]bool{}
+	for _, s := range hand.Streets {
+		for _, a := range s.Actions {
+			contributions[a.Actor] += a.Amount
+			if a.Type == "fold" {
+				folded[a.Actor] = true
+			}
+		}
+	}
+	invested := contributions[hero]
+	if folded[hero] || invested == 0 {
+		return 0, false
+	}
+
+	heroCombos, err := equity.ParseRange(*hand.HoleCards)
+	if err != nil || len(heroCombos) != 1 {
+		return 0, false
+	}
+	players := [][]equity.Combo{heroCombos}
+	// stakes 是每位參與攤牌的玩家投入（以 Hero 的投入為上限），順序與 players 相同
+	stakes := []int{invested}
+	for _, v := range hand.Villains {
+		if v.HoleCards == "" {
+			continue
+		}
+		// 棄牌的對手不參與攤牌
+		if v.Position != "" && folded[v.Position] {
+			continue
+		}
+		combos, err := equity.ParseRange(v.HoleCards)
+		if err != nil || len(combos) != 1 {
+			return 0, false
+		}
+		players = append(players, combos)
+		// 沒有位置或沒有動作的對手無法得知投入，視為蓋過 Hero
+		stake, ok := contributions[v.Position]
+		if v.Position == "" || !ok || stake > invested {
+			stake = invested
+		}
+		stakes = append(stakes, stake)
+	}
+	if len(players) < 2 {
+		return 0, false
+	}
+
+	// 全下當時的公共牌
+	var board []cards.Card
+	if hand.Board != nil && *hand.Board != "" {
+		list, err := cards.ParseList(*hand.Board)
+		if err != nil {
+			return 0, false
+		}
+		board = list
+	}
+	if n := actions.BoardSize(street); n < len(board) {
+		board = board[:n]
+	}
+	if len(board) < actions.BoardSize(street) {
+		return 0, false
+	}
+
+	// 同一組對手只計算一次勝率；只剩 Hero 有資格的池（例如棄牌玩家留下的死錢）全歸 Hero
+	equities := map[string]float64{}
+	heroEquity := func(eligible []int) (float64, bool) {
+		if len(eligible) == 1 {
+			return 1, true
+		}
+		key := fmt.Sprint(eligible)
+		if e, ok := equities[key]; ok {
+			return e, true
+		}
+		subset := make([][]equity.Combo, len(eligible))
+		for i, p := range eligible {
+			subset[i] = players[p]
+		}
+		result, err := equity.Calculate(subset, board, equity.Options{
+			Iterations: statsIterations,
+			Seed:       handSeed(hand.ID),
+			MaxExact:   statsMaxExact,
+		})
+		if err != nil {
+			return 0, false
+		}
+		equities[key] = result.Players[0].Equity
+		return equities[key], true
+	}
+
+	levels := append([]int{}, stakes...)
+	sort.Ints(levels)
+	ev := 0.0
+	prev := 0
+	for _, level := range levels {
+		if level == prev {
+			continue
+		}
+		// 這一層的池：每位玩家（包括已棄牌的）在 prev 到 level 之間投入的部分
+		pot := 0
+		for _, amount := range contributions {
+			pot += clamp(amount, prev, level) - prev
+		}
+		eligible := []int{}
+		for i, stake := range stakes {
+			if stake >= level {
+				eligible = append(eligible, i)
+			}
+		}
+		e, ok := heroEquity(eligible)
+		if !ok {
+			return 0, false
+		}
+		ev += e * float64(pot)
+		prev = level
+	}
+
+	return ev - float64(invested), true
+}
+
+// clamp 把 v 限制在 lo 到 hi 之間
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// handSeed 讓同一手牌每次統計都得到相同的 Monte Carlo 結果
+func handSeed(id string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return int64(h.Sum64() >> 1)
+}
@@ -0,0 +1,73 @@
+package stats
+
+import (
+	"poker_tracker_backend/models"
+	"testing"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestAllInEVVillainCoversHero(t *testing.T) {
+	// 對手全下 1000，Hero 只有 100 可以跟注：超過的 900 Hero 贏不到
+	hand := models.Hand{
+		ID:        "covered",
+		HoleCards: strPtr("AhAd"),
+		Position:  strPtr("BTN"),
+		Villains:  []models.Villain{{HoleCards: "7c2s", Position: "CO"}},
+		Streets: []models.Street{{Name: "preflop", Actions: []models.Action{
+			{Actor: "CO", Type: "raise", Amount: 1000, AllIn: true},
+			{Actor: "BTN", Type: "call", Amount: 100, AllIn: true},
+		}}},
+	}
+	ev, ok := AllInEV(hand)
+	if !ok {
+		t.Fatal("expected an all-in EV")
+	}
+	// AA 對 72o 約 87%：0.87 × 200 − 100
+	if ev < 68 || ev > 78 {
+		t.Errorf("got EV %.2f, want about 73", ev)
+	}
+}
+
+func TestAllInEVSidePot(t *testing.T) {
+	// Hero 200 全下，SB 只有 50；BB 跟注 200
+	// Hero 的 A♠A♥ 對 BB 的 K♠K♥ 爭 300 的邊池，主池 150 還有 SB 的 7♣2♦
+	hand := models.Hand{
+		ID:        "side-pot",
+		HoleCards: strPtr("AsAh"),
+		Position:  strPtr("BTN"),
+		Villains: []models.Villain{
+			{HoleCards: "7c2d", Position: "SB"},
+			{HoleCards: "KsKh", Position: "BB"},
+		},
+		Streets: []models.Street{{Name: "preflop", Actions: []models.Action{
+			{Actor: "BTN", Type: "raise", Amount: 200, AllIn: true},
+			{Actor: "SB", Type: "call", Amount: 50, AllIn: true},
+			{Actor: "BB", Type: "call", Amount: 200},
+		}}},
+	}
+	ev, ok := AllInEV(hand)
+	if !ok {
+		t.Fatal("expected an all-in EV")
+	}
+	// 主池的三人勝率約 0.70，邊池對 KK 約 0.82：0.70 × 150 + 0.82 × 300 − 200 ≈ 151
+	if ev < 140 || ev > 165 {
+		t.Errorf("got EV %.2f, want about 151", ev)
+	}
+}
+
+func TestAllInEVNeedsKnownVillain(t *testing.T) {
+	hand := models.Hand{
+		ID:        "unknown",
+		HoleCards: strPtr("AhAd"),
+		Position:  strPtr("BTN"),
+		Villains:  []models.Villain{{Position: "CO"}},
+		Streets: []models.Street{{Name: "preflop", Actions: []models.Action{
+			{Actor: "CO", Type: "raise", Amount: 100, AllIn: true},
+			{Actor: "BTN", Type: "call", Amount: 100, AllIn: true},
+		}}},
+	}
+	if _, ok := AllInEV(hand); ok {
+		t.Error("expected no EV without villain hole cards")
+	}
+}
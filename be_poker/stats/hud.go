@@ -0,0 +1,191 @@
+package stats
+
+import (
+	"poker_tracker_backend/cards"
+	"poker_tracker_backend/evaluator"
+	"poker_tracker_backend/models"
+)
+
+// villainFlags 是一手牌中某位對手的 HUD 統計是否有機會、是否發生
+type villainFlags struct {
+	vpip, pfr                 bool
+	bets, calls               int
+	sawFlop, showdown         bool
+	wsdKnown, wonShowdown     bool
+	foldToCBetOpp, foldedToCB bool
+}
+
+// HUDFor 依手牌中記錄的動作計算 player 的 HUD 統計
+// 玩家以 villain 的 position 對應動作中的 actor；hands 應該只包含有這位玩家的手牌
+func HUDFor(player models.Player, hands []models.Hand) models.PlayerHUD {
+	hud := models.PlayerHUD{Player: player}
+	count := func(s *models.StatValue, opp, hit bool) {
+		if opp {
+			s.Opportunities++
+		}
+		if opp && hit {
+			s.Count++
+		}
+	}
+
+	for _, hand := range hands {
+		f, ok := playerFlags(hand, player.ID)
+		if !ok {
+			hud.Skipped++
+			continue
+		}
+		hud.Hands++
+		count(&hud.VPIP, true, f.vpip)
+		count(&hud.PFR, true, f.pfr)
+		count(&hud.WTSD, f.sawFlop, f.showdown)
+		count(&hud.WSD, f.showdown && f.wsdKnown, f.wonShowdown)
+		count(&hud.FoldToCBet, f.foldToCBetOpp, f.foldedToCB)
+		hud.Aggression.Bets += f.bets
+		hud.Aggression.Calls += f.calls
+	}
+
+	for _, s := range []*models.StatValue{&hud.VPIP, &hud.PFR, &hud.WTSD, &hud.WSD, &hud.FoldToCBet} {
+		if s.Opportunities > 0 {
+			s.Percent = round2(float64(s.Count) / float64(s.Opportunities) * 100)
+		}
+	}
+	if hud.Aggression.Calls > 0 {
+		factor := round2(float64(hud.Aggression.Bets) / float64(hud.Aggression.Calls))
+		hud.Aggression.Factor = &factor
+	}
+	return hud
+}
+
+// playerFlags 判斷 playerID 在這手牌的行為；玩家沒有位置或沒有翻牌前動作時回傳 false
+func playerFlags(hand models.Hand, playerID string) (villainFlags, bool) {
+	var f villainFlags
+	var villain *models.Villain
+	for i := range hand.Villains {
+		if hand.Villains[i].PlayerID == playerID {
+			villain = &hand.Villains[i]
+			break
+		}
+	}
+	if villain == nil || villain.Position == "" || len(hand.Streets) == 0 || hand.Streets[0].Name != "preflop" {
+		return f, false
+	}
+	actor := villain.Position
+
+	acted := false
+	for _, a := range hand.Streets[0].Actions {
+		if a.Actor == actor {
+			acted = true
+			break
+		}
+	}
+	if !acted {
+		return f, false
+	}
+
+	folded := map[string]bool{}
+	players := map[string]bool{}
+	if hand.Position != nil && *hand.Position != "" {
+		players[*hand.Position] = true
+	}
+	lastRaiser := "" // 翻牌前最後加注者
+	foldedPreflop := false
+	streets := map[string]bool{}
+
+	for _, street := range hand.Streets {
+		streets[street.Name] = true
+		preflop := street.Name == "preflop"
+		flopBets := 0       // 翻牌圈目前的下注與加注次數
+		facingCBet := false // 對手正面對持續下注
+
+		for _, a := range street.Actions {
+			players[a.Actor] = true
+			aggressive := a.Type == "raise" || a.Type == "bet"
+
+			if a.Actor == actor && !folded[actor] {
+				switch {
+				case preflop:
+					if a.Type == "call" || aggressive {
+						f.vpip = true
+					}
+					if aggressive {
+						f.pfr = true
+					}
+					foldedPreflop = a.Type == "fold"
+				case aggressive:
+					f.bets++
+				case a.Type == "call":
+					f.calls++
+				}
+				if facingCBet {
+					f.foldToCBetOpp = true
+					f.foldedToCB = a.Type == "fold"
+					facingCBet = false
+				}
+			}
+
+			if a.Type == "fold" {
+				folded[a.Actor] = true
+			}
+			if aggressive {
+				if preflop {
+					lastRaiser = a.Actor
+				}
+				if street.Name == "flop" {
+					flopBets++
+					// 翻牌圈第一個下注來自翻牌前加注者才算持續下注，之後有人加注就不再是
+					facingCBet = flopBets == 1 && a.Type == "bet" && a.Actor == lastRaiser && a.Actor != actor
+				}
+			}
+		}
+	}
+
+	boardSize := 0
+	if hand.Board != nil && *hand.Board != "" {
+		if list, err := cards.ParseList(*hand.Board); err == nil {
+			boardSize = len(list)
+		}
+	}
+	if foldedPreflop {
+		return f, true
+	}
+	f.sawFlop = streets["flop"] || boardSize >= 3
+	if !f.sawFlop || folded[actor] || !(streets["river"] || boardSize == 5) {
+		return f, true
+	}
+
+	remaining := []string{}
+	for p := range players {
+		if !folded[p] {
+			remaining = append(remaining, p)
+		}
+	}
+	if len(remaining) < 2 {
+		return f, true
+	}
+	f.showdown = true
+
+	// 攤牌的每位玩家手牌都已知時以牌力判斷贏家；只剩 hero 與這位玩家時以 hero 的輸贏判斷
+	if result, err := evaluator.Showdown(hand); err == nil && result.Complete {
+		known := map[string]bool{}
+		won := false
+		for _, p := range result.Players {
+			known[p.Position] = true
+			if p.Position == actor {
+				won = p.Winner
+			}
+		}
+		all := true
+		for _, p := range remaining {
+			all = all && known[p]
+		}
+		if all {
+			f.wsdKnown, f.wonShowdown = true, won
+		}
+	}
+	heroIn := hand.Position != nil && *hand.Position != "" && !folded[*hand.Position]
+	if !f.wsdKnown && len(remaining) == 2 && heroIn && hand.Result != 0 {
+		f.wsdKnown = true
+		f.wonShowdown = hand.Result < 0
+	}
+	return f, true
+}
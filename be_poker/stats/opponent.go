@@ -0,0 +1,77 @@
+package stats
+
+import (
+	"poker_tracker_backend/currency"
+	"poker_tracker_backend/models"
+	"poker_tracker_backend/sessions"
+	"sort"
+)
+
+// AgainstPlayer 彙總 hero 在 player 參與的手牌中的結果
+// hands 應該只包含有這位玩家的手牌；錦標賽手牌的輸贏是籌碼，只計入手數
+func AgainstPlayer(player models.Player, allSessions []models.Session, hands []models.Hand, conv *currency.Converter) models.PlayerSummary {
+	summary := models.PlayerSummary{Player: player, Currency: conv.To}
+	sessionByID := map[string]models.Session{}
+	for _, session := range allSessions {
+		sessionByID[session.ID] = session
+	}
+
+	sessionsSeen := map[string]bool{}
+	missing := map[string]bool{}
+	for _, hand := range hands {
+		session := sessionByID[hand.SessionID]
+		summary.Hands++
+		if hand.SessionID != "" {
+			sessionsSeen[hand.SessionID] = true
+		}
+
+		date := hand.Date
+		if date == "" {
+			date = session.Date
+		}
+		if date != "" && (summary.FirstSeen == "" || date < summary.FirstSeen) {
+			summary.FirstSeen = date
+		}
+		if date > summary.LastSeen {
+			summary.LastSeen = date
+		}
+
+		for _, v := range hand.Villains {
+			if v.PlayerID == player.ID && v.HoleCards != "" {
+				summary.KnownHands++
+				break
+			}
+		}
+
+		if sessions.IsTournament(session) {
+			continue
+		}
+		switch {
+		case hand.Result > 0:
+			summary.Won++
+		case hand.Result < 0:
+			summary.Lost++
+		}
+		if result, err := conv.Convert(float64(hand.Result), session.Currency, date); err == nil {
+			summary.NetResult += result
+		} else {
+			missing[conv.Code(session.Currency)] = true
+		}
+		if session.BigBlind > 0 {
+			summary.BBHands++
+			summary.TotalBB += float64(hand.Result) / float64(session.BigBlind)
+		}
+	}
+
+	summary.Sessions = len(sessionsSeen)
+	if summary.BBHands > 0 {
+		summary.BBPer100 = round2(summary.TotalBB / float64(summary.BBHands) * 100)
+	}
+	summary.NetResult = round2(summary.NetResult)
+	summary.TotalBB = round2(summary.TotalBB)
+	for code := range missing {
+		summary.MissingRates = append(summary.MissingRates, code)
+	}
+	sort.Strings(summary.MissingRates)
+	return summary
+}
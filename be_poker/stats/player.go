@@ -0,0 +1,175 @@
+package stats
+
+import "poker_tracker_backend/models"
+
+// 偷盲的位置
+var stealPositions = map[string]bool{"CO": true, "BTN": true, "SB": true}
+
+// handFlags 是一手牌中 Hero 的各項統計是否有機會、是否發生
+type handFlags struct {
+	vpip, pfr                     bool
+	threeBetOpp, threeBet         bool
+	foldToThreeBetOpp, foldedTo3B bool
+	stealOpp, steal               bool
+	cbetOpp, cbet                 bool
+}
+
+// PlayerStatsFor 計算 Hero 的翻牌前統計，並依位置與級別分組
+func PlayerStatsFor(sessions []models.Session, hands []models.Hand) models.PlayerStats {
+	stakes := map[string]string{}
+	for _, s := range sessions {
+		stakes[s.ID] = StakesKey(s)
+	}
+
+	result := models.PlayerStats{
+		ByPosition: map[string]models.StatLine{},
+		ByStakes:   map[string]models.StatLine{},
+	}
+	for _, hand := range hands {
+		flags, ok := heroFlags(hand)
+		if !ok {
+			result.Skipped++
+			continue
+		}
+		result.Overall = addFlags(result.Overall, flags)
+		position := *hand.Position
+		result.ByPosition[position] = addFlags(result.ByPosition[position], flags)
+		if key, ok := stakes[hand.SessionID]; ok {
+			result.ByStakes[key] = addFlags(result.ByStakes[key], flags)
+		}
+	}
+
+	result.Overall = finish(result.Overall)
+	for k, line := range result.ByPosition {
+		result.ByPosition[k] = finish(line)
+	}
+	for k, line := range result.ByStakes {
+		result.ByStakes[k] = finish(line)
+	}
+	return result
+}
+
+// heroFlags 依結構化動作判斷 Hero 在這手牌的行為
+// Hero 以 hand.Position 對應動作中的 actor
+func heroFlags(hand models.Hand) (handFlags, bool) {
+	var f handFlags
+	if hand.Position == nil || *hand.Position == "" || len(hand.Streets) == 0 || hand.Streets[0].Name != "preflop" {
+		return f, false
+	}
+	hero := *hand.Position
+
+	acted := false
+	for _, a := range hand.Streets[0].Actions {
+		if a.Actor == hero {
+			acted = true
+			break
+		}
+	}
+	if !acted {
+		return f, false
+	}
+
+	raises := 0         // 目前翻牌前的加注次數
+	voluntary := false  // 是否已有人自願入池
+	heroOpened := false // Hero 是否為第一個加注的人
+	lastRaiser := ""
+	heroFolded := false
+	decided := false // Hero 是否已做過第一個自願的決定
+
+	for _, a := range hand.Streets[0].Actions {
+		if a.Type == "ante" || a.Type == "post" {
+			continue
+		}
+		aggressive := a.Type == "raise" || a.Type == "bet"
+
+		if a.Actor == hero {
+			if !decided {
+				decided = true
+				if raises == 0 && !voluntary && stealPositions[hero] {
+					f.stealOpp = true
+					f.steal = aggressive
+				}
+			}
+			switch {
+			case raises == 1 && !heroOpened:
+				f.threeBetOpp = true
+				if aggressive {
+					f.threeBet = true
+				}
+			case raises == 2 && heroOpened && !f.foldToThreeBetOpp:
+				f.foldToThreeBetOpp = true
+				f.foldedTo3B = a.Type == "fold"
+			}
+			if a.Type == "call" || aggressive {
+				f.vpip = true
+			}
+			if aggressive {
+				f.pfr = true
+				if raises == 0 {
+					heroOpened = true
+				}
+			}
+			if a.Type == "fold" {
+				heroFolded = true
+			}
+		}
+
+		if aggressive {
+			raises++
+			lastRaiser = a.Actor
+		}
+		if a.Type == "call" || aggressive {
+			voluntary = true
+		}
+	}
+
+	// c-bet：Hero 是翻牌前最後加注者，翻牌圈在 Hero 之前沒人下注
+	if lastRaiser == hero && !heroFolded {
+		for _, street := range hand.Streets[1:] {
+			if street.Name != "flop" {
+				continue
+			}
+			for _, a := range street.Actions {
+				if a.Actor == hero {
+					if a.Type == "check" || a.Type == "bet" {
+						f.cbetOpp = true
+						f.cbet = a.Type == "bet"
+					}
+					break
+				}
+				if a.Type == "bet" || a.Type == "raise" {
+					break
+				}
+			}
+		}
+	}
+	return f, true
+}
+
+func addFlags(line models.StatLine, f handFlags) models.StatLine {
+	line.Hands++
+	count := func(s *models.StatValue, opp, hit bool) {
+		if opp {
+			s.Opportunities++
+		}
+		if opp && hit {
+			s.Count++
+		}
+	}
+	count(&line.VPIP, true, f.vpip)
+	count(&line.PFR, true, f.pfr)
+	count(&line.ThreeBet, f.threeBetOpp, f.threeBet)
+	count(&line.FoldToThreeBet, f.foldToThreeBetOpp, f.foldedTo3B)
+	count(&line.Steal, f.stealOpp, f.steal)
+	count(&line.CBet, f.cbetOpp, f.cbet)
+	return line
+}
+
+func finish(line models.StatLine) models.StatLine {
+	for _, s := range []*models.StatValue{&line.VPIP, &line.PFR, &line.ThreeBet, &line.FoldToThreeBet, &line.Steal, &line.CBet} {
+		if s.Opportunities > 0 {
+			s.Percent = round2(float64(s.Count) / float64(s.Opportunities) * 100)
+		}
+	}
+	return line
+}
@@ -0,0 +1,300 @@
+package stats
+
+import (
+	"poker_tracker_backend/currency"
+	"poker_tracker_backend/models"
+	"poker_tracker_backend/sessions"
+	"sort"
+	"strconv"
+)
+
+// Compute 彙總所有 session 與手牌的統計，金額以 conv 換算成報表幣別
+// 錦標賽的手牌輸贏是籌碼，所以不計入現金局的統計，錦標賽另外以買入與獎金計算
+func Compute(allSessions []models.Session, allHands []models.Hand, conv *currency.Converter) models.Stats {
+	sessionList := []models.Session{}
+	tournaments := []models.Session{}
+	tournamentIDs := map[string]bool{}
+	for _, session := range allSessions {
+		if sessions.IsTournament(session) {
+			tournaments = append(tournaments, session)
+			tournamentIDs[session.ID] = true
+			continue
+		}
+		sessionList = append(sessionList, session)
+	}
+	hands := []models.Hand{}
+	for _, hand := range allHands {
+		if !tournamentIDs[hand.SessionID] {
+			hands = append(hands, hand)
+		}
+	}
+
+	sessionByID := map[string]models.Session{}
+	for _, session := range sessionList {
+		sessionByID[session.ID] = session
+	}
+
+	totalProfit := 0.0
+	sessionProfits := map[string]float64{}
+	sessionHandResults := map[string]int{}
+	sessionHands := map[string]int{}
+	sessionEV := map[string]float64{}
+	sessionBB := map[string]float64{}
+	byStakes := map[string]float64{}
+	byLocation := map[string]float64{}
+	byStakesEV := map[string]float64{}
+	byLocationEV := map[string]float64{}
+	byStakesBB := map[string]float64{}
+	byLocationBB := map[string]float64{}
+	byCurrency := map[string]models.CurrencyBreakdown{}
+	byTag := map[string]models.TagStats{}
+	tagBBHands := map[string]int{}
+	bySessionTag := map[string]float64{}
+	missing := map[string]bool{}
+	sessionCount := 0
+	winSessions := 0
+	cashProfit := 0.0
+	cashSessions := 0
+	gaps := []models.SessionGap{}
+	hours, timedProfit := 0.0, 0.0
+	timedBB, timedBBHours := 0.0, 0.0
+
+	totalEV := 0.0
+	allInHands := 0
+	totalBB := 0.0
+	totalEVBB := 0.0
+	bbHands := 0
+	for _, hand := range hands {
+		session := sessionByID[hand.SessionID]
+		date := hand.Date
+		if date == "" {
+			date = session.Date
+		}
+
+		// 符合條件的全下手牌以期望值取代實際結果
+		ev, ok := AllInEV(hand)
+		if ok {
+			allInHands++
+		} else {
+			ev = float64(hand.Result)
+		}
+
+		_, scale := currency.Parse(session.Currency)
+		code := conv.Code(session.Currency)
+		breakdown := byCurrency[code]
+		breakdown.Hands++
+		breakdown.Profit += float64(hand.Result) / scale
+		sessionHandResults[hand.SessionID] += hand.Result
+		sessionHands[hand.SessionID]++
+
+		// 缺少匯率的手牌不計入換算後的總計
+		result, err := conv.Convert(float64(hand.Result), session.Currency, date)
+		if err != nil {
+			missing[code] = true
+		} else {
+			convertedEV, _ := conv.Convert(ev, session.Currency, date)
+			breakdown.Converted += result
+			totalProfit += result
+			sessionProfits[hand.SessionID] += result
+			totalEV += convertedEV
+			sessionEV[hand.SessionID] += convertedEV
+		}
+		byCurrency[code] = breakdown
+
+		// 沒有 session 或大盲為 0 的手牌無法換算成大盲
+		if bb := session.BigBlind; bb > 0 {
+			bbHands++
+			won := float64(hand.Result) / float64(bb)
+			totalBB += won
+			totalEVBB += ev / float64(bb)
+			sessionBB[hand.SessionID] += won
+		}
+
+		// 帶多個標籤的手牌會計入每一個標籤
+		for _, tag := range hand.Tags {
+			tagStats := byTag[tag.Name]
+			tagStats.Hands++
+			if err == nil {
+				tagStats.Profit += result
+			}
+			if bb := session.BigBlind; bb > 0 {
+				tagStats.TotalBB += float64(hand.Result) / float64(bb)
+				tagBBHands[tag.Name]++
+			}
+			byTag[tag.Name] = tagStats
+		}
+	}
+
+	for _, session := range sessionList {
+		sessionCount++
+		profit := sessionProfits[session.ID]
+		if profit > 0 {
+			winSessions++
+		}
+		stakeKey := StakesKey(session)
+		byStakes[stakeKey] += profit
+		byLocation[session.Location] += profit
+		byStakesEV[stakeKey] += sessionEV[session.ID]
+		byLocationEV[session.Location] += sessionEV[session.ID]
+		byStakesBB[stakeKey] += sessionBB[session.ID]
+		byLocationBB[session.Location] += sessionBB[session.ID]
+		for _, tag := range session.Tags {
+			bySessionTag[tag.Name] += profit
+		}
+
+		code := conv.Code(session.Currency)
+		breakdown := byCurrency[code]
+		breakdown.Sessions++
+		byCurrency[code] = breakdown
+
+		// 以買入與兌現計算的實際結果；沒有結算的 session 以手牌輸贏代替
+		sessions.Summarize(&session)
+		result := sessionHandResults[session.ID]
+		if session.CashResult != nil {
+			result = *session.CashResult
+			if converted, err := conv.Convert(float64(result), session.Currency, session.Date); err == nil {
+				cashProfit += converted
+				cashSessions++
+			} else {
+				missing[code] = true
+			}
+			if sessionHands[session.ID] > 0 && result != sessionHandResults[session.ID] {
+				gaps = append(gaps, models.SessionGap{
+					SessionID:   session.ID,
+					Date:        session.Date,
+					Location:    session.Location,
+					HandsResult: sessionHandResults[session.ID],
+					CashResult:  result,
+					Gap:         result - sessionHandResults[session.ID],
+				})
+			}
+		}
+		if d, ok := sessions.Duration(session); ok {
+			converted, err := conv.Convert(float64(result), session.Currency, session.Date)
+			if err != nil {
+				missing[code] = true
+				continue
+			}
+			hours += d.Hours()
+			timedProfit += converted
+			if session.BigBlind > 0 {
+				timedBB += float64(result) / float64(session.BigBlind)
+				timedBBHours += d.Hours()
+			}
+		}
+	}
+
+	avgSession := 0.0
+	avgSessionBB := 0.0
+	if sessionCount > 0 {
+		avgSession = totalProfit / float64(sessionCount)
+		avgSessionBB = totalBB / float64(sessionCount)
+	}
+	winRate := 0
+	if sessionCount > 0 {
+		winRate = int(float64(winSessions) / float64(sessionCount) * 100)
+	}
+	bbPer100 := 0.0
+	if bbHands > 0 {
+		bbPer100 = totalBB / float64(bbHands) * 100
+	}
+
+	hourlyRate := 0.0
+	if hours > 0 {
+		hourlyRate = timedProfit / hours
+	}
+	bbPerHour := 0.0
+	if timedBBHours > 0 {
+		bbPerHour = timedBB / timedBBHours
+	}
+
+	for code, b := range byCurrency {
+		b.Profit = round2(b.Profit)
+		b.Converted = round2(b.Converted)
+		byCurrency[code] = b
+	}
+	for name, t := range byTag {
+		if tagBBHands[name] > 0 {
+			t.BBPer100 = round2(t.TotalBB / float64(tagBBHands[name]) * 100)
+		}
+		t.Profit = round2(t.Profit)
+		t.TotalBB = round2(t.TotalBB)
+		byTag[name] = t
+	}
+	tournamentStats := computeTournaments(tournaments, conv, missing)
+
+	missingRates := []string{}
+	for code := range missing {
+		missingRates = append(missingRates, code)
+	}
+	sort.Strings(missingRates)
+
+	return models.Stats{
+		Currency:          conv.To,
+		TotalProfit:       round2(totalProfit),
+		TotalSessions:     sessionCount,
+		WinRate:           winRate,
+		AvgSession:        round2(avgSession),
+		ByStakes:          roundMap(byStakes),
+		ByLocation:        roundMap(byLocation),
+		ByCurrency:        byCurrency,
+		MissingRates:      missingRates,
+		AllInEVProfit:     round2(totalEV),
+		AllInHands:        allInHands,
+		Luck:              round2(totalProfit - totalEV),
+		ByStakesAllInEV:   roundMap(byStakesEV),
+		ByLocationAllInEV: roundMap(byLocationEV),
+		TotalHands:        len(hands),
+		BBHands:           bbHands,
+		TotalBB:           round2(totalBB),
+		BBPer100:          round2(bbPer100),
+		AvgSessionBB:      round2(avgSessionBB),
+		AllInEVBB:         round2(totalEVBB),
+		ByStakesBB:        roundMap(byStakesBB),
+		ByLocationBB:      roundMap(byLocationBB),
+		CashProfit:        round2(cashProfit),
+		CashSessions:      cashSessions,
+		HoursPlayed:       round2(hours),
+		HourlyRate:        round2(hourlyRate),
+		BBPerHour:         round2(bbPerHour),
+		ResultGaps:        gaps,
+		ByTag:             byTag,
+		BySessionTag:      roundMap(bySessionTag),
+		Tournaments:       tournamentStats,
+	}
+}
+
+// StakesKey 回傳 session 的級別標籤，例如 "$1/2"、"TWD 100/200"、"tournament $100+10"
+// 以分記錄的 session 會換回元，例如 "$0.01/0.02"
+func StakesKey(session models.Session) string {
+	code, scale := currency.Parse(session.Currency)
+	prefix := ""
+	amounts := formatAmount(float64(session.SmallBlind)/scale) + "/" + formatAmount(float64(session.BigBlind)/scale)
+	// 錦標賽以買入加手續費表示
+	if sessions.IsTournament(session) && session.Tournament != nil {
+		prefix = session.Type + " "
+		amounts = formatAmount(float64(session.Tournament.BuyIn)/scale) + "+" + formatAmount(float64(session.Tournament.Fee)/scale)
+	}
+	if code == "" || code == "USD" {
+		return prefix + "$" + amounts
+	}
+	return prefix + code + " " + amounts
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func round2(v float64) float64 {
+	if v < 0 {
+		return -round2(-v)
+	}
+	return float64(int64(v*100+0.5)) / 100
+}
+
+func roundMap(m map[string]float64) map[string]float64 {
+	for k, v := range m {
+		m[k] = round2(v)
+	}
+	return m
+}
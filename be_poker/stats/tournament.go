@@ -0,0 +1,65 @@
+package stats
+
+import (
+	"poker_tracker_backend/currency"
+	"poker_tracker_backend/models"
+	"poker_tracker_backend/sessions"
+)
+
+// computeTournaments 計算錦標賽的 ROI、ITM 與平均名次
+// 花費與獎金只計入已有結果（有名次或已結束）的錦標賽，進行中的不會被當成輸掉買入
+func computeTournaments(list []models.Session, conv *currency.Converter, missing map[string]bool) models.TournamentStats {
+	result := models.TournamentStats{ByType: map[string]float64{}}
+	placeSum := 0
+	placed := 0
+	for _, session := range list {
+		t := session.Tournament
+		if t == nil {
+			continue
+		}
+		result.Count++
+		sessions.Summarize(&session)
+		if session.CashResult == nil {
+			continue
+		}
+
+		code := conv.Code(session.Currency)
+		cost, err := conv.Convert(float64(sessions.Cost(*t)), session.Currency, session.Date)
+		if err != nil {
+			missing[code] = true
+			continue
+		}
+		winnings, _ := conv.Convert(float64(t.Prize+t.Bounties), session.Currency, session.Date)
+		bounties, _ := conv.Convert(float64(t.Bounties), session.Currency, session.Date)
+
+		result.Finished++
+		result.Entries += sessions.Entries(*t)
+		result.Cost += cost
+		result.Winnings += winnings
+		result.Bounties += bounties
+		result.ByType[session.Type] += winnings - cost
+		if t.Prize > 0 {
+			result.ITM++
+		}
+		if t.Place > 0 {
+			placeSum += t.Place
+			placed++
+		}
+	}
+
+	result.Profit = round2(result.Winnings - result.Cost)
+	if result.Cost > 0 {
+		result.ROI = round2((result.Winnings - result.Cost) / result.Cost * 100)
+	}
+	if result.Finished > 0 {
+		result.ITMRate = round2(float64(result.ITM) / float64(result.Finished) * 100)
+	}
+	if placed > 0 {
+		result.AvgFinish = round2(float64(placeSum) / float64(placed))
+	}
+	result.Cost = round2(result.Cost)
+	result.Winnings = round2(result.Winnings)
+	result.Bounties = round2(result.Bounties)
+	result.ByType = roundMap(result.ByType)
+	return result
+}
@@ -0,0 +1,150 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// MaxCellLength 是 Excel 儲存格最多能放的字元數，超過的文字會被截斷
+const MaxCellLength = 32767
+
+// Workbook 依序寫出 XLSX 的工作表，每一列寫完就送出，不會把整份檔案留在記憶體
+// 一次只能寫一張工作表，開始下一張時前一張就結束
+type Workbook struct {
+	zip    *zip.Writer
+	sheets []string
+	sheet  io.Writer
+	row    int
+}
+
+// NewWorkbook 開始寫一份 XLSX 到 w
+func NewWorkbook(w io.Writer) *Workbook {
+	return &Workbook{zip: zip.NewWriter(w)}
+}
+
+// AddSheet 結束目前的工作表並開始新的一張
+func (wb *Workbook) AddSheet(name string) error {
+	if err := wb.endSheet(); err != nil {
+		return err
+	}
+	wb.sheets = append(wb.sheets, name)
+	f, err := wb.zip.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(wb.sheets)))
+	if err != nil {
+		return err
+	}
+	wb.sheet = f
+	wb.row = 0
+	_, err = io.WriteString(f, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return err
+}
+
+// WriteRow 在目前的工作表加上一列；int、float64 寫成數字，bool 寫成布林，其他寫成文字
+func (wb *Workbook) WriteRow(values ...interface{}) error {
+	if wb.sheet == nil {
+		return errors.New("xlsx: no sheet to write to")
+	}
+	wb.row++
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<row r="%d">`, wb.row)
+	for i, v := range values {
+		ref := columnName(i) + strconv.Itoa(wb.row)
+		switch v := v.(type) {
+		case nil:
+			continue
+		case int:
+			fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case float64:
+			fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			n := 0
+			if v {
+				n = 1
+			}
+			fmt.Fprintf(&b, `<c r="%s" t="b"><v>%d</v></c>`, ref, n)
+		default:
+			s := fmt.Sprint(v)
+			if s == "" {
+				continue
+			}
+			if runes := []rune(s); len(runes) > MaxCellLength {
+				s = string(runes[:MaxCellLength])
+			}
+			fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			xml.EscapeText(&b, []byte(s))
+			b.WriteString(`</t></is></c>`)
+		}
+	}
+	b.WriteString(`</row>`)
+	_, err := wb.sheet.Write(b.Bytes())
+	return err
+}
+
+// Close 結束最後一張工作表並寫入活頁簿的目錄
+func (wb *Workbook) Close() error {
+	if err := wb.endSheet(); err != nil {
+		return err
+	}
+
+	var contentTypes, workbook, rels bytes.Buffer
+	contentTypes.WriteString(xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	workbook.WriteString(xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	rels.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i, name := range wb.sheets {
+		n := i + 1
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		workbook.WriteString(`<sheet name="`)
+		xml.EscapeText(&workbook, []byte(name))
+		fmt.Fprintf(&workbook, `" sheetId="%d" r:id="rId%d"/>`, n, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+	}
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	rels.WriteString(`</Relationships>`)
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"[Content_Types].xml", contentTypes.Bytes()},
+		{"_rels/.rels", []byte(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`)},
+		{"xl/workbook.xml", workbook.Bytes()},
+		{"xl/_rels/workbook.xml.rels", rels.Bytes()},
+	}
+	for _, file := range files {
+		f, err := wb.zip.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(file.data); err != nil {
+			return err
+		}
+	}
+	return wb.zip.Close()
+}
+
+func (wb *Workbook) endSheet() error {
+	if wb.sheet == nil {
+		return nil
+	}
+	_, err := io.WriteString(wb.sheet, `</sheetData></worksheet>`)
+	wb.sheet = nil
+	return err
+}
+
+// columnName 把從 0 開始的欄位序號轉成 A、B、…、Z、AA
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}